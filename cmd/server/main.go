@@ -2,18 +2,19 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/handlers"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/middleware"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/metrics"
 	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
 )
 
@@ -21,31 +22,103 @@ func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig("configs/config.yaml")
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("failed to load config", err)
+	}
+	if err := logging.Setup(cfg.Logging, os.Stderr); err != nil {
+		fatal("failed to set up logging", err)
 	}
 
 	// Initialize WebSocket hub
 	hub := websocket.NewHub()
 
+	// Join the cluster if configured
+	var broadcaster *websocket.TCPBroadcaster
+	if cfg.Cluster.Enabled {
+		broadcaster = websocket.NewTCPBroadcaster(cfg.Cluster)
+		if err := broadcaster.Start(); err != nil {
+			fatal("failed to start cluster broadcaster", err)
+		}
+		hub.SetBroadcaster(broadcaster)
+	}
+
 	// Initialize services
 	roomManager := services.NewRoomManager()
+	roomManager.SetOwnershipCheck(hub.IsLocalKey)
+	if cfg.Storage.RoomsDir != "" {
+		store, err := services.NewFileRoomStore(cfg.Storage.RoomsDir)
+		if err != nil {
+			fatal("failed to initialize room store", err)
+		}
+		roomManager.SetStore(store, hub.ResumeToken)
+	}
 	wordBank, err := services.NewWordBank(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize word bank: %v", err)
+		fatal("failed to initialize word bank", err)
 	}
 	gameEngine := services.NewGameEngine(wordBank, cfg)
+	history, err := services.NewGameHistory(cfg.Storage.GameHistoryFile, cfg.Storage.GameHistorySize)
+	if err != nil {
+		fatal("failed to initialize game history", err)
+	}
+	gameEngine.SetHistory(history)
+	profiles, err := services.NewProfileStore(cfg.Storage.ProfilesFile, cfg.Rating)
+	if err != nil {
+		fatal("failed to initialize profiles", err)
+	}
+	gameEngine.SetProfiles(profiles)
+	roomManager.SetRatings(profiles.Rating)
+	leaderboards, err := services.NewLeaderboards(cfg.Leaderboards)
+	if err != nil {
+		fatal("failed to initialize leaderboards", err)
+	}
+	gameEngine.SetLeaderboards(leaderboards)
+	quickPlay := services.NewQuickPlayQueue(roomManager)
+	bans := services.NewBanList()
+	accounts, err := services.NewAccountManager(cfg.Auth)
+	if err != nil {
+		fatal("failed to initialize accounts", err)
+	}
+	audit, err := services.NewAuditLog(cfg.Admin.AuditLogFile, cfg.Admin.AuditLogSize)
+	if err != nil {
+		fatal("failed to initialize audit log", err)
+	}
 
 	// Set up message processor for WebSocket hub
 	hub.SetMessageProcessor(func(msg *websocket.MessageWithClient) {
-		handlers.HandleWebSocketMessage(hub, roomManager, gameEngine, msg.Client, msg.Message)
+		handlers.HandleWebSocketMessage(hub, roomManager, gameEngine, accounts, profiles, leaderboards, quickPlay, bans, msg.Client, msg.Message)
 	})
 
-	// Start hub in a goroutine
+	// Follow players in and out of rooms as their connections drop
+	hub.SetLifecycleHooks(websocket.LifecycleHooks{
+		OnDisconnect: func(roomID, userID string) {
+			handlers.HandlePlayerDisconnected(hub, roomManager, gameEngine, roomID, userID)
+		},
+		OnReconnect: func(roomID, userID string) {
+			handlers.HandlePlayerReconnected(hub, roomManager, roomID, userID)
+		},
+	})
+
+	// Bring back the rooms saved before the last shutdown
+	snapshots, err := roomManager.RestoreRooms()
+	if err != nil {
+		slog.Error("failed to restore some rooms", logging.Err(err))
+	}
+	handlers.RestoreRooms(hub, roomManager, gameEngine, snapshots)
+
+	// Expose hub and room state to metrics scrapes
+	registerMetrics(hub, roomManager)
+
+	// Start hub and room cleanup in goroutines
 	go hub.Run()
+	go roomManager.Cleanup()
+	go leaderboards.Run()
+	go quickPlay.Run(func() {
+		handlers.MatchQuickPlay(hub, roomManager, gameEngine, quickPlay)
+	})
 
 	// Set up router
 	router := mux.NewRouter()
-	setupRoutes(router, hub, roomManager)
+	setupRoutes(router, cfg, hub, roomManager, gameEngine, history, profiles, leaderboards, accounts, bans, audit)
 
 	// Apply middleware
 	srv := &http.Server{
@@ -58,59 +131,160 @@ func main() {
 
 	// Start server
 	go func() {
-		log.Printf("Starting server on %s", cfg.Server.Port)
+		slog.Info("starting server", "addr", cfg.Server.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			fatal("server error", err)
 		}
 	}()
 
 	// Handle graceful shutdown
-	gracefulShutdown(srv, hub, roomManager)
+	gracefulShutdown(cfg, srv, hub, roomManager, gameEngine, broadcaster)
+	quickPlay.Stop()
+	history.Close()
+	if err := profiles.Close(); err != nil {
+		slog.Error("failed to save profiles", logging.Err(err))
+	}
+	leaderboards.Stop()
+	if err := leaderboards.Close(); err != nil {
+		slog.Error("failed to save leaderboards", logging.Err(err))
+	}
+	audit.Close()
 }
 
 // setupRoutes configures the HTTP routes
-func setupRoutes(router *mux.Router, hub *websocket.Hub, roomManager *services.RoomManager) {
+func setupRoutes(router *mux.Router, cfg *config.Config, hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, history *services.GameHistory, profiles *services.ProfileStore, leaderboards *services.Leaderboards, accounts *services.AccountManager, bans *services.BanList, audit *services.AuditLog) {
 	// Health check endpoint
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		// Let load balancers stop routing here while the server drains
+		if hub.Draining() {
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
 	// WebSocket endpoint
 	router.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		handlers.ServeWS(hub, w, r)
+		handlers.ServeWS(hub, accounts, bans, w, r)
 	}).Methods("GET")
 
+	// Account endpoints, disabled unless a JWT secret is configured
+	authRouter := router.PathPrefix("/api/auth").Subrouter()
+	authRouter.HandleFunc("/signup", handlers.Signup(accounts)).Methods("POST")
+	authRouter.HandleFunc("/login", handlers.Login(accounts)).Methods("POST")
+	authRouter.HandleFunc("/refresh", handlers.RefreshTokens(accounts)).Methods("POST")
+	authRouter.Handle("/me", middleware.AuthMiddleware(accounts)(middleware.RequireAccount(handlers.GetCurrentAccount()))).Methods("GET")
+
 	// Room API endpoints
 	roomRouter := router.PathPrefix("/api/rooms").Subrouter()
+	roomRouter.Use(middleware.AuthMiddleware(accounts))
 	roomRouter.HandleFunc("/public", handlers.GetPublicRooms(roomManager)).Methods("GET")
 	roomRouter.HandleFunc("", handlers.CreateRoom(hub, roomManager)).Methods("POST")
 	roomRouter.HandleFunc("/{roomID}", handlers.GetRoomDetails(roomManager)).Methods("GET")
+	roomRouter.HandleFunc("/{roomID}/events", handlers.StreamRoomEvents(hub, roomManager)).Methods("GET")
+	roomRouter.HandleFunc("/{roomID}/games", handlers.GetRoomGames(history)).Methods("GET")
+
+	// Game history endpoints
+	gameRouter := router.PathPrefix("/api/games").Subrouter()
+	gameRouter.Use(middleware.AuthMiddleware(accounts))
+	gameRouter.HandleFunc("", handlers.GetRecentGames(history)).Methods("GET")
+	gameRouter.HandleFunc("/{gameID}", handlers.GetGame(history)).Methods("GET")
+
+	// Player endpoints
+	playerRouter := router.PathPrefix("/api/players").Subrouter()
+	playerRouter.Use(middleware.AuthMiddleware(accounts))
+	playerRouter.HandleFunc("/{userID}", handlers.GetPlayerProfile(profiles, accounts)).Methods("GET")
+	playerRouter.HandleFunc("/{userID}/games", handlers.GetPlayerGames(history)).Methods("GET")
+
+	// Global leaderboard endpoints
+	leaderboardRouter := router.PathPrefix("/api/leaderboards").Subrouter()
+	leaderboardRouter.Use(middleware.AuthMiddleware(accounts))
+	leaderboardRouter.HandleFunc("/{window}", handlers.GetGlobalLeaderboard(leaderboards)).Methods("GET")
+
+	// Admin API, disabled unless an admin token is configured
+	adminRouter := router.PathPrefix("/api/admin").Subrouter()
+	adminRouter.Use(middleware.AdminAuth(cfg.Admin.Token))
+	adminRouter.HandleFunc("/stats", handlers.GetAdminStats(hub, roomManager)).Methods("GET")
+	adminRouter.HandleFunc("/rooms", handlers.ListAdminRooms(roomManager)).Methods("GET")
+	adminRouter.HandleFunc("/rooms/{roomID}", handlers.GetAdminRoom(roomManager, audit)).Methods("GET")
+	adminRouter.HandleFunc("/rooms/{roomID}", handlers.AdminCloseRoom(hub, roomManager, audit)).Methods("DELETE")
+	adminRouter.HandleFunc("/rooms/{roomID}/end-round", handlers.AdminEndRound(hub, roomManager, gameEngine, audit)).Methods("POST")
+	adminRouter.HandleFunc("/rooms/{roomID}/end-game", handlers.AdminEndGame(hub, roomManager, gameEngine, audit)).Methods("POST")
+	adminRouter.HandleFunc("/connections", handlers.ListAdminConnections(hub)).Methods("GET")
+	adminRouter.HandleFunc("/players/{userID}/kick", handlers.AdminKickPlayer(hub, roomManager, gameEngine, audit)).Methods("POST")
+	adminRouter.HandleFunc("/players/{userID}/ban", handlers.AdminBanPlayer(hub, roomManager, gameEngine, bans, audit)).Methods("POST")
+	adminRouter.HandleFunc("/bans", handlers.ListAdminBans(bans)).Methods("GET")
+	adminRouter.HandleFunc("/bans/{kind}/{value}", handlers.AdminLiftBan(bans, audit)).Methods("DELETE")
+	adminRouter.HandleFunc("/announcements", handlers.AdminAnnounce(hub, audit)).Methods("POST")
+	adminRouter.HandleFunc("/audit", handlers.GetAdminAuditLog(audit)).Methods("GET")
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Default.Handler()).Methods("GET")
+}
+
+// registerMetrics adds gauges read from the hub and room manager on each scrape
+func registerMetrics(hub *websocket.Hub, roomManager *services.RoomManager) {
+	metrics.Default.NewGaugeFunc("doodledash_connected_clients",
+		"Clients connected to this node.",
+		func() float64 {
+			return float64(hub.GetConnectedClients())
+		})
+	metrics.Default.NewGaugeVecFunc("doodledash_rooms",
+		"Rooms owned by this node, by game state.",
+		"state",
+		func() map[string]float64 {
+			counts := make(map[string]float64)
+			for state, count := range roomManager.CountByState() {
+				counts[string(state)] = float64(count)
+			}
+			return counts
+		})
 }
 
-// gracefulShutdown handles server shutdown gracefully
-func gracefulShutdown(srv *http.Server, hub *websocket.Hub, roomManager *services.RoomManager) {
+// gracefulShutdown waits for a signal, then lets in-flight rounds finish
+// and stops everything in order within the shutdown timeout
+func gracefulShutdown(cfg *config.Config, srv *http.Server, hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, broadcaster *websocket.TCPBroadcaster) {
 	// Create channel for OS signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	<-stop
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server", "drain_period", cfg.Server.DrainPeriod.String())
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Shutdown WebSocket hub
-	hub.Shutdown()
+	// Refuse new connections and games, and give running rounds time to end
+	handlers.DrainGames(ctx, hub, roomManager, gameEngine, cfg.Server.DrainPeriod)
+
+	// Stop round timers before the room actors they dispatch to
+	gameEngine.Stop()
+
+	// Close every connection and stop the hub and room actors
+	if err := hub.Shutdown(ctx); err != nil {
+		slog.Error("hub shutdown error", logging.Err(err))
+	}
+	if broadcaster != nil {
+		broadcaster.Close()
+	}
 
-	// Cleanup rooms
-	roomManager.Cleanup()
+	// Save rooms for the next start, then stop room cleanup and pending
+	// player removals
+	roomManager.SaveAll()
+	roomManager.Stop()
 
 	// Shutdown HTTP server
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		slog.Error("server shutdown error", logging.Err(err))
 	}
 
-	log.Println("Server gracefully stopped")
-}
\ No newline at end of file
+	slog.Info("server gracefully stopped")
+}
+
+// fatal logs an error that keeps the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.Err(err))
+	os.Exit(1)
+}
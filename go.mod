@@ -3,9 +3,9 @@ module github.com/RITWIZSINGH/DoodleDash-backend
 go 1.23.0
 
 require (
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/rs/cors v1.11.1 // indirect
-	golang.org/x/time v0.12.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/rs/cors v1.11.1
+	golang.org/x/time v0.12.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// signWithHeader signs claims under an arbitrary header with the issuer's key
+func signWithHeader(ti *TokenIssuer, header string, claims Claims) string {
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ti.signature(signed))
+}
+
+func TestVerify(t *testing.T) {
+	ti := NewTokenIssuer(testSecret, time.Minute, time.Hour)
+	pair, err := ti.Issue("acc_1", "alice", 3)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// Change the first signature character; the last one carries padding
+	// bits that may not change the decoded signature
+	parts := strings.Split(pair.AccessToken, ".")
+	flipped := "A"
+	if parts[2][0] == 'A' {
+		flipped = "B"
+	}
+	tampered := parts[0] + "." + parts[1] + "." + flipped + parts[2][1:]
+
+	// Swap the payload for another account's, keeping the signature
+	forged, _ := json.Marshal(Claims{Subject: "acc_2", Type: TokenTypeAccess, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	swapped := parts[0] + "." + base64.RawURLEncoding.EncodeToString(forged) + "." + parts[2]
+
+	valid := Claims{Subject: "acc_1", Type: TokenTypeAccess, ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Second).Unix()
+
+	other := NewTokenIssuer("fedcba9876543210fedcba9876543210", time.Minute, time.Hour)
+	otherPair, err := other.Issue("acc_1", "alice", 3)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		tokenType string
+		wantErr   error
+	}{
+		{"access token", pair.AccessToken, TokenTypeAccess, nil},
+		{"refresh token", pair.RefreshToken, TokenTypeRefresh, nil},
+		{"tampered signature", tampered, TokenTypeAccess, ErrInvalidToken},
+		{"tampered payload", swapped, TokenTypeAccess, ErrInvalidToken},
+		{"other key", otherPair.AccessToken, TokenTypeAccess, ErrInvalidToken},
+		{"refresh as access", pair.RefreshToken, TokenTypeAccess, ErrInvalidToken},
+		{"access as refresh", pair.AccessToken, TokenTypeRefresh, ErrInvalidToken},
+		{"alg none", signWithHeader(ti, `{"alg":"none","typ":"JWT"}`, valid), TokenTypeAccess, ErrInvalidToken},
+		{"alg HS512", signWithHeader(ti, `{"alg":"HS512","typ":"JWT"}`, valid), TokenTypeAccess, ErrInvalidToken},
+		{"unsigned", strings.Join(parts[:2], ".") + ".", TokenTypeAccess, ErrInvalidToken},
+		{"malformed", "not-a-token", TokenTypeAccess, ErrInvalidToken},
+		{"expired", signWithHeader(ti, tokenHeader, expired), TokenTypeAccess, ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		claims, err := ti.Verify(tt.token, tt.tokenType)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Verify error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (claims.Subject != "acc_1" || claims.Username != "alice" || claims.Generation != 3) {
+			t.Errorf("%s: Verify claims = %+v, want acc_1/alice generation 3", tt.name, claims)
+		}
+	}
+}
+
+func TestVerifyExpiresAfterTTL(t *testing.T) {
+	ti := NewTokenIssuer(testSecret, -time.Second, time.Hour)
+	pair, err := ti.Issue("acc_1", "alice", 1)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if _, err := ti.Verify(pair.AccessToken, TokenTypeAccess); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Verify error = %v, want %v", err, ErrExpiredToken)
+	}
+	if _, err := ti.Verify(pair.RefreshToken, TokenTypeRefresh); err != nil {
+		t.Errorf("Verify refresh error = %v, want nil", err)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -10,13 +11,22 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	Server     ServerConfig     `yaml:"server"`
-	WebSocket  WebSocketConfig  `yaml:"websocket"`
-	Game       GameConfig       `yaml:"game"`
-	Points     PointsConfig     `yaml:"points"`
-	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
-	CORS       CORSConfig       `yaml:"cors"`
-	WordBank   WordBankConfig   `yaml:"word_bank"`
+	Server       ServerConfig      `yaml:"server"`
+	WebSocket    WebSocketConfig   `yaml:"websocket"`
+	Game         GameConfig        `yaml:"game"`
+	Points       PointsConfig      `yaml:"points"`
+	RateLimit    RateLimitConfig   `yaml:"rate_limit"`
+	CORS         CORSConfig        `yaml:"cors"`
+	WordBank     WordBankConfig    `yaml:"word_bank"`
+	Session      SessionConfig     `yaml:"session"`
+	Cluster      ClusterConfig     `yaml:"cluster"`
+	Admin        AdminConfig       `yaml:"admin"`
+	Logging      LoggingConfig     `yaml:"logging"`
+	Storage      StorageConfig     `yaml:"storage"`
+	Auth         AuthConfig        `yaml:"auth"`
+	Leaderboards LeaderboardConfig `yaml:"leaderboards"`
+	Rating       RatingConfig      `yaml:"rating"`
+	Matchmaking  MatchmakingConfig `yaml:"matchmaking"`
 }
 
 // ServerConfig contains HTTP server configuration
@@ -25,26 +35,45 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	DrainPeriod     time.Duration `yaml:"drain_period"`     // How long in-flight rounds get to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // Deadline for the whole shutdown, drain included
 }
 
 // WebSocketConfig contains WebSocket-specific configuration
 type WebSocketConfig struct {
-	ReadBufferSize  int           `yaml:"read_buffer_size"`
-	WriteBufferSize int           `yaml:"write_buffer_size"`
-	MaxMessageSize  int64         `yaml:"max_message_size"`
-	PongWait        time.Duration `yaml:"pong_wait"`
-	PingPeriod      time.Duration `yaml:"ping_period"`
-	WriteWait       time.Duration `yaml:"write_wait"`
+	ReadBufferSize       int           `yaml:"read_buffer_size"`
+	WriteBufferSize      int           `yaml:"write_buffer_size"`
+	MaxMessageSize       int64         `yaml:"max_message_size"`
+	MaxBinaryMessageSize int64         `yaml:"max_binary_message_size"` // Inbound limit on the binary drawing protocol
+	PongWait             time.Duration `yaml:"pong_wait"`
+	PingPeriod           time.Duration `yaml:"ping_period"`
+	WriteWait            time.Duration `yaml:"write_wait"`
+
+	// Outbound queue limits for slow clients
+	SendQueueSize            int           `yaml:"send_queue_size"`
+	CriticalDeliveryDeadline time.Duration `yaml:"critical_delivery_deadline"`
+
+	// permessage-deflate; messages smaller than the threshold are sent uncompressed
+	EnableCompression    bool `yaml:"enable_compression"`
+	CompressionLevel     int  `yaml:"compression_level"`
+	CompressionThreshold int  `yaml:"compression_threshold"`
+
+	// Canvas snapshots for late joiners are split into messages of at most
+	// this many bytes
+	CanvasSnapshotChunkSize int `yaml:"canvas_snapshot_chunk_size"`
 }
 
 // GameConfig contains game-specific configuration
 type GameConfig struct {
-	MaxPlayersPerRoom      int           `yaml:"max_players_per_room"`
-	MinPlayersToStart      int           `yaml:"min_players_to_start"`
-	RoundDuration          time.Duration `yaml:"round_duration"`
-	MaxRounds              int           `yaml:"max_rounds"`
-	RoomCleanupInterval    time.Duration `yaml:"room_cleanup_interval"`
-	InactiveRoomTimeout    time.Duration `yaml:"inactive_room_timeout"`
+	MaxPlayersPerRoom       int           `yaml:"max_players_per_room"`
+	MaxSpectatorsPerRoom    int           `yaml:"max_spectators_per_room"`     // 0 disables spectating
+	MinPlayersToStart       int           `yaml:"min_players_to_start"`
+	RoundDuration           time.Duration `yaml:"round_duration"`
+	MaxRounds               int           `yaml:"max_rounds"`
+	MaxDrawCommandsPerRound int           `yaml:"max_draw_commands_per_round"` // Kept for canvas snapshots; a clear starts over
+	RoomCleanupInterval     time.Duration `yaml:"room_cleanup_interval"`
+	InactiveRoomTimeout     time.Duration `yaml:"inactive_room_timeout"`
 }
 
 // PointsConfig contains point system configuration
@@ -61,6 +90,51 @@ type PointsConfig struct {
 type RateLimitConfig struct {
 	RequestsPerMinute int `yaml:"requests_per_minute"`
 	BurstSize         int `yaml:"burst_size"`
+
+	// HTTP clients are limited by IP, or by API key if they send a known one
+	// in APIKeyHeader. Forwarding headers are only believed from TrustedProxies
+	// (IPs or CIDRs). Buckets unused for IdleTimeout are dropped.
+	TrustedProxies []string      `yaml:"trusted_proxies"`
+	APIKeyHeader   string        `yaml:"api_key_header"`
+	APIKeys        []string      `yaml:"api_keys"`
+	IdleTimeout    time.Duration `yaml:"idle_timeout"`
+
+	// Per-route overrides; the longest matching path prefix wins
+	Routes []RouteRateLimit `yaml:"routes"`
+
+	// Inbound WebSocket messages, limited per client
+	Messages MessageRateLimitConfig `yaml:"messages"`
+}
+
+// RouteRateLimit overrides the HTTP rate limit for paths under PathPrefix
+type RouteRateLimit struct {
+	PathPrefix        string `yaml:"path_prefix"`
+	RequestsPerMinute int    `yaml:"requests_per_minute"`
+	BurstSize         int    `yaml:"burst_size"`
+	Exempt            bool   `yaml:"exempt"` // Not rate limited at all
+}
+
+// MessageRateLimitConfig limits inbound WebSocket messages. Each client gets
+// a token bucket per message type, sized by the type's category. Rejected
+// messages count as violations; within ViolationWindow of each other they
+// escalate from being dropped to a warning, a mute and finally a disconnect.
+type MessageRateLimitConfig struct {
+	Drawing MessageRate `yaml:"drawing"` // draw_* and clear_canvas
+	Chat    MessageRate `yaml:"chat"`    // send_guess and chat_message
+	Auth    MessageRate `yaml:"auth"`    // register, which hashes a password
+	Other   MessageRate `yaml:"other"`   // Everything else
+
+	ViolationWindow time.Duration `yaml:"violation_window"`
+	WarnAfter       int           `yaml:"warn_after"`
+	MuteAfter       int           `yaml:"mute_after"`
+	DisconnectAfter int           `yaml:"disconnect_after"`
+	MuteDuration    time.Duration `yaml:"mute_duration"`
+}
+
+// MessageRate is a token bucket refilled at PerSecond and holding up to Burst
+type MessageRate struct {
+	PerSecond float64 `yaml:"per_second"`
+	Burst     int     `yaml:"burst"`
 }
 
 // CORSConfig contains CORS configuration
@@ -77,6 +151,104 @@ type WordBankConfig struct {
 	HardWordsFile   string `yaml:"hard_words_file"`
 }
 
+// SessionConfig contains reconnect/resume configuration
+type SessionConfig struct {
+	ResumeGracePeriod time.Duration `yaml:"resume_grace_period"`
+	MaxReplayMessages int           `yaml:"max_replay_messages"`
+}
+
+// ClusterConfig contains multi-node configuration. When disabled the server
+// runs as a single node.
+type ClusterConfig struct {
+	Enabled      bool         `yaml:"enabled"`
+	NodeID       string       `yaml:"node_id"`
+	ListenAddr   string       `yaml:"listen_addr"`
+	SharedSecret string       `yaml:"shared_secret"`
+	Peers        []PeerConfig `yaml:"peers"`
+}
+
+// PeerConfig identifies another node in the cluster
+type PeerConfig struct {
+	NodeID string `yaml:"node_id"`
+	Addr   string `yaml:"addr"`
+}
+
+// AdminConfig contains admin API configuration. The admin API is disabled
+// while Token is empty.
+type AdminConfig struct {
+	Token        string `yaml:"token"`          // Sent as "Authorization: Bearer <token>"
+	AuditLogFile string `yaml:"audit_log_file"` // Admin actions are appended here as JSON lines; empty keeps them in memory only
+	AuditLogSize int    `yaml:"audit_log_size"` // Recent actions kept in memory
+}
+
+// LoggingConfig contains structured logging configuration
+type LoggingConfig struct {
+	Level      string            `yaml:"level"`      // debug, info, warn or error
+	Format     string            `yaml:"format"`     // json or text
+	Subsystems map[string]string `yaml:"subsystems"` // Level overrides by subsystem, e.g. hub: debug
+}
+
+// StorageConfig contains on-disk persistence configuration
+type StorageConfig struct {
+	RoomsDir         string        `yaml:"rooms_dir"`         // Room snapshots are kept here; empty disables them
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"` // Every room is also snapshotted this often
+
+	GameHistoryFile string `yaml:"game_history_file"` // Finished games are appended here as JSON lines; empty keeps them in memory only
+	GameHistorySize int    `yaml:"game_history_size"` // Recent games kept in memory and served by the API
+
+	ProfilesFile string `yaml:"profiles_file"` // Registered players' lifetime statistics; empty keeps them in memory only
+}
+
+// AuthConfig contains account configuration. Accounts are disabled while
+// JWTSecret is empty, and everyone plays as a guest.
+type AuthConfig struct {
+	JWTSecret       string        `yaml:"jwt_secret"` // Signs access and refresh tokens
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+
+	AccountsFile       string `yaml:"accounts_file"`       // Accounts are kept here; empty keeps them in memory only
+	PasswordIterations int    `yaml:"password_iterations"` // PBKDF2 rounds for new password hashes
+}
+
+// LeaderboardConfig contains global leaderboard configuration
+type LeaderboardConfig struct {
+	IncludeGuests bool   `yaml:"include_guests"` // Guests are ranked alongside registered players
+	Timezone      string `yaml:"timezone"`       // Daily and weekly leaderboards roll over at midnight here, e.g. "Europe/Berlin"
+	File          string `yaml:"file"`           // Leaderboards are kept here; empty keeps them in memory only
+}
+
+// RatingConfig contains skill rating configuration. Registered players are
+// rated from their placements in every game they finish; guests always
+// have the initial rating.
+type RatingConfig struct {
+	Initial int     `yaml:"initial"`  // Rating of players who haven't finished a game yet
+	KFactor float64 `yaml:"k_factor"` // Most a rating can move in one game
+}
+
+// Matchmaking modes
+const (
+	MatchmakingFill  = "fill"  // Prefer the fullest rooms
+	MatchmakingSkill = "skill" // Prefer rooms whose players are rated close to the player
+)
+
+// MatchmakingConfig contains public room matchmaking configuration
+type MatchmakingConfig struct {
+	Mode string `yaml:"mode"` // MatchmakingFill or MatchmakingSkill
+
+	// In skill mode, the widest gap allowed between a player's rating and a
+	// room's average rating. It starts at RatingGap and grows by
+	// RatingGapGrowth every second the player waits, up to MaxRatingGap.
+	RatingGap       int `yaml:"rating_gap"`
+	RatingGapGrowth int `yaml:"rating_gap_growth"`
+	MaxRatingGap    int `yaml:"max_rating_gap"`
+
+	// Quick play puts QuickPlayGroupSize compatible queued players in a new
+	// room together. A player still queued after QuickPlayMaxWait gets a
+	// room of their own for others to join.
+	QuickPlayGroupSize int           `yaml:"quick_play_group_size"`
+	QuickPlayMaxWait   time.Duration `yaml:"quick_play_max_wait"`
+}
+
 // Global configuration instance
 var AppConfig *Config
 
@@ -124,22 +296,34 @@ func GetDefaultConfig() *Config {
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
+
+			DrainPeriod:     20 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
 		},
 		WebSocket: WebSocketConfig{
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
-			MaxMessageSize:  512,
-			PongWait:        60 * time.Second,
-			PingPeriod:      54 * time.Second,
-			WriteWait:       10 * time.Second,
+			ReadBufferSize:           1024,
+			WriteBufferSize:          1024,
+			MaxMessageSize:           512,
+			MaxBinaryMessageSize:     16384,
+			PongWait:                 60 * time.Second,
+			PingPeriod:               54 * time.Second,
+			WriteWait:                10 * time.Second,
+			SendQueueSize:            256,
+			CriticalDeliveryDeadline: 15 * time.Second,
+			EnableCompression:        false,
+			CompressionLevel:         1,
+			CompressionThreshold:     512,
+			CanvasSnapshotChunkSize:  16384,
 		},
 		Game: GameConfig{
-			MaxPlayersPerRoom:   8,
-			MinPlayersToStart:   2,
-			RoundDuration:       60 * time.Second,
-			MaxRounds:           5,
-			RoomCleanupInterval: 5 * time.Minute,
-			InactiveRoomTimeout: 30 * time.Minute,
+			MaxPlayersPerRoom:       8,
+			MaxSpectatorsPerRoom:    10,
+			MinPlayersToStart:       2,
+			RoundDuration:           60 * time.Second,
+			MaxRounds:               5,
+			MaxDrawCommandsPerRound: 10000,
+			RoomCleanupInterval:     5 * time.Minute,
+			InactiveRoomTimeout:     30 * time.Minute,
 		},
 		Points: PointsConfig{
 			BaseGuessPoints:       100,
@@ -152,6 +336,23 @@ func GetDefaultConfig() *Config {
 		RateLimit: RateLimitConfig{
 			RequestsPerMinute: 60,
 			BurstSize:         10,
+			APIKeyHeader:      "X-API-Key",
+			IdleTimeout:       10 * time.Minute,
+			Routes: []RouteRateLimit{
+				{PathPrefix: "/health", Exempt: true},
+				{PathPrefix: "/api/auth", RequestsPerMinute: 10, BurstSize: 5},
+			},
+			Messages: MessageRateLimitConfig{
+				Drawing:         MessageRate{PerSecond: 60, Burst: 120},
+				Chat:            MessageRate{PerSecond: 2, Burst: 5},
+				Auth:            MessageRate{PerSecond: 0.2, Burst: 3},
+				Other:           MessageRate{PerSecond: 5, Burst: 10},
+				ViolationWindow: 10 * time.Second,
+				WarnAfter:       5,
+				MuteAfter:       20,
+				DisconnectAfter: 50,
+				MuteDuration:    10 * time.Second,
+			},
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: []string{
@@ -171,6 +372,47 @@ func GetDefaultConfig() *Config {
 			MediumWordsFile: "data/words.json",
 			HardWordsFile:   "data/words.json",
 		},
+		Session: SessionConfig{
+			ResumeGracePeriod: 60 * time.Second,
+			MaxReplayMessages: 200,
+		},
+		Cluster: ClusterConfig{
+			Enabled:    false,
+			NodeID:     "node-1",
+			ListenAddr: ":7946",
+		},
+		Admin: AdminConfig{
+			AuditLogSize: 1000,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Storage: StorageConfig{
+			SnapshotInterval: 30 * time.Second,
+			GameHistorySize:  10000,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:     15 * time.Minute,
+			RefreshTokenTTL:    30 * 24 * time.Hour,
+			PasswordIterations: 600000,
+		},
+		Leaderboards: LeaderboardConfig{
+			IncludeGuests: true,
+			Timezone:      "UTC",
+		},
+		Rating: RatingConfig{
+			Initial: 1200,
+			KFactor: 32,
+		},
+		Matchmaking: MatchmakingConfig{
+			Mode:               MatchmakingFill,
+			RatingGap:          100,
+			RatingGapGrowth:    10,
+			MaxRatingGap:       500,
+			QuickPlayGroupSize: 4,
+			QuickPlayMaxWait:   2 * time.Minute,
+		},
 	}
 }
 
@@ -180,6 +422,12 @@ func validateConfig(config *Config) error {
 	if config.Server.Port == "" {
 		return fmt.Errorf("server port cannot be empty")
 	}
+	if config.Server.DrainPeriod < 0 {
+		return fmt.Errorf("server drain period cannot be negative")
+	}
+	if config.Server.ShutdownTimeout <= config.Server.DrainPeriod {
+		return fmt.Errorf("server shutdown timeout must be longer than the drain period")
+	}
 
 	// Validate game config
 	if config.Game.MaxPlayersPerRoom < 2 {
@@ -191,12 +439,18 @@ func validateConfig(config *Config) error {
 	if config.Game.MinPlayersToStart > config.Game.MaxPlayersPerRoom {
 		return fmt.Errorf("min players to start cannot be greater than max players per room")
 	}
+	if config.Game.MaxSpectatorsPerRoom < 0 {
+		return fmt.Errorf("max spectators per room cannot be negative")
+	}
 	if config.Game.RoundDuration <= 0 {
 		return fmt.Errorf("round duration must be positive")
 	}
 	if config.Game.MaxRounds <= 0 {
 		return fmt.Errorf("max rounds must be positive")
 	}
+	if config.Game.MaxDrawCommandsPerRound <= 0 {
+		return fmt.Errorf("max draw commands per round must be positive")
+	}
 
 	// Validate points config
 	if config.Points.BaseGuessPoints <= 0 {
@@ -213,6 +467,147 @@ func validateConfig(config *Config) error {
 	if config.WebSocket.MaxMessageSize <= 0 {
 		return fmt.Errorf("WebSocket max message size must be positive")
 	}
+	if config.WebSocket.MaxBinaryMessageSize < config.WebSocket.MaxMessageSize {
+		return fmt.Errorf("WebSocket max binary message size cannot be less than max message size")
+	}
+	if config.WebSocket.PongWait <= 0 {
+		return fmt.Errorf("WebSocket pong wait must be positive")
+	}
+	if config.WebSocket.PingPeriod <= 0 {
+		return fmt.Errorf("WebSocket ping period must be positive")
+	}
+	if config.WebSocket.PingPeriod >= config.WebSocket.PongWait {
+		return fmt.Errorf("WebSocket ping period must be less than pong wait")
+	}
+	if config.WebSocket.WriteWait <= 0 {
+		return fmt.Errorf("WebSocket write wait must be positive")
+	}
+	if config.WebSocket.SendQueueSize <= 0 {
+		return fmt.Errorf("WebSocket send queue size must be positive")
+	}
+	if config.WebSocket.CriticalDeliveryDeadline <= 0 {
+		return fmt.Errorf("WebSocket critical delivery deadline must be positive")
+	}
+	if config.WebSocket.CompressionLevel < -2 || config.WebSocket.CompressionLevel > 9 {
+		return fmt.Errorf("WebSocket compression level must be between -2 and 9")
+	}
+	if config.WebSocket.CompressionThreshold < 0 {
+		return fmt.Errorf("WebSocket compression threshold cannot be negative")
+	}
+	if config.WebSocket.CanvasSnapshotChunkSize < 1024 {
+		return fmt.Errorf("WebSocket canvas snapshot chunk size must be at least 1024 bytes")
+	}
+
+	// Validate CORS config
+	if len(config.CORS.AllowedOrigins) == 0 {
+		return fmt.Errorf("at least one allowed origin is required")
+	}
+
+	// Validate session config
+	if config.Session.ResumeGracePeriod <= 0 {
+		return fmt.Errorf("session resume grace period must be positive")
+	}
+	if config.Session.MaxReplayMessages < 0 {
+		return fmt.Errorf("session max replay messages cannot be negative")
+	}
+
+	// Validate cluster config
+	if config.Cluster.Enabled {
+		if config.Cluster.NodeID == "" {
+			return fmt.Errorf("cluster node ID cannot be empty")
+		}
+		if config.Cluster.ListenAddr == "" {
+			return fmt.Errorf("cluster listen address cannot be empty")
+		}
+		// Peers are trusted to act for any user, so the bus must not be open
+		if len(config.Cluster.SharedSecret) < 32 {
+			return fmt.Errorf("cluster shared secret must be at least 32 characters")
+		}
+		seen := map[string]bool{config.Cluster.NodeID: true}
+		for _, peer := range config.Cluster.Peers {
+			if peer.NodeID == "" || peer.Addr == "" {
+				return fmt.Errorf("cluster peers need a node ID and address")
+			}
+			if seen[peer.NodeID] {
+				return fmt.Errorf("duplicate cluster node ID %q", peer.NodeID)
+			}
+			seen[peer.NodeID] = true
+		}
+	}
+
+	// Validate logging config; subsystem names are checked by the logging package
+	logLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+	if !logLevels[config.Logging.Level] {
+		return fmt.Errorf("log level must be debug, info, warn or error")
+	}
+	for subsystem, level := range config.Logging.Subsystems {
+		if !logLevels[level] {
+			return fmt.Errorf("log level for %s must be debug, info, warn or error", subsystem)
+		}
+	}
+	if config.Logging.Format != "json" && config.Logging.Format != "text" {
+		return fmt.Errorf("log format must be json or text")
+	}
+
+	// Validate storage config
+	if config.Storage.RoomsDir != "" && config.Storage.SnapshotInterval <= 0 {
+		return fmt.Errorf("room snapshot interval must be positive")
+	}
+	if config.Storage.GameHistorySize <= 0 {
+		return fmt.Errorf("game history size must be positive")
+	}
+
+	// Validate auth config
+	if config.Auth.JWTSecret != "" {
+		if len(config.Auth.JWTSecret) < 32 {
+			return fmt.Errorf("JWT secret must be at least 32 characters")
+		}
+		if config.Auth.AccessTokenTTL <= 0 {
+			return fmt.Errorf("access token TTL must be positive")
+		}
+		if config.Auth.RefreshTokenTTL <= config.Auth.AccessTokenTTL {
+			return fmt.Errorf("refresh token TTL must be longer than the access token TTL")
+		}
+		if config.Auth.PasswordIterations < 100000 {
+			return fmt.Errorf("password iterations must be at least 100000")
+		}
+	}
+
+	// Validate leaderboard config
+	if _, err := time.LoadLocation(config.Leaderboards.Timezone); err != nil {
+		return fmt.Errorf("invalid leaderboard time zone: %w", err)
+	}
+
+	// Validate rating and matchmaking config
+	if config.Rating.Initial <= 0 {
+		return fmt.Errorf("initial rating must be positive")
+	}
+	if config.Rating.KFactor <= 0 {
+		return fmt.Errorf("rating K-factor must be positive")
+	}
+	if config.Matchmaking.Mode != MatchmakingFill && config.Matchmaking.Mode != MatchmakingSkill {
+		return fmt.Errorf("matchmaking mode must be %q or %q", MatchmakingFill, MatchmakingSkill)
+	}
+	if config.Matchmaking.RatingGap < 0 || config.Matchmaking.RatingGapGrowth < 0 {
+		return fmt.Errorf("matchmaking rating gap and growth cannot be negative")
+	}
+	if config.Matchmaking.MaxRatingGap < config.Matchmaking.RatingGap {
+		return fmt.Errorf("max matchmaking rating gap cannot be below the initial gap")
+	}
+	if config.Matchmaking.QuickPlayGroupSize < config.Game.MinPlayersToStart || config.Matchmaking.QuickPlayGroupSize > config.Game.MaxPlayersPerRoom {
+		return fmt.Errorf("quick play group size must be between min players to start and max players per room")
+	}
+	if config.Matchmaking.QuickPlayMaxWait <= 0 {
+		return fmt.Errorf("quick play max wait must be positive")
+	}
+
+	// Validate admin config
+	if config.Admin.Token != "" && len(config.Admin.Token) < 16 {
+		return fmt.Errorf("admin token must be at least 16 characters")
+	}
+	if config.Admin.AuditLogSize <= 0 {
+		return fmt.Errorf("admin audit log size must be positive")
+	}
 
 	// Validate rate limit config
 	if config.RateLimit.RequestsPerMinute <= 0 {
@@ -221,6 +616,34 @@ func validateConfig(config *Config) error {
 	if config.RateLimit.BurstSize <= 0 {
 		return fmt.Errorf("burst size must be positive")
 	}
+	for _, proxy := range config.RateLimit.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+	}
+	if config.RateLimit.IdleTimeout <= 0 {
+		return fmt.Errorf("rate limit idle timeout must be positive")
+	}
+	for _, route := range config.RateLimit.Routes {
+		if route.PathPrefix == "" {
+			return fmt.Errorf("rate limit routes need a path prefix")
+		}
+		if !route.Exempt && (route.RequestsPerMinute <= 0 || route.BurstSize <= 0) {
+			return fmt.Errorf("rate limit for %s needs a positive rate and burst size", route.PathPrefix)
+		}
+	}
+	messages := config.RateLimit.Messages
+	for name, limit := range map[string]MessageRate{"drawing": messages.Drawing, "chat": messages.Chat, "auth": messages.Auth, "other": messages.Other} {
+		if limit.PerSecond <= 0 || limit.Burst <= 0 {
+			return fmt.Errorf("%s message rate and burst must be positive", name)
+		}
+	}
+	if messages.ViolationWindow <= 0 || messages.MuteDuration <= 0 {
+		return fmt.Errorf("message violation window and mute duration must be positive")
+	}
+	if messages.WarnAfter <= 0 || messages.MuteAfter < messages.WarnAfter || messages.DisconnectAfter < messages.MuteAfter {
+		return fmt.Errorf("message rate limit thresholds must be positive and in order: warn, mute, disconnect")
+	}
 
 	return nil
 }
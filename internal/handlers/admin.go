@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/middleware"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+	"github.com/gorilla/mux"
+)
+
+var adminLogger = logging.For("admin")
+
+// How long an admin request waits for a room's actor to run its action
+const adminActionTimeout = 5 * time.Second
+
+// Longest announcement operators may broadcast
+const maxAnnouncementLength = 500
+
+// Audit entries returned by the audit endpoint, by default and at most
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+var (
+	errRoomBusy    = errors.New("room mailbox is full")
+	errRoomTimeout = errors.New("room did not respond in time")
+)
+
+// adminActionRequest is the optional body of admin actions
+type adminActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// adminBanRequest is the body of a ban
+type adminBanRequest struct {
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"` // e.g. "24h"; empty bans permanently
+	BanIP    bool   `json:"ban_ip"`   // Also ban the address the player connected from
+}
+
+// adminStats summarizes the node for operators
+type adminStats struct {
+	Hub          *websocket.HubStats      `json:"hub"`
+	RoomsByState map[models.GameState]int `json:"rooms_by_state"`
+	RoomActors   int                      `json:"room_actors"`
+}
+
+// GetAdminStats returns hub and room statistics
+func GetAdminStats(hub *websocket.Hub, roomManager *services.RoomManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, adminStats{
+			Hub:          hub.GetStats(),
+			RoomsByState: roomManager.CountByState(),
+			RoomActors:   hub.GetActiveRoomActors(),
+		})
+	}
+}
+
+// ListAdminRooms returns every room on this node, public or private
+func ListAdminRooms(roomManager *services.RoomManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rooms := make([]*models.PublicRoomInfo, 0)
+		for _, room := range roomManager.GetAllRooms() {
+			rooms = append(rooms, room.GetPublicRoomInfo())
+		}
+		sort.Slice(rooms, func(i, j int) bool {
+			return rooms[i].ID < rooms[j].ID
+		})
+		writeAdminJSON(w, http.StatusOK, rooms)
+	}
+}
+
+// GetAdminRoom returns a room's full state, including the current word.
+// Viewing the word is audited.
+func GetAdminRoom(roomManager *services.RoomManager, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := mux.Vars(r)["roomID"]
+		room := roomManager.GetRoom(roomID)
+		if room == nil {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+
+		audit.Record(middleware.ClientIP(r), "view_room", roomID, nil)
+		writeAdminJSON(w, http.StatusOK, room.GetRoomState())
+	}
+}
+
+// ListAdminConnections returns the clients connected to this node
+func ListAdminConnections(hub *websocket.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, hub.GetConnections())
+	}
+}
+
+// AdminEndRound ends a room's current round as if its timer ran out
+func AdminEndRound(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := mux.Vars(r)["roomID"]
+		req, ok := decodeAdminRequest[adminActionRequest](w, r)
+		if !ok {
+			return
+		}
+		room := roomManager.GetRoom(roomID)
+		if room == nil {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+
+		ended := false
+		err := runOnRoom(hub, roomID, func() {
+			if room.IsDrawingRound(room.CurrentRound) {
+				HandleRoundEnd(hub, roomManager, gameEngine, roomID)
+				ended = true
+			}
+		})
+		if err != nil {
+			recordFailedAction(audit, r, "end_round", roomID, req.Reason, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if !ended {
+			http.Error(w, "No round in progress", http.StatusConflict)
+			return
+		}
+
+		audit.Record(middleware.ClientIP(r), "end_round", roomID, reasonDetails(req.Reason))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminEndGame ends a room's game and returns it to the lobby
+func AdminEndGame(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := mux.Vars(r)["roomID"]
+		req, ok := decodeAdminRequest[adminActionRequest](w, r)
+		if !ok {
+			return
+		}
+		room := roomManager.GetRoom(roomID)
+		if room == nil {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+
+		ended := false
+		err := runOnRoom(hub, roomID, func() {
+			if room.GetState() == models.GameStatePlaying {
+				HandleGameEnd(hub, roomManager, gameEngine, roomID)
+				ended = true
+			}
+		})
+		if err != nil {
+			recordFailedAction(audit, r, "end_game", roomID, req.Reason, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if !ended {
+			http.Error(w, "No game in progress", http.StatusConflict)
+			return
+		}
+
+		audit.Record(middleware.ClientIP(r), "end_game", roomID, reasonDetails(req.Reason))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminCloseRoom closes a room. Its players stay connected and are told
+// the room was closed.
+func AdminCloseRoom(hub *websocket.Hub, roomManager *services.RoomManager, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := mux.Vars(r)["roomID"]
+		req, ok := decodeAdminRequest[adminActionRequest](w, r)
+		if !ok {
+			return
+		}
+		room := roomManager.GetRoom(roomID)
+		if room == nil {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+
+		closed := false
+		err := runOnRoom(hub, roomID, func() {
+			playerIDs := append(room.GetPlayerIDs(), room.GetSpectatorIDs()...)
+			if !roomManager.CloseRoom(roomID) {
+				return
+			}
+			closed = true
+
+			msgData, err := newRoomClosedJSON(roomID, req.Reason)
+			for _, userID := range playerIDs {
+				hub.ReleaseSeat(roomID, userID)
+				if err == nil {
+					hub.SendToClient(userID, msgData)
+				}
+			}
+		})
+		if err != nil {
+			recordFailedAction(audit, r, "close_room", roomID, req.Reason, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if !closed {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+
+		audit.Record(middleware.ClientIP(r), "close_room", roomID, reasonDetails(req.Reason))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminKickPlayer removes a player from their room and disconnects them.
+// They may connect again unless banned.
+func AdminKickPlayer(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := mux.Vars(r)["userID"]
+		req, ok := decodeAdminRequest[adminActionRequest](w, r)
+		if !ok {
+			return
+		}
+
+		found, err := kickPlayer(hub, roomManager, gameEngine, userID, closeReason("kicked", req.Reason))
+		if err != nil {
+			recordFailedAction(audit, r, "kick_player", userID, req.Reason, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if !found {
+			http.Error(w, "Player not found", http.StatusNotFound)
+			return
+		}
+
+		audit.Record(middleware.ClientIP(r), "kick_player", userID, reasonDetails(req.Reason))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminBanPlayer bans a player's user ID, and optionally the IP they
+// connected from, then kicks them. Banning a player who isn't here still
+// bans the user ID.
+func AdminBanPlayer(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, bans *services.BanList, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := mux.Vars(r)["userID"]
+		req, ok := decodeAdminRequest[adminBanRequest](w, r)
+		if !ok {
+			return
+		}
+
+		var duration time.Duration
+		if req.Duration != "" {
+			parsed, err := time.ParseDuration(req.Duration)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "Invalid ban duration", http.StatusBadRequest)
+				return
+			}
+			duration = parsed
+		}
+
+		// The IP is only known while the player is connected to this node
+		var ip string
+		if req.BanIP {
+			if client, exists := hub.GetClientByUserID(userID); exists {
+				ip = client.RemoteAddr()
+			}
+			if ip == "" {
+				http.Error(w, "Player's IP is not known on this node", http.StatusConflict)
+				return
+			}
+		}
+
+		added := []*services.Ban{bans.Add(services.BanKindUser, userID, req.Reason, duration)}
+		if ip != "" {
+			added = append(added, bans.Add(services.BanKindIP, ip, req.Reason, duration))
+		}
+
+		_, kickErr := kickPlayer(hub, roomManager, gameEngine, userID, closeReason("banned", req.Reason))
+		if kickErr != nil {
+			adminLogger.ErrorContext(r.Context(), "kicking banned player", logging.UserID(userID), logging.Err(kickErr))
+		}
+
+		details := reasonDetails(req.Reason)
+		if details == nil {
+			details = make(map[string]string)
+		}
+		if kickErr != nil {
+			details["kick_error"] = kickErr.Error()
+		}
+		details["duration"] = req.Duration
+		if ip != "" {
+			details["ip"] = ip
+		}
+		audit.Record(middleware.ClientIP(r), "ban_player", userID, details)
+		writeAdminJSON(w, http.StatusCreated, added)
+	}
+}
+
+// ListAdminBans returns the bans in force
+func ListAdminBans(bans *services.BanList) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, bans.List())
+	}
+}
+
+// AdminLiftBan lifts a user or IP ban
+func AdminLiftBan(bans *services.BanList, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		kind, value := vars["kind"], vars["value"]
+		if kind != services.BanKindUser && kind != services.BanKindIP {
+			http.Error(w, "Ban kind must be user or ip", http.StatusBadRequest)
+			return
+		}
+		if !bans.Remove(kind, value) {
+			http.Error(w, "Ban not found", http.StatusNotFound)
+			return
+		}
+
+		audit.Record(middleware.ClientIP(r), "lift_ban", kind+":"+value, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminAnnounce broadcasts an announcement to every connected client
+func AdminAnnounce(hub *websocket.Hub, audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var data models.AnnouncementData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		data.Message = utils.SanitizeInput(data.Message)
+		if data.Message == "" || len(data.Message) > maxAnnouncementLength {
+			http.Error(w, fmt.Sprintf("Announcement must be 1 to %d characters", maxAnnouncementLength), http.StatusBadRequest)
+			return
+		}
+
+		msg, err := websocket.NewAnnouncementMessage(data.Message)
+		if err != nil {
+			adminLogger.Error("creating announcement message", logging.Err(err))
+			http.Error(w, "Failed to create announcement", http.StatusInternalServerError)
+			return
+		}
+		msgData, err := msg.ToJSON()
+		if err != nil {
+			adminLogger.Error("encoding announcement message", logging.Err(err))
+			http.Error(w, "Failed to create announcement", http.StatusInternalServerError)
+			return
+		}
+		hub.BroadcastToAll(msgData)
+
+		audit.Record(middleware.ClientIP(r), "announce", "", map[string]string{"message": data.Message})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetAdminAuditLog returns the most recent admin actions, newest first.
+// The limit query parameter caps how many are returned.
+func GetAdminAuditLog(audit *services.AuditLog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := limitParam(w, r, defaultAuditLimit, maxAuditLimit)
+		if !ok {
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, audit.Recent(limit))
+	}
+}
+
+// kickPlayer removes a player from their room on this node, if they are
+// seated in one, and disconnects them with reason. Returns false if the
+// player is neither seated nor connected here.
+func kickPlayer(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, userID, reason string) (bool, error) {
+	removed := false
+	if room := roomManager.FindPlayerRoom(userID); room != nil {
+		err := runOnRoom(hub, room.ID, func() {
+			if player, exists := room.GetPlayer(userID); exists {
+				removePlayer(hub, roomManager, gameEngine, room, player)
+				removed = true
+			}
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// The seat is released first so the disconnect doesn't hold it open
+	client, connected := hub.GetClientByUserID(userID)
+	if connected {
+		client.Kick(reason)
+	}
+	return removed || connected, nil
+}
+
+// runOnRoom runs task on the room's actor, serialized with the room's
+// messages and timers, and waits for it to finish. A task the actor hasn't
+// started by the timeout is cancelled, so an action reported as failed
+// never takes effect later.
+func runOnRoom(hub *websocket.Hub, roomID string, task func()) error {
+	var mutex sync.Mutex
+	started, cancelled := false, false
+	done := make(chan struct{})
+	if !hub.DispatchToRoom(roomID, func() {
+		mutex.Lock()
+		if cancelled {
+			mutex.Unlock()
+			return
+		}
+		started = true
+		mutex.Unlock()
+
+		defer close(done)
+		task()
+	}) {
+		return errRoomBusy
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(adminActionTimeout):
+	}
+
+	mutex.Lock()
+	if !started {
+		cancelled = true
+		mutex.Unlock()
+		return errRoomTimeout
+	}
+	mutex.Unlock()
+
+	// Too late to cancel; report what the task did
+	<-done
+	return nil
+}
+
+// newRoomClosedJSON encodes a room_closed message
+func newRoomClosedJSON(roomID, reason string) ([]byte, error) {
+	msg, err := websocket.NewRoomClosedMessage(roomID, reason)
+	if err != nil {
+		adminLogger.Error("creating room closed message", logging.Err(err))
+		return nil, err
+	}
+	msgData, err := msg.ToJSON()
+	if err != nil {
+		adminLogger.Error("encoding room closed message", logging.Err(err))
+		return nil, err
+	}
+	return msgData, nil
+}
+
+// decodeAdminRequest decodes an optional JSON body. An empty body decodes
+// as the zero value; a malformed one is answered with 400.
+func decodeAdminRequest[T any](w http.ResponseWriter, r *http.Request) (T, bool) {
+	var req T
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// closeReason builds the close frame reason for a kicked player
+func closeReason(action, reason string) string {
+	if reason == "" {
+		return action
+	}
+	return action + ": " + reason
+}
+
+// reasonDetails returns audit details holding reason, or nil without one
+// recordFailedAction audits an action that didn't take effect because its
+// room couldn't run it
+func recordFailedAction(audit *services.AuditLog, r *http.Request, action, target, reason string, err error) {
+	details := reasonDetails(reason)
+	if details == nil {
+		details = make(map[string]string)
+	}
+	details["error"] = err.Error()
+	audit.Record(middleware.ClientIP(r), action, target, details)
+}
+
+func reasonDetails(reason string) map[string]string {
+	if reason == "" {
+		return nil
+	}
+	return map[string]string{"reason": reason}
+}
+
+// writeAdminJSON writes a JSON response
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
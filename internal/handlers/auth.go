@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/auth"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/middleware"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// Signup creates an account and returns its tokens
+func Signup(accounts *services.AccountManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !accounts.Enabled() {
+			http.NotFound(w, r)
+			return
+		}
+
+		var data models.SignupData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !utils.ValidateUserName(data.Username) {
+			http.Error(w, "Invalid username", http.StatusBadRequest)
+			return
+		}
+		if !validPassword(data.Password) {
+			http.Error(w, "Password must be 8 to 128 characters", http.StatusBadRequest)
+			return
+		}
+
+		account, err := accounts.Create(utils.GenerateUserID(), data.Username, data.Password, utils.SanitizeInput(data.Avatar))
+		if errors.Is(err, services.ErrUsernameTaken) {
+			http.Error(w, "Username is taken", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, services.ErrHashingBusy) {
+			http.Error(w, "Server is busy, try again", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "creating account", logging.Err(err))
+			http.Error(w, "Failed to create account", http.StatusInternalServerError)
+			return
+		}
+		writeAuthenticated(w, r, accounts, account, http.StatusCreated)
+	}
+}
+
+// Login checks a username and password and returns the account's tokens
+func Login(accounts *services.AccountManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !accounts.Enabled() {
+			http.NotFound(w, r)
+			return
+		}
+
+		var data models.LoginData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		account, err := accounts.Login(data.Username, data.Password)
+		if errors.Is(err, services.ErrHashingBusy) {
+			http.Error(w, "Server is busy, try again", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			logger.InfoContext(r.Context(), "failed login", "client_ip", middleware.ClientIP(r))
+			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+			return
+		}
+		writeAuthenticated(w, r, accounts, account, http.StatusOK)
+	}
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair
+func RefreshTokens(accounts *services.AccountManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !accounts.Enabled() {
+			http.NotFound(w, r)
+			return
+		}
+
+		var data models.RefreshData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		account, tokens, err := accounts.Refresh(data.RefreshToken)
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) {
+			http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "refreshing tokens", logging.Err(err))
+			http.Error(w, "Failed to refresh tokens", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(authenticatedData(account, tokens))
+	}
+}
+
+// GetCurrentAccount returns the account a request is authenticated as
+func GetCurrentAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account, _ := middleware.AccountFrom(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(account.ToPublicAccount())
+	}
+}
+
+// handleRegister turns the connected guest into an account. They keep
+// their user ID, and with it their room, score and session.
+func handleRegister(hub *websocket.Hub, accounts *services.AccountManager, client *websocket.Client, message *websocket.Message) {
+	if sendCachedReply(hub, client, message) {
+		return
+	}
+	if !accounts.Enabled() {
+		sendClientError(client, message, "Accounts are disabled", "ACCOUNTS_DISABLED")
+		return
+	}
+
+	user := client.GetUser()
+	if !user.IsGuest() {
+		sendClientError(client, message, "Already registered", "ALREADY_REGISTERED")
+		return
+	}
+
+	var data models.RegisterData
+	if err := message.UnmarshalData(&data); err != nil {
+		sendClientError(client, message, "Invalid register data", "INVALID_DATA")
+		return
+	}
+	username := user.Username
+	if data.Username != "" && data.Username != username {
+		// Other players know them by their current name
+		if client.GetRoomID() != "" {
+			sendClientError(client, message, "Can't change username while in a room", "IN_ROOM")
+			return
+		}
+		username = data.Username
+	}
+	if !utils.ValidateUserName(username) {
+		sendClientError(client, message, "Invalid username", "INVALID_USERNAME")
+		return
+	}
+	if !validPassword(data.Password) {
+		sendClientError(client, message, "Password must be 8 to 128 characters", "INVALID_PASSWORD")
+		return
+	}
+
+	// Hashing the password is deliberately slow, so keep it off the room's actor
+	go func() {
+		account, err := accounts.Create(user.ID, username, data.Password, user.Avatar)
+		switch {
+		case errors.Is(err, services.ErrUsernameTaken):
+			sendClientError(client, message, "Username is taken", "USERNAME_TAKEN")
+			return
+		case errors.Is(err, services.ErrAccountExists):
+			sendClientError(client, message, "Already registered", "ALREADY_REGISTERED")
+			return
+		case errors.Is(err, services.ErrHashingBusy):
+			sendClientError(client, message, "Server is busy, try again", "SERVER_BUSY")
+			return
+		case err != nil:
+			logger.Error("creating account", logging.UserID(user.ID), logging.Err(err))
+			sendClientError(client, message, "Failed to create account", "REGISTRATION_FAILED")
+			return
+		}
+		user.Register(account.Username)
+
+		tokens, err := accounts.IssueTokens(account)
+		if err != nil {
+			logger.Error("issuing tokens", logging.UserID(user.ID), logging.Err(err))
+			sendClientError(client, message, "Failed to issue tokens", "REGISTRATION_FAILED")
+			return
+		}
+		msg, err := websocket.NewAuthenticatedMessage(authenticatedData(account, tokens))
+		if err != nil {
+			sendClientError(client, message, "Failed to create authenticated message", "MESSAGE_CREATION_FAILED")
+			return
+		}
+		sendReply(hub, client, message, msg, true)
+	}()
+}
+
+// signIn switches a connected client over to the account an access token
+// was issued to. Returns false after replying with an error if it can't.
+func signIn(hub *websocket.Hub, accounts *services.AccountManager, bans *services.BanList, client *websocket.Client, message *websocket.Message, accessToken string) bool {
+	if !accounts.Enabled() {
+		sendClientError(client, message, "Accounts are disabled", "ACCOUNTS_DISABLED")
+		return false
+	}
+	account, err := accounts.Authenticate(accessToken)
+	if err != nil {
+		sendClientError(client, message, "Invalid or expired access token", "INVALID_TOKEN")
+		return false
+	}
+	if client.GetUser().ID == account.ID {
+		return true
+	}
+	if client.GetRoomID() != "" {
+		sendClientError(client, message, "Leave your room before signing in", "IN_ROOM")
+		return false
+	}
+	if _, banned := bans.Check(account.ID, ""); banned {
+		client.Kick("banned")
+		return false
+	}
+
+	hub.SwitchUser(client, account.NewUser())
+	return true
+}
+
+// validPassword checks a new password's length
+func validPassword(password string) bool {
+	return len(password) >= services.MinPasswordLength && len(password) <= services.MaxPasswordLength
+}
+
+// authenticatedData combines an account with its tokens
+func authenticatedData(account *models.Account, tokens *auth.TokenPair) models.AuthenticatedData {
+	return models.AuthenticatedData{
+		Account:      account.ToPublicAccount(),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	}
+}
+
+// writeAuthenticated issues tokens for an account and writes them with status
+func writeAuthenticated(w http.ResponseWriter, r *http.Request, accounts *services.AccountManager, account *models.Account, status int) {
+	tokens, err := accounts.IssueTokens(account)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "issuing tokens", logging.UserID(account.ID), logging.Err(err))
+		http.Error(w, "Failed to issue tokens", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(authenticatedData(account, tokens))
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// sendCanvasSnapshot sends a client what has been drawn so far this round,
+// so players who join or come back mid-round don't see a blank canvas. Only
+// drawing commands are sent, never the word.
+func sendCanvasSnapshot(client *websocket.Client, room *models.Room) {
+	round, drawerID, commands := room.GetCanvas()
+	if len(commands) == 0 {
+		return
+	}
+
+	chunkSize := config.GetConfig().WebSocket.CanvasSnapshotChunkSize
+	messages, err := websocket.NewCanvasSnapshotMessages(round, drawerID, commands, chunkSize)
+	if err != nil {
+		logger.Error("creating canvas snapshot messages", logging.RoomID(room.ID), logging.Err(err))
+		return
+	}
+	for _, msg := range messages {
+		if err := client.SendMessage(msg); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Room messages an event stream can queue before dropping new ones
+	eventStreamBuffer = 256
+
+	// How often an idle event stream sends a comment to keep proxies from
+	// closing it
+	eventStreamKeepAlive = 15 * time.Second
+)
+
+// Room events forwarded to event streams. Chat is left out since guesses
+// can contain the word.
+var streamedEventTypes = map[models.MessageType]bool{
+	models.MessageTypeGameStarted:        true,
+	models.MessageTypeNewRound:           true,
+	models.MessageTypeRoundEnded:         true,
+	models.MessageTypeGameEnded:          true,
+	models.MessageTypePlayerJoined:       true,
+	models.MessageTypePlayerLeft:         true,
+	models.MessageTypePlayerDisconnected: true,
+	models.MessageTypePlayerReconnected:  true,
+	models.MessageTypeDrawData:           true,
+	models.MessageTypeClearCanvas:        true,
+	models.MessageTypeTimer:              true,
+	models.MessageTypePointsAwarded:      true,
+	models.MessageTypeLeaderboard:        true,
+}
+
+// Fields that may hold the secret word and are removed from streamed events
+var secretEventFields = []string{"word", "current_word"}
+
+// RoomSnapshot is the first event on a room event stream
+type RoomSnapshot struct {
+	Room        *models.PublicRoomInfo `json:"room"`
+	Leaderboard []*models.PublicUser   `json:"leaderboard"`
+	Drawing     []models.DrawCommand   `json:"drawing"`
+}
+
+// StreamRoomEvents serves a room's broadcasts as Server-Sent Events, for
+// overlays and clients that can't use WebSockets
+func StreamRoomEvents(hub *websocket.Hub, roomManager *services.RoomManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := mux.Vars(r)["roomID"]
+
+		// Rooms owned by another node can be streamed, but only this node
+		// can tell whether a room it owns exists
+		room := roomManager.GetRoom(roomID)
+		if room == nil && hub.IsLocalKey(roomID) {
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		}
+
+		// The stream outlives the server's write timeout
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			logger.WarnContext(r.Context(), "clearing write deadline for event stream", logging.Err(err))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// Subscribe before taking the snapshot so no events fall in between
+		observer := hub.ObserveRoom(roomID, eventStreamBuffer)
+		defer hub.StopObserving(observer)
+
+		if room != nil {
+			snapshot := RoomSnapshot{
+				Room:        room.GetPublicRoomInfo(),
+				Leaderboard: getLeaderboard(room),
+				Drawing:     room.GetDrawingData(),
+			}
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "encoding room snapshot", logging.RoomID(roomID), logging.Err(err))
+				return
+			}
+			writeEvent(w, "snapshot", data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(eventStreamKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case message, ok := <-observer.Events():
+				if !ok {
+					return
+				}
+				eventType, data, ok := streamableEvent(message)
+				if !ok {
+					continue
+				}
+				writeEvent(w, string(eventType), data)
+				if err := rc.Flush(); err != nil {
+					return
+				}
+
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				if err := rc.Flush(); err != nil {
+					return
+				}
+
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// streamableEvent decides whether a room message goes out on event streams
+// and strips anything that could reveal the word
+func streamableEvent(message []byte) (models.MessageType, []byte, bool) {
+	var msg models.Message
+	if err := json.Unmarshal(message, &msg); err != nil || !streamedEventTypes[msg.Type] {
+		return "", nil, false
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Data, &data); err == nil {
+		stripped := false
+		for _, field := range secretEventFields {
+			if _, exists := data[field]; exists {
+				delete(data, field)
+				stripped = true
+			}
+		}
+		if stripped {
+			if msg.Data, err = json.Marshal(data); err != nil {
+				return "", nil, false
+			}
+			if message, err = json.Marshal(msg); err != nil {
+				return "", nil, false
+			}
+		}
+	}
+
+	return msg.Type, message, true
+}
+
+// writeEvent writes one Server-Sent Event. The data is single-line JSON.
+func writeEvent(w http.ResponseWriter, event string, data []byte) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -1,15 +1,16 @@
 package handlers
 
 import (
-	"log"
-
 	"time"
 
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
 	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
 )
 
+var logger = logging.For("game")
+
 // HandleGameStart starts a new game
 func HandleGameStart(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID string) {
 	room := roomManager.GetRoom(roomID)
@@ -19,17 +20,19 @@ func HandleGameStart(hub *websocket.Hub, roomManager *services.RoomManager, game
 
 	room.StartGame()
 	gameEngine.StartGame(room)
+	gamesStarted.Inc()
+	roomManager.SaveRoom(roomID)
 
 	// Notify players
 	roomInfo := room.GetPublicRoomInfo()
 	msg, err := websocket.NewGameStartedMessage(roomInfo)
 	if err != nil {
-		log.Printf("Error creating game started message: %v", err)
+		logger.Error("creating game started message", logging.Err(err))
 		return
 	}
 	msgData, err := msg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting message to JSON: %v", err)
+		logger.Error("encoding game started message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, msgData, nil)
@@ -50,10 +53,13 @@ func HandleNewRound(hub *websocket.Hub, roomManager *services.RoomManager, gameE
 
 	drawer, exists := room.GetPlayer(room.CurrentDrawer)
 	if !exists {
-		log.Printf("Drawer not found for room %s", roomID)
+		logger.Warn("drawer not found, ending round", logging.RoomID(roomID), logging.UserID(room.CurrentDrawer))
 		HandleRoundEnd(hub, roomManager, gameEngine, roomID)
 		return
 	}
+	logger.Info("round started", logging.RoomID(roomID), logging.UserID(drawer.ID),
+		"round", room.CurrentRound, logging.Sensitive("word", word))
+	roomManager.SaveRoom(roomID)
 
 	// Send new round message to drawer (with actual word)
 	drawerData := websocket.NewRoundData{
@@ -67,7 +73,7 @@ func HandleNewRound(hub *websocket.Hub, roomManager *services.RoomManager, gameE
 	}
 	drawerMsg, err := websocket.NewNewRoundMessage(drawerData)
 	if err != nil {
-		log.Printf("Error creating new round message: %v", err)
+		logger.Error("creating new round message", logging.Err(err))
 		return
 	}
 	if client, exists := hub.GetClientByUserID(room.CurrentDrawer); exists {
@@ -85,12 +91,12 @@ func HandleNewRound(hub *websocket.Hub, roomManager *services.RoomManager, gameE
 	}
 	othersMsg, err := websocket.NewNewRoundMessage(othersData)
 	if err != nil {
-		log.Printf("Error creating new round message: %v", err)
+		logger.Error("creating new round message", logging.Err(err))
 		return
 	}
 	othersMsgData, err := othersMsg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting others message to JSON: %v", err)
+		logger.Error("encoding others message", logging.Err(err))
 		return
 	}
 
@@ -102,7 +108,7 @@ func HandleNewRound(hub *websocket.Hub, roomManager *services.RoomManager, gameE
 	hub.BroadcastToRoom(roomID, othersMsgData, excludeClient)
 
 	// Start timer
-	go runRoundTimer(hub, roomManager, gameEngine, roomID)
+	go runRoundTimer(hub, roomManager, gameEngine, roomID, room.CurrentRound)
 }
 
 // HandleRoundEnd ends the current round
@@ -152,11 +158,15 @@ func HandleRoundEnd(hub *websocket.Hub, roomManager *services.RoomManager, gameE
 		}
 	}
 
-	drawer, _ := room.GetPlayer(room.CurrentDrawer)
+	// The drawer may have been removed from the room mid-round
+	drawerName := ""
+	if drawer, exists := room.GetPlayer(room.CurrentDrawer); exists {
+		drawerName = drawer.Username
+	}
 	roundEndData := websocket.RoundEndData{
 		Word:         room.CurrentWord,
 		DrawerID:     room.CurrentDrawer,
-		DrawerName:   drawer.Username,
+		DrawerName:   drawerName,
 		DrawerPoints: drawerPoints,
 		Guessers:     guessers,
 		Leaderboard:  getLeaderboard(room),
@@ -168,27 +178,33 @@ func HandleRoundEnd(hub *websocket.Hub, roomManager *services.RoomManager, gameE
 		roundEndData.NextRound = 0 // Indicate game is ending
 	}
 
-	room.EndRound()
-	gameEngine.EndRound(room)
+	gameEngine.EndRound(room, roundEndData)
+	roundsPlayed.Inc()
+	roomManager.SaveRoom(roomID)
 
 	// Send round end message
 	msg, err := websocket.NewRoundEndedMessage(roundEndData)
 	if err != nil {
-		log.Printf("Error creating round ended message: %v", err)
+		logger.Error("creating round ended message", logging.Err(err))
 		return
 	}
 	msgData, err := msg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting round ended message to JSON: %v", err)
+		logger.Error("encoding round ended message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, msgData, nil)
 
-	// Check if game should end
+	continueGame(hub, roomManager, gameEngine, room)
+}
+
+// continueGame starts the next round, or ends the game after the last one.
+// No new rounds start while the server drains.
+func continueGame(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, room *models.Room) {
 	if room.CurrentRound >= room.MaxRounds {
-		HandleGameEnd(hub, roomManager, gameEngine, roomID)
-	} else {
-		HandleNewRound(hub, roomManager, gameEngine, roomID)
+		HandleGameEnd(hub, roomManager, gameEngine, room.ID)
+	} else if !hub.Draining() {
+		HandleNewRound(hub, roomManager, gameEngine, room.ID)
 	}
 }
 
@@ -233,54 +249,79 @@ func HandleGameEnd(hub *websocket.Hub, roomManager *services.RoomManager, gameEn
 		},
 	}
 
-	room.EndGame()
+	profiles := gameEngine.EndGame(room, gameEndData)
+	gamesFinished.Inc()
+	roomManager.SaveRoom(roomID)
 
 	// Send game end message
 	msg, err := websocket.NewGameEndedMessage(gameEndData)
 	if err != nil {
-		log.Printf("Error creating game ended message: %v", err)
+		logger.Error("creating game ended message", logging.Err(err))
 		return
 	}
 	msgData, err := msg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting game ended message to JSON: %v", err)
+		logger.Error("encoding game ended message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, msgData, nil)
+	sendProfiles(hub, profiles)
 }
 
-// runRoundTimer manages the round timer
-func runRoundTimer(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID string) {
-	room := roomManager.GetRoom(roomID)
-	if room == nil {
-		return
-	}
-
+// runRoundTimer ticks once a second for the given round. Each tick is
+// dispatched to the room's actor so it is serialized with player messages.
+func runRoundTimer(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID string, round int) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			timeLeft := room.GetTimeLeft()
-			timerMsg, err := websocket.NewTimerMessage(timeLeft, string(room.Phase))
-			if err != nil {
-				log.Printf("Error creating timer message: %v", err)
-				continue
-			}
-			timerData, err := timerMsg.ToJSON()
-			if err != nil {
-				log.Printf("Error converting timer message to JSON: %v", err)
-				continue
-			}
-			hub.BroadcastToRoom(roomID, timerData, nil)
-
-			if timeLeft <= 0 {
-				HandleRoundEnd(hub, roomManager, gameEngine, roomID)
-				return
-			}
+		case <-gameEngine.Stopped():
+			return
+		}
+		if !isRoundActive(roomManager, roomID, round) {
+			return
 		}
+		hub.DispatchToRoom(roomID, func() {
+			handleRoundTick(hub, roomManager, gameEngine, roomID, round)
+		})
+	}
+}
+
+// handleRoundTick broadcasts the time left and ends the round when it runs out
+func handleRoundTick(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID string, round int) {
+	// The round may have ended while this tick was queued
+	if !isRoundActive(roomManager, roomID, round) {
+		return
+	}
+	room := roomManager.GetRoom(roomID)
+
+	timeLeft := room.GetTimeLeft()
+	timerMsg, err := websocket.NewTimerMessage(timeLeft, string(room.Phase))
+	if err != nil {
+		logger.Error("creating timer message", logging.Err(err))
+		return
+	}
+	timerData, err := timerMsg.ToJSON()
+	if err != nil {
+		logger.Error("encoding timer message", logging.Err(err))
+		return
+	}
+	hub.BroadcastToRoom(roomID, timerData, nil)
+
+	if timeLeft <= 0 {
+		HandleRoundEnd(hub, roomManager, gameEngine, roomID)
+	}
+}
+
+// isRoundActive checks that the room is still drawing the given round
+func isRoundActive(roomManager *services.RoomManager, roomID string, round int) bool {
+	room := roomManager.GetRoom(roomID)
+	if room == nil {
+		return false
 	}
+	return room.IsDrawingRound(round)
 }
 
 // contains checks if a slice contains a string
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// Games returned by the list endpoints, by default and at most
+const (
+	defaultGamesLimit = 20
+	maxGamesLimit     = 100
+)
+
+// GetRecentGames returns the most recently finished games, newest first
+func GetRecentGames(history *services.GameHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := limitParam(w, r, defaultGamesLimit, maxGamesLimit)
+		if !ok {
+			return
+		}
+		writeGamesJSON(w, history.Recent(limit))
+	}
+}
+
+// GetGame returns a finished game with its rounds
+func GetGame(history *services.GameHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		gameID := vars["gameID"]
+
+		record, exists := history.Get(gameID)
+		if !exists {
+			http.Error(w, "Game not found", http.StatusNotFound)
+			return
+		}
+		writeGamesJSON(w, record)
+	}
+}
+
+// GetRoomGames returns the games finished in a room, newest first
+func GetRoomGames(history *services.GameHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := limitParam(w, r, defaultGamesLimit, maxGamesLimit)
+		if !ok {
+			return
+		}
+		vars := mux.Vars(r)
+		writeGamesJSON(w, history.ListByRoom(vars["roomID"], limit))
+	}
+}
+
+// GetPlayerGames returns the games a player took part in, newest first
+func GetPlayerGames(history *services.GameHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := limitParam(w, r, defaultGamesLimit, maxGamesLimit)
+		if !ok {
+			return
+		}
+		vars := mux.Vars(r)
+		writeGamesJSON(w, history.ListByPlayer(vars["userID"], limit))
+	}
+}
+
+// limitParam reads the limit query parameter, capped at maxLimit, answering
+// 400 if it is invalid
+func limitParam(w http.ResponseWriter, r *http.Request, defaultLimit, maxLimit int) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return 0, false
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, true
+}
+
+func writeGamesJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
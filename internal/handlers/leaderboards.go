@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+	"github.com/gorilla/mux"
+)
+
+// Players returned by the leaderboard endpoints, by default and at most
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// GetGlobalLeaderboard returns a window's top players, ranked by the
+// category in the by query parameter
+func GetGlobalLeaderboard(leaderboards *services.Leaderboards) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, ok := leaderboardCategory(r.URL.Query().Get("by"))
+		if !ok {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return
+		}
+		limit, ok := limitParam(w, r, defaultLeaderboardLimit, maxLeaderboardLimit)
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		data, exists := leaderboards.Top(models.LeaderboardWindow(vars["window"]), category, limit)
+		if !exists {
+			http.Error(w, "Leaderboard not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+	}
+}
+
+// handleGetGlobalLeaderboard sends a window's top players
+func handleGetGlobalLeaderboard(hub *websocket.Hub, leaderboards *services.Leaderboards, client *websocket.Client, message *websocket.Message) {
+	var data models.GetGlobalLeaderboardData
+	if err := message.UnmarshalData(&data); err != nil {
+		sendClientError(client, message, "Invalid leaderboard data", "INVALID_DATA")
+		return
+	}
+	category, ok := leaderboardCategory(string(data.Category))
+	if !ok {
+		sendClientError(client, message, "Invalid leaderboard category", "INVALID_CATEGORY")
+		return
+	}
+	limit := data.Limit
+	if limit <= 0 {
+		limit = defaultLeaderboardLimit
+	}
+	if limit > maxLeaderboardLimit {
+		limit = maxLeaderboardLimit
+	}
+
+	leaderboard, exists := leaderboards.Top(data.Window, category, limit)
+	if !exists {
+		sendClientError(client, message, "Leaderboard not found", "LEADERBOARD_NOT_FOUND")
+		return
+	}
+	msg, err := websocket.NewGlobalLeaderboardMessage(leaderboard)
+	if err != nil {
+		sendClientError(client, message, "Failed to create leaderboard message", "MESSAGE_CREATION_FAILED")
+		return
+	}
+	sendReply(hub, client, message, msg, false)
+}
+
+// leaderboardCategory parses a leaderboard category, defaulting to score
+func leaderboardCategory(value string) (models.LeaderboardCategory, bool) {
+	switch category := models.LeaderboardCategory(value); category {
+	case "":
+		return models.LeaderboardByScore, true
+	case models.LeaderboardByScore, models.LeaderboardByWins, models.LeaderboardByCorrectGuesses, models.LeaderboardByDrawerPoints:
+		return category, true
+	default:
+		return "", false
+	}
+}
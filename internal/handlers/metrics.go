@@ -0,0 +1,31 @@
+package handlers
+
+import "github.com/RITWIZSINGH/DoodleDash-backend/pkg/metrics"
+
+var (
+	gamesStarted = metrics.Default.NewCounter(
+		"doodledash_games_started_total",
+		"Games started.")
+
+	gamesFinished = metrics.Default.NewCounter(
+		"doodledash_games_finished_total",
+		"Games that reached the end, including ones ended early.")
+
+	roundsPlayed = metrics.Default.NewCounter(
+		"doodledash_rounds_played_total",
+		"Rounds that ran to their end.")
+
+	guesses = metrics.Default.NewCounterVec(
+		"doodledash_guesses_total",
+		"Guesses checked against the word, by result (correct or incorrect).",
+		"result")
+)
+
+// recordGuess counts a checked guess
+func recordGuess(correct bool) {
+	if correct {
+		guesses.WithLabelValues("correct").Inc()
+		return
+	}
+	guesses.WithLabelValues("incorrect").Inc()
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// HandlePlayerDisconnected holds a dropped player's seat until they resume
+// or the grace period runs out. A drawer who drops ends the round so the
+// game doesn't stall on an empty canvas.
+func HandlePlayerDisconnected(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID, userID string) {
+	room := roomManager.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+	if spectator, exists := room.GetSpectator(userID); exists {
+		if !spectator.Connected() {
+			removeSpectator(hub, roomManager, roomID, spectator)
+		}
+		return
+	}
+	player, exists := room.GetPlayer(userID)
+	if !exists || player.Connected() {
+		return
+	}
+
+	schedulePlayerTimeout(hub, roomManager, gameEngine, roomID, userID)
+
+	broadcastPlayerEvent(hub, roomID, player, websocket.NewPlayerDisconnectedMessage)
+
+	if room.CurrentDrawer == userID && room.IsDrawingRound(room.CurrentRound) {
+		logger.Info("drawer disconnected, ending round", logging.RoomID(roomID), logging.UserID(userID))
+		HandleRoundEnd(hub, roomManager, gameEngine, roomID)
+	}
+}
+
+// HandlePlayerReconnected tells the room that a dropped player is back and
+// catches the player up on the canvas
+func HandlePlayerReconnected(hub *websocket.Hub, roomManager *services.RoomManager, roomID, userID string) {
+	// Only players whose seat was being held were announced as gone
+	announce := roomManager.CancelRemoval(roomID, userID)
+
+	room := roomManager.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+	player, exists := room.GetPlayer(userID)
+	if !exists {
+		return
+	}
+
+	// The canvas may have changed while they were away
+	if client, connected := hub.GetClientByUserID(userID); connected {
+		sendCanvasSnapshot(client, room)
+	}
+	if !announce {
+		return
+	}
+	broadcastPlayerEvent(hub, roomID, player, websocket.NewPlayerReconnectedMessage)
+}
+
+// schedulePlayerTimeout removes a disconnected player from the room once
+// the grace period runs out. A removal that can't be queued on the room's
+// actor is tried again shortly rather than lost.
+func schedulePlayerTimeout(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID, userID string) {
+	var timeout func()
+	timeout = func() {
+		if !hub.DispatchToRoom(roomID, func() {
+			handlePlayerTimeout(hub, roomManager, gameEngine, roomID, userID)
+		}) {
+			logger.Warn("room mailbox is full, retrying player removal", logging.RoomID(roomID), logging.UserID(userID))
+			roomManager.RetryRemoval(roomID, userID, timeout)
+		}
+	}
+	roomManager.ScheduleRemoval(roomID, userID, timeout)
+}
+
+// handlePlayerTimeout removes a player who didn't come back in time from the
+// room and its drawer rotation, and ends the game if too few are left
+func handlePlayerTimeout(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID, userID string) {
+	room := roomManager.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+	player, exists := room.GetPlayer(userID)
+	if !exists || player.Connected() {
+		return
+	}
+
+	removePlayer(hub, roomManager, gameEngine, room, player)
+	logger.Info("removed player after disconnect timeout", logging.RoomID(roomID), logging.UserID(userID))
+}
+
+// removePlayer takes a player out of a room and its drawer rotation. A
+// drawer removed mid-round ends the round, and the game ends if too few
+// players are left.
+func removePlayer(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, room *models.Room, player *models.User) {
+	roomID := room.ID
+	wasDrawing := room.CurrentDrawer == player.ID && room.IsDrawingRound(room.CurrentRound)
+
+	roomManager.CancelRemoval(roomID, player.ID)
+	roomManager.LeaveRoom(roomID, player.ID)
+	hub.ReleaseSeat(roomID, player.ID)
+
+	broadcastPlayerEvent(hub, roomID, player, websocket.NewPlayerLeftMessage)
+
+	// The room is gone if it was the last player
+	if roomManager.GetRoom(roomID) == nil {
+		closeForSpectators(hub, room)
+		return
+	}
+	if room.State == models.GameStatePlaying && !gameEngine.HasEnoughPlayers(room) {
+		logger.Info("not enough players left, ending game", logging.RoomID(roomID))
+		HandleGameEnd(hub, roomManager, gameEngine, roomID)
+		return
+	}
+	if wasDrawing {
+		HandleRoundEnd(hub, roomManager, gameEngine, roomID)
+	}
+}
+
+// broadcastPlayerEvent sends a player_* message about player to the room
+func broadcastPlayerEvent(hub *websocket.Hub, roomID string, player *models.User, newMessage func(*models.PublicUser) (*websocket.Message, error)) {
+	msg, err := newMessage(player.ToPublicUser())
+	if err != nil {
+		logger.Error("creating player message", logging.Err(err))
+		return
+	}
+	msgData, err := msg.ToJSON()
+	if err != nil {
+		logger.Error("encoding player message", logging.Err(err))
+		return
+	}
+	hub.BroadcastToRoom(roomID, msgData, nil)
+}
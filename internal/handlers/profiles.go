@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+	"github.com/gorilla/mux"
+)
+
+// GetPlayerProfile returns a registered player's lifetime statistics
+func GetPlayerProfile(profiles *services.ProfileStore, accounts *services.AccountManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		profile, exists := findProfile(profiles, accounts, vars["userID"])
+		if !exists {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profile)
+	}
+}
+
+// handleGetProfile sends a player's profile, by default the client's own
+func handleGetProfile(hub *websocket.Hub, profiles *services.ProfileStore, accounts *services.AccountManager, client *websocket.Client, message *websocket.Message) {
+	var data models.GetProfileData
+	if err := message.UnmarshalData(&data); err != nil {
+		sendClientError(client, message, "Invalid profile data", "INVALID_DATA")
+		return
+	}
+	if data.UserID == "" {
+		data.UserID = client.GetUser().ID
+	}
+
+	profile, exists := findProfile(profiles, accounts, data.UserID)
+	if !exists {
+		sendClientError(client, message, "Profile not found", "PROFILE_NOT_FOUND")
+		return
+	}
+	msg, err := websocket.NewProfileMessage(profile)
+	if err != nil {
+		sendClientError(client, message, "Failed to create profile message", "MESSAGE_CREATION_FAILED")
+		return
+	}
+	sendReply(hub, client, message, msg, false)
+}
+
+// sendProfiles sends players their profiles updated by a finished game
+func sendProfiles(hub *websocket.Hub, profiles []*models.PublicProfile) {
+	for _, profile := range profiles {
+		msg, err := websocket.NewProfileMessage(profile)
+		if err != nil {
+			logger.Error("creating profile message", logging.Err(err))
+			continue
+		}
+		msgData, err := msg.ToJSON()
+		if err != nil {
+			logger.Error("encoding profile message", logging.Err(err))
+			continue
+		}
+		hub.SendToClient(profile.UserID, msgData)
+	}
+}
+
+// findProfile returns a player's profile. Registered players who haven't
+// finished a round yet get an empty one; guests have none.
+func findProfile(profiles *services.ProfileStore, accounts *services.AccountManager, userID string) (*models.PublicProfile, bool) {
+	if profile, exists := profiles.Get(userID); exists {
+		return profile, true
+	}
+	account, exists := accounts.Get(userID)
+	if !exists {
+		return nil, false
+	}
+	return models.NewProfile(account.ID, account.Username, account.Avatar, profiles.Rating(account.ID)).ToPublicProfile(), true
+}
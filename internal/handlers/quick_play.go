@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// handleQuickPlay puts the client in the quick play queue with their
+// preferences
+func handleQuickPlay(hub *websocket.Hub, queue *services.QuickPlayQueue, client *websocket.Client, message *websocket.Message) {
+	var data models.QuickPlayData
+	if err := message.UnmarshalData(&data); err != nil {
+		sendClientError(client, message, "Invalid quick play data", "INVALID_DATA")
+		return
+	}
+
+	cfg := config.GetConfig()
+	switch models.Difficulty(data.Difficulty) {
+	case "", models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard:
+	default:
+		sendClientError(client, message, "Invalid difficulty", "INVALID_DIFFICULTY")
+		return
+	}
+	if data.Language != "" && !utils.ValidateLanguage(data.Language) {
+		sendClientError(client, message, "Invalid language", "INVALID_LANGUAGE")
+		return
+	}
+	if data.MaxPlayers != 0 && (data.MaxPlayers < cfg.Game.MinPlayersToStart || data.MaxPlayers > cfg.Game.MaxPlayersPerRoom) {
+		sendClientError(client, message, "Invalid max players", "INVALID_MAX_PLAYERS")
+		return
+	}
+
+	if client.GetRoomID() != "" {
+		sendClientError(client, message, "Leave your room before quick play", "IN_ROOM")
+		return
+	}
+	if hub.Draining() {
+		sendClientError(client, message, "Server is shutting down", "SERVER_SHUTTING_DOWN")
+		return
+	}
+
+	userID := client.GetUser().ID
+	if !queue.Join(userID, data) {
+		sendClientError(client, message, "Already in the quick play queue", "ALREADY_QUEUED")
+		return
+	}
+	logger.Info("player queued for quick play", logging.UserID(userID))
+
+	status, queued := queue.Status(userID, time.Now())
+	if !queued {
+		// Matched between joining and now; room_joined follows
+		return
+	}
+	sendQuickPlayStatus(hub, client, message, status)
+}
+
+// handleCancelQuickPlay takes the client out of the quick play queue
+func handleCancelQuickPlay(hub *websocket.Hub, queue *services.QuickPlayQueue, client *websocket.Client, message *websocket.Message) {
+	if !queue.Leave(client.GetUser().ID) {
+		sendClientError(client, message, "Not in the quick play queue", "NOT_QUEUED")
+		return
+	}
+	sendQuickPlayStatus(hub, client, message, &models.QuickPlayStatusData{State: "cancelled"})
+}
+
+// sendQuickPlayStatus sends a quick play status in reply to a request
+func sendQuickPlayStatus(hub *websocket.Hub, client *websocket.Client, message *websocket.Message, status *models.QuickPlayStatusData) {
+	msg, err := websocket.NewQuickPlayStatusMessage(status)
+	if err != nil {
+		sendClientError(client, message, "Failed to create quick play status message", "MESSAGE_CREATION_FAILED")
+		return
+	}
+	sendReply(hub, client, message, msg, false)
+}
+
+// MatchQuickPlay places the queued players that can be placed in rooms and
+// tells the rest where they stand. Players who disconnected or joined a
+// room on their own drop out of the queue.
+func MatchQuickPlay(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, queue *services.QuickPlayQueue) {
+	for _, userID := range queue.Queued() {
+		client, exists := hub.GetClientByUserID(userID)
+		if !exists || client.GetRoomID() != "" {
+			queue.Leave(userID)
+		}
+	}
+
+	// Nobody is placed in a room while the server shuts down
+	if !hub.Draining() {
+		for _, match := range queue.Match(time.Now()) {
+			match := match
+			if !hub.DispatchToRoom(match.Room.ID, func() {
+				joinQuickPlayMatch(hub, roomManager, gameEngine, queue, match)
+			}) {
+				for _, ticket := range match.Tickets {
+					queue.Requeue(ticket)
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, userID := range queue.Queued() {
+		status, queued := queue.Status(userID, now)
+		if !queued {
+			continue
+		}
+		msg, err := websocket.NewQuickPlayStatusMessage(status)
+		if err != nil {
+			logger.Error("creating quick play status message", logging.Err(err))
+			return
+		}
+		msgData, err := msg.ToJSON()
+		if err != nil {
+			logger.Error("encoding quick play status message", logging.Err(err))
+			return
+		}
+		hub.SendToClient(userID, msgData)
+	}
+}
+
+// joinQuickPlayMatch joins matched players to their room, on the room's
+// actor. Players who can't be joined go back in the queue.
+func joinQuickPlayMatch(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, queue *services.QuickPlayQueue, match *services.QuickPlayMatch) {
+	room := roomManager.GetRoom(match.Room.ID)
+	if room == nil {
+		for _, ticket := range match.Tickets {
+			queue.Requeue(ticket)
+		}
+		return
+	}
+
+	// Players matched together learn about each other from room_joined;
+	// only those already waiting in the room are told who joined
+	waiting := room.GetPlayerIDs()
+	var joined []*websocket.Client
+	for _, ticket := range match.Tickets {
+		client, exists := hub.GetClientByUserID(ticket.UserID)
+		if !exists || client.GetRoomID() != "" {
+			continue
+		}
+		if !roomManager.JoinRoom(room.ID, ticket.UserID, client.GetUser()) {
+			queue.Requeue(ticket)
+			continue
+		}
+		hub.AddClientToRoom(client, room.ID)
+		joined = append(joined, client)
+	}
+
+	// A room made for players who all left in the meantime isn't needed
+	if match.Created && room.GetPlayerCount() == 0 {
+		roomManager.CloseRoom(room.ID)
+		return
+	}
+	if len(joined) == 0 {
+		return
+	}
+	// The room was made with the first matched player as host, who may
+	// not have been joined
+	if match.Created {
+		room.EnsureHost()
+	}
+	logger.Info("quick play players joined room", logging.RoomID(room.ID), "players", len(joined))
+
+	roomMsg, err := websocket.NewRoomJoinedMessage(room.GetPublicRoomInfo())
+	if err != nil {
+		logger.Error("creating room joined message", logging.Err(err))
+		return
+	}
+	for _, client := range joined {
+		client.SendMessage(roomMsg)
+		sendCanvasSnapshot(client, room)
+
+		playerMsg, err := websocket.NewPlayerJoinedMessage(client.GetUser().ToPublicUser())
+		if err != nil {
+			logger.Error("creating player joined message", logging.Err(err))
+			continue
+		}
+		jsonData, err := playerMsg.ToJSON()
+		if err != nil {
+			logger.Error("encoding player joined message", logging.Err(err))
+			continue
+		}
+		for _, userID := range waiting {
+			hub.SendToClient(userID, jsonData)
+		}
+	}
+
+	startIfFull(hub, roomManager, gameEngine, room)
+}
+
+// startIfFull starts the game in a room that starts on its own once it has
+// filled up
+func startIfFull(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, room *models.Room) {
+	if !room.AutoStart || room.State != models.GameStateLobby || !room.IsFull() || hub.Draining() {
+		return
+	}
+	logger.Info("room full, starting game", logging.RoomID(room.ID))
+	HandleGameStart(hub, roomManager, gameEngine, room.ID)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// How soon a restored game that couldn't be queued on its room is tried again
+const resumeRetryDelay = time.Second
+
+// RestoreRooms picks up the games in rooms restored from snapshots at boot.
+// Every player is treated as disconnected: they can resume with the token
+// they held before the restart, and lose their seat if they don't come back
+// within the grace period. Rounds in progress carry on with the time they
+// had left, and games between rounds move on to the next round.
+func RestoreRooms(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, snapshots []*models.RoomSnapshot) {
+	for _, snapshot := range snapshots {
+		roomID := snapshot.ID
+		room := roomManager.GetRoom(roomID)
+		if room == nil {
+			continue
+		}
+
+		for _, userID := range room.GetPlayerIDs() {
+			player, exists := room.GetPlayer(userID)
+			if !exists {
+				continue
+			}
+			if token := snapshot.ResumeTokens[userID]; token != "" {
+				hub.RestoreSession(player, token, roomID)
+			}
+
+			schedulePlayerTimeout(hub, roomManager, gameEngine, roomID, userID)
+		}
+
+		if room.GetState() != models.GameStatePlaying {
+			continue
+		}
+		dispatchResumeGame(hub, roomManager, gameEngine, roomID)
+	}
+}
+
+// dispatchResumeGame queues resumeGame on the room's actor, trying again
+// shortly while the room's mailbox is full so the game isn't left stalled
+func dispatchResumeGame(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID string) {
+	if hub.DispatchToRoom(roomID, func() {
+		resumeGame(hub, roomManager, gameEngine, roomID)
+	}) {
+		return
+	}
+	logger.Warn("room mailbox is full, retrying game resume", logging.RoomID(roomID))
+	time.AfterFunc(resumeRetryDelay, func() {
+		if roomManager.GetRoom(roomID) != nil {
+			dispatchResumeGame(hub, roomManager, gameEngine, roomID)
+		}
+	})
+}
+
+// resumeGame restarts the round timer of a restored room, or starts its
+// next round if it was between rounds
+func resumeGame(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, roomID string) {
+	room := roomManager.GetRoom(roomID)
+	if room == nil {
+		return
+	}
+
+	if room.IsDrawingRound(room.CurrentRound) {
+		go runRoundTimer(hub, roomManager, gameEngine, roomID, room.CurrentRound)
+		return
+	}
+	continueGame(hub, roomManager, gameEngine, room)
+}
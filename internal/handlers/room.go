@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/middleware"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
 	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
@@ -35,9 +36,16 @@ func CreateRoom(hub *websocket.Hub, roomManager *services.RoomManager) http.Hand
 			return
 		}
 		data.RoomName = utils.SanitizeInput(data.RoomName)
+		if data.Language != "" && !utils.ValidateLanguage(data.Language) {
+			http.Error(w, "Invalid language", http.StatusBadRequest)
+			return
+		}
 
-		// Create guest user as host
+		// Signed-in players host as their account, anyone else as a guest
 		user := models.NewGuestUser()
+		if account, ok := middleware.AccountFrom(r.Context()); ok {
+			user = account.NewUser()
+		}
 		roomType := models.RoomTypePublic
 		if data.RoomType == "private" {
 			roomType = models.RoomTypePrivate
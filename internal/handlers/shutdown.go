@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// DrainGames gives rounds in progress up to drainPeriod to finish before
+// the server stops, broadcasting a server_shutting_down countdown every
+// second. No new games or rounds start meanwhile. Rounds still running when
+// the period is up are ended early so players get the word and their
+// points. Returns early once no round is in progress.
+func DrainGames(ctx context.Context, hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, drainPeriod time.Duration) {
+	hub.Drain()
+	shutdownAt := time.Now().Add(drainPeriod)
+	deadline := time.NewTimer(drainPeriod)
+	defer deadline.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		broadcastShutdownCountdown(hub, shutdownAt)
+		if len(activeRounds(roomManager)) == 0 {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			endActiveRounds(ctx, hub, roomManager, gameEngine)
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func broadcastShutdownCountdown(hub *websocket.Hub, shutdownAt time.Time) {
+	msg, err := websocket.NewServerShuttingDownMessage(shutdownAt)
+	if err != nil {
+		logger.Error("creating server shutting down message", logging.Err(err))
+		return
+	}
+	msgData, err := msg.ToJSON()
+	if err != nil {
+		logger.Error("encoding server shutting down message", logging.Err(err))
+		return
+	}
+	hub.BroadcastToNode(msgData)
+}
+
+// activeRounds returns the IDs of rooms with a round being drawn
+func activeRounds(roomManager *services.RoomManager) []string {
+	var roomIDs []string
+	for _, room := range roomManager.GetAllRooms() {
+		if room.IsDrawingRound(room.CurrentRound) {
+			roomIDs = append(roomIDs, room.ID)
+		}
+	}
+	return roomIDs
+}
+
+// endActiveRounds ends every round in progress on its room's actor and
+// waits for them to finish or ctx to be done
+func endActiveRounds(ctx context.Context, hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine) {
+	roomIDs := activeRounds(roomManager)
+	done := make(chan struct{}, len(roomIDs))
+	pending := 0
+	for _, roomID := range roomIDs {
+		logger.Info("ending round early for shutdown", logging.RoomID(roomID))
+		roomID := roomID
+		dispatched := hub.DispatchToRoom(roomID, func() {
+			defer func() { done <- struct{}{} }()
+			room := roomManager.GetRoom(roomID)
+			if room != nil && room.IsDrawingRound(room.CurrentRound) {
+				HandleRoundEnd(hub, roomManager, gameEngine, roomID)
+			}
+		})
+		if dispatched {
+			pending++
+		}
+	}
+
+	for ; pending > 0; pending-- {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
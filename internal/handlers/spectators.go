@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// handlePromoteSpectator lets the host make a spectator a player between
+// games
+func handlePromoteSpectator(hub *websocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, client *websocket.Client, message *websocket.Message) {
+	roomID := client.GetRoomID()
+	if roomID == "" {
+		sendClientError(client, message, "Not in a room", "NOT_IN_ROOM")
+		return
+	}
+
+	room := roomManager.GetRoom(roomID)
+	if room == nil {
+		sendClientError(client, message, "Room not found", "ROOM_NOT_FOUND")
+		return
+	}
+
+	var data models.PromoteSpectatorData
+	if err := message.UnmarshalData(&data); err != nil {
+		sendClientError(client, message, "Invalid promote spectator data", "INVALID_DATA")
+		return
+	}
+
+	if room.HostID != client.GetUser().ID {
+		sendClientError(client, message, "Only host can promote spectators", "NOT_HOST")
+		return
+	}
+
+	if room.GetState() != models.GameStateLobby {
+		sendClientError(client, message, "Spectators can only be promoted between games", "INVALID_STATE")
+		return
+	}
+
+	spectator, exists := room.GetSpectator(data.UserID)
+	if !exists {
+		sendClientError(client, message, "Spectator not found", "SPECTATOR_NOT_FOUND")
+		return
+	}
+
+	if !room.PromoteSpectator(spectator.ID) {
+		sendClientError(client, message, "Room is full", "ROOM_FULL")
+		return
+	}
+	roomManager.SaveRoom(roomID)
+	logger.Info("spectator promoted", logging.RoomID(roomID), logging.UserID(spectator.ID))
+
+	broadcastPlayerEvent(hub, roomID, spectator, websocket.NewSpectatorPromotedMessage)
+	startIfFull(hub, roomManager, gameEngine, room)
+}
+
+// removeSpectator takes a spectator out of a room. Spectators hold no seat,
+// so one who drops is removed straight away and joins again to keep
+// watching.
+func removeSpectator(hub *websocket.Hub, roomManager *services.RoomManager, roomID string, spectator *models.User) {
+	roomManager.LeaveRoom(roomID, spectator.ID)
+	hub.ReleaseSeat(roomID, spectator.ID)
+
+	broadcastPlayerEvent(hub, roomID, spectator, websocket.NewSpectatorLeftMessage)
+}
+
+// closeForSpectators tells the spectators left in a room that was removed
+// with its last player that it closed, and takes them out of it
+func closeForSpectators(hub *websocket.Hub, room *models.Room) {
+	msgData, err := newRoomClosedJSON(room.ID, "All players left")
+	if err != nil {
+		logger.Error("creating room closed message", logging.Err(err))
+	}
+	for _, userID := range room.GetSpectatorIDs() {
+		hub.ReleaseSeat(room.ID, userID)
+		if err == nil {
+			hub.SendToClient(userID, msgData)
+		}
+	}
+}
@@ -1,37 +1,86 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 	"sort"
 
-	"github.com/gorilla/websocket"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/middleware"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
 	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
 	wsocket "github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
 )
 
-// ServeWS upgrades an HTTP connection to WebSocket
-func ServeWS(hub *wsocket.Hub, w http.ResponseWriter, r *http.Request) {
-	upgrader := websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin: func(r *http.Request) bool {
-			return true // In production, implement proper origin checking
-		},
+// ServeWS upgrades an HTTP connection to WebSocket. Banned IPs are turned
+// away before the upgrade; banned users once their session is known. No
+// connections are accepted while the server shuts down. Clients with an
+// access token, in the access_token query parameter or an Authorization
+// header, play as their account; anyone else plays as a guest.
+func ServeWS(hub *wsocket.Hub, accounts *services.AccountManager, bans *services.BanList, w http.ResponseWriter, r *http.Request) {
+	if hub.Draining() {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg := config.GetConfig()
+	remoteAddr := middleware.ClientIP(r)
+	if _, banned := bans.Check("", remoteAddr); banned {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Browsers can't set headers on WebSocket handshakes, so the token may
+	// come in the query string instead
+	var account *models.Account
+	accessToken := r.URL.Query().Get("access_token")
+	if accessToken == "" {
+		accessToken = middleware.BearerToken(r)
+	}
+	if accessToken != "" {
+		var err error
+		account, err = accounts.Authenticate(accessToken)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if _, banned := bans.Check(account.ID, ""); banned {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
 	}
-	
+
+	upgrader := wsocket.NewUpgrader(cfg)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
-		http.Error(w, "WebSocket upgrade failed", http.StatusBadRequest)
+		// The upgrader has already replied with an error status
+		logger.WarnContext(r.Context(), "websocket upgrade failed", logging.Err(err))
 		return
 	}
 
-	// Create guest user
-	user := models.NewGuestUser()
-	client := wsocket.NewClient(hub, conn, user)
+	// Resume the previous session if the client presents a valid token,
+	// otherwise start over as a new guest user. An account takes over its
+	// own session, if it has one.
+	resumeToken := r.URL.Query().Get("resume_token")
+	if account != nil {
+		resumeToken = hub.ResumeToken(account.ID)
+	}
+	var client *wsocket.Client
+	if session := hub.ResumeSession(resumeToken); session != nil {
+		if _, banned := bans.Check(session.User.ID, ""); banned {
+			wsocket.RejectConnection(conn, cfg, "banned")
+			return
+		}
+		client = wsocket.NewResumedClient(hub, conn, session)
+	} else if account != nil {
+		client = wsocket.NewClient(hub, conn, account.NewUser())
+	} else {
+		client = wsocket.NewClient(hub, conn, models.NewGuestUser())
+	}
+	client.SetCompression(wsocket.CompressionNegotiated(upgrader, r))
+	client.SetRemoteAddr(remoteAddr)
 	hub.RegisterClient(client)
 
 	// Start read and write pumps
@@ -40,84 +89,173 @@ func ServeWS(hub *wsocket.Hub, w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleWebSocketMessage processes incoming WebSocket messages
-func HandleWebSocketMessage(hub *wsocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, client *wsocket.Client, message *wsocket.Message) {
+func HandleWebSocketMessage(hub *wsocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, accounts *services.AccountManager, profiles *services.ProfileStore, leaderboards *services.Leaderboards, quickPlay *services.QuickPlayQueue, bans *services.BanList, client *wsocket.Client, message *wsocket.Message) {
 	switch message.Type {
 	case models.MessageTypeConnect:
-		handleConnect(hub, client, message)
+		handleConnect(hub, accounts, bans, client, message)
+	case models.MessageTypeRegister:
+		handleRegister(hub, accounts, client, message)
+	case models.MessageTypeGetProfile:
+		handleGetProfile(hub, profiles, accounts, client, message)
 	case models.MessageTypeCreateRoom:
 		handleCreateRoom(hub, roomManager, client, message)
 	case models.MessageTypeJoinRoom:
-		handleJoinRoom(hub, roomManager, client, message)
+		handleJoinRoom(hub, roomManager, gameEngine, client, message)
+	case models.MessageTypeQuickPlay:
+		handleQuickPlay(hub, quickPlay, client, message)
+	case models.MessageTypeCancelQuickPlay:
+		handleCancelQuickPlay(hub, quickPlay, client, message)
 	case models.MessageTypeLeaveRoom:
 		handleLeaveRoom(hub, roomManager, client, message)
 	case models.MessageTypeStartGame:
 		handleStartGame(hub, roomManager, gameEngine, client, message)
-	case models.MessageTypeDrawStart:
-		handleDrawStart(hub, roomManager, client, message)
-	case models.MessageTypeDrawMove:
-		handleDrawMove(hub, roomManager, client, message)
-	case models.MessageTypeDrawEnd:
-		handleDrawEnd(hub, roomManager, client, message)
+	case models.MessageTypePromoteSpectator:
+		handlePromoteSpectator(hub, roomManager, gameEngine, client, message)
+	case models.MessageTypeDrawStart, models.MessageTypeDrawMove, models.MessageTypeDrawEnd, models.MessageTypeDrawBatch:
+		handleDraw(hub, roomManager, client, message)
 	case models.MessageTypeSendGuess:
 		handleSendGuess(hub, roomManager, gameEngine, client, message)
 	case models.MessageTypeListPublicRooms:
 		handleListPublicRooms(hub, roomManager, client, message)
+	case models.MessageTypeGetGlobalLeaderboard:
+		handleGetGlobalLeaderboard(hub, leaderboards, client, message)
 	default:
 		// Note: Using a helper function to send error since sendError is not exported
-		sendClientError(client, "Unknown message type", "UNKNOWN_MESSAGE_TYPE")
+		sendClientError(client, message, "Unknown message type", "UNKNOWN_MESSAGE_TYPE")
 	}
 }
 
-// Helper function to send error messages
-func sendClientError(client *wsocket.Client, message, code string) {
+// Helper function to send error messages in reply to a request
+func sendClientError(client *wsocket.Client, request *wsocket.Message, message, code string) {
 	// Create an error message and send it
 	errorMsg, err := wsocket.NewErrorMessage(message, code)
 	if err != nil {
-		log.Printf("Error creating error message: %v", err)
+		logger.Error("creating error message", logging.Err(err))
+		return
+	}
+	logger.Debug("rejected request", logging.UserID(request.UserID), logging.RoomID(request.RoomID),
+		logging.MessageType(request.Type), logging.RequestID(request.RequestID), "code", code)
+	client.SendMessage(errorMsg.InReplyTo(request))
+}
+
+// sendSystemReply sends a system chat message in reply to a request
+func sendSystemReply(client *wsocket.Client, request *wsocket.Message, text string) {
+	systemMsg, err := wsocket.NewChatMessage("System", text, true)
+	if err != nil {
+		logger.Error("creating system message", logging.Err(err))
+		return
+	}
+	client.SendMessage(systemMsg.InReplyTo(request))
+}
+
+// sendCachedReply resends the reply to a request the client already made,
+// so retried requests with the same request ID are idempotent. Returns
+// false if there is no cached reply.
+func sendCachedReply(hub *wsocket.Hub, client *wsocket.Client, request *wsocket.Message) bool {
+	if request.RequestID == "" {
+		return false
+	}
+	reply, exists := hub.CachedReply(client.GetUser().ID, request.RequestID)
+	if !exists {
+		return false
+	}
+	client.SendJSON(reply)
+	return true
+}
+
+// sendReply sends a direct reply to a request, caching it when cache is set
+// so a retry of the same request gets the same answer
+func sendReply(hub *wsocket.Hub, client *wsocket.Client, request *wsocket.Message, reply *wsocket.Message, cache bool) {
+	reply.InReplyTo(request)
+	if !cache || request.RequestID == "" {
+		client.SendMessage(reply)
+		return
+	}
+
+	data, err := reply.ToJSON()
+	if err != nil {
+		logger.Error("encoding reply", logging.Err(err))
 		return
 	}
-	client.SendMessage(errorMsg)
+	hub.CacheReply(client.GetUser().ID, request.RequestID, data)
+	client.SendJSON(data)
 }
 
-// handleConnect processes a connection message
-func handleConnect(hub *wsocket.Hub, client *wsocket.Client, message *wsocket.Message) {
+// handleConnect processes a connection message. A connect message with an
+// access token signs the client in to that account.
+func handleConnect(hub *wsocket.Hub, accounts *services.AccountManager, bans *services.BanList, client *wsocket.Client, message *wsocket.Message) {
 	var data models.ConnectData
 	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid connect data", "INVALID_DATA")
+		sendClientError(client, message, "Invalid connect data", "INVALID_DATA")
 		return
 	}
 
-	// Validate and sanitize username
-	if !utils.ValidateUserName(data.Username) {
-		sendClientError(client, "Invalid username", "INVALID_USERNAME")
+	// Validate and sanitize username; accounts keep their own
+	guest := data.AccessToken == "" && client.GetUser().IsGuest()
+	if guest && !utils.ValidateUserName(data.Username) {
+		sendClientError(client, message, "Invalid username", "INVALID_USERNAME")
 		return
 	}
 	data.Username = utils.SanitizeInput(data.Username)
 
+	// Settle protocol version and capabilities before touching the user
+	capabilities, err := client.Negotiate(data.ProtocolVersion, data.Capabilities)
+	if err != nil {
+		sendClientError(client, message, err.Error(), "UNSUPPORTED_PROTOCOL_VERSION")
+		return
+	}
+
+	if data.AccessToken != "" && !signIn(hub, accounts, bans, client, message, data.AccessToken) {
+		return
+	}
+
 	// Update user information
 	user := client.GetUser()
-	user.Username = data.Username
-	user.Avatar = data.Avatar
-	user.GuestUser = false
+	if user.IsGuest() {
+		user.Username = data.Username
+	}
+	if data.Avatar != "" || user.IsGuest() {
+		user.Avatar = data.Avatar
+	}
 	user.UpdateActivity()
 
-	client.SendSystemMessage("Successfully connected to the server")
+	// Legacy clients that don't declare a version only understand the chat acknowledgement
+	if data.ProtocolVersion == 0 {
+		sendSystemReply(client, message, "Successfully connected to the server")
+		return
+	}
+
+	connectedMsg, err := wsocket.NewConnectedMessage(client.ProtocolVersion(), capabilities, user.ToPublicUser())
+	if err != nil {
+		sendClientError(client, message, "Failed to create connected message", "MESSAGE_CREATION_FAILED")
+		return
+	}
+	sendReply(hub, client, message, connectedMsg, false)
 }
 
 // handleCreateRoom processes room creation
 func handleCreateRoom(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
+	// A retried request must not create a second room
+	if sendCachedReply(hub, client, message) {
+		return
+	}
+
 	var data models.CreateRoomData
 	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid room creation data", "INVALID_DATA")
+		sendClientError(client, message, "Invalid room creation data", "INVALID_DATA")
 		return
 	}
 
 	// Validate input
 	if !utils.ValidateUserName(data.RoomName) {
-		sendClientError(client, "Invalid room name", "INVALID_ROOM_NAME")
+		sendClientError(client, message, "Invalid room name", "INVALID_ROOM_NAME")
 		return
 	}
 	data.RoomName = utils.SanitizeInput(data.RoomName)
+	if data.Language != "" && !utils.ValidateLanguage(data.Language) {
+		sendClientError(client, message, "Invalid language", "INVALID_LANGUAGE")
+		return
+	}
 
 	roomType := models.RoomTypePublic
 	if data.RoomType == "private" {
@@ -127,7 +265,7 @@ func handleCreateRoom(hub *wsocket.Hub, roomManager *services.RoomManager, clien
 	// Create room
 	room := roomManager.CreateRoom(client.GetUser().ID, roomType, data.RoomName, data)
 	if room == nil {
-		sendClientError(client, "Failed to create room", "ROOM_CREATION_FAILED")
+		sendClientError(client, message, "Failed to create room", "ROOM_CREATION_FAILED")
 		return
 	}
 
@@ -139,22 +277,22 @@ func handleCreateRoom(hub *wsocket.Hub, roomManager *services.RoomManager, clien
 	roomInfo := room.GetPublicRoomInfo()
 	msg, err := wsocket.NewRoomCreatedMessage(roomInfo)
 	if err != nil {
-		sendClientError(client, "Failed to create room message", "MESSAGE_CREATION_FAILED")
+		sendClientError(client, message, "Failed to create room message", "MESSAGE_CREATION_FAILED")
 		return
 	}
-	client.SendMessage(msg)
+	sendReply(hub, client, message, msg, true)
 
 	// Broadcast to all about new public room
 	if room.Type == models.RoomTypePublic {
 		publicRooms := roomManager.GetPublicRooms()
 		roomsMsg, err := wsocket.NewPublicRoomsListMessage(publicRooms)
 		if err != nil {
-			log.Printf("Error creating public rooms list message: %v", err)
+			logger.Error("creating public rooms list message", logging.Err(err))
 			return
 		}
 		jsonData, err := roomsMsg.ToJSON()
 		if err != nil {
-			log.Printf("Error converting rooms message to JSON: %v", err)
+			logger.Error("encoding rooms message", logging.Err(err))
 			return
 		}
 		hub.BroadcastToAll(jsonData)
@@ -162,30 +300,61 @@ func handleCreateRoom(hub *wsocket.Hub, roomManager *services.RoomManager, clien
 }
 
 // handleJoinRoom processes joining a room
-func handleJoinRoom(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
+func handleJoinRoom(hub *wsocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, client *wsocket.Client, message *wsocket.Message) {
+	// A retried request gets the original reply instead of a JOIN_FAILED
+	if sendCachedReply(hub, client, message) {
+		return
+	}
+
 	var data models.JoinRoomData
 	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid join room data", "INVALID_DATA")
+		sendClientError(client, message, "Invalid join room data", "INVALID_DATA")
+		return
+	}
+	if data.Spectator && !client.HasCapability(wsocket.CapabilitySpectator) {
+		sendClientError(client, message, "Spectating requires the spectator capability", "CAPABILITY_REQUIRED")
 		return
 	}
 
 	// Validate room code
 	data.RoomCode = utils.NormalizeRoomCode(data.RoomCode)
 	if !utils.ValidateRoomCode(data.RoomCode) {
-		sendClientError(client, "Invalid room code", "INVALID_ROOM_CODE")
+		sendClientError(client, message, "Invalid room code", "INVALID_ROOM_CODE")
+		return
+	}
+
+	// Rooms owned by another node are joined there
+	if hub.ForwardToOwner(data.RoomCode, client, message) {
 		return
 	}
 
 	// Find room by code
 	room := roomManager.GetRoomByCode(data.RoomCode)
 	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
+		sendClientError(client, message, "Room not found", "ROOM_NOT_FOUND")
 		return
 	}
 
-	// Join room
-	if !roomManager.JoinRoom(room.ID, client.GetUser().ID, client.GetUser()) {
-		sendClientError(client, "Failed to join room", "JOIN_FAILED")
+	// Finish the join on the target room's actor so it is serialized with
+	// that room's game events
+	if message.RoomID != room.ID {
+		message.RoomID = room.ID
+		if !hub.DispatchToRoom(room.ID, func() {
+			handleJoinRoom(hub, roomManager, gameEngine, client, message)
+		}) {
+			sendClientError(client, message, "Room is busy, try again", "ROOM_BUSY")
+		}
+		return
+	}
+
+	// Join room, as a spectator if asked
+	if data.Spectator {
+		if !roomManager.SpectateRoom(room.ID, client.GetUser().ID, client.GetUser()) {
+			sendClientError(client, message, "Failed to join room as spectator", "SPECTATE_FAILED")
+			return
+		}
+	} else if !roomManager.JoinRoom(room.ID, client.GetUser().ID, client.GetUser()) {
+		sendClientError(client, message, "Failed to join room", "JOIN_FAILED")
 		return
 	}
 
@@ -196,263 +365,186 @@ func handleJoinRoom(hub *wsocket.Hub, roomManager *services.RoomManager, client
 	roomInfo := room.GetPublicRoomInfo()
 	roomMsg, err := wsocket.NewRoomJoinedMessage(roomInfo)
 	if err != nil {
-		sendClientError(client, "Failed to create room joined message", "MESSAGE_CREATION_FAILED")
+		sendClientError(client, message, "Failed to create room joined message", "MESSAGE_CREATION_FAILED")
 		return
 	}
-	client.SendMessage(roomMsg)
+	sendReply(hub, client, message, roomMsg, true)
+	sendCanvasSnapshot(client, room)
 
 	// Notify other players
-	playerMsg, err := wsocket.NewPlayerJoinedMessage(client.GetUser().ToPublicUser())
+	newJoinedMessage := wsocket.NewPlayerJoinedMessage
+	if data.Spectator {
+		newJoinedMessage = wsocket.NewSpectatorJoinedMessage
+	}
+	playerMsg, err := newJoinedMessage(client.GetUser().ToPublicUser())
 	if err != nil {
-		log.Printf("Error creating player joined message: %v", err)
+		logger.Error("creating player joined message", logging.Err(err))
 		return
 	}
 	jsonData, err := playerMsg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting player joined message to JSON: %v", err)
+		logger.Error("encoding player joined message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(room.ID, jsonData, client)
+
+	if !data.Spectator {
+		startIfFull(hub, roomManager, gameEngine, room)
+	}
 }
 
 // handleLeaveRoom processes leaving a room
 func handleLeaveRoom(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
 	roomID := client.GetRoomID()
 	if roomID == "" {
-		sendClientError(client, "Not in a room", "NOT_IN_ROOM")
+		sendClientError(client, message, "Not in a room", "NOT_IN_ROOM")
 		return
 	}
 
 	room := roomManager.GetRoom(roomID)
 	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
+		sendClientError(client, message, "Room not found", "ROOM_NOT_FOUND")
 		return
 	}
 
 	// Remove from room
+	newLeftMessage := wsocket.NewPlayerLeftMessage
+	if _, spectating := room.GetSpectator(client.GetUser().ID); spectating {
+		newLeftMessage = wsocket.NewSpectatorLeftMessage
+	}
 	roomManager.LeaveRoom(roomID, client.GetUser().ID)
 	hub.RemoveClientFromRoom(client, roomID)
 
 	// Notify other players
-	playerMsg, err := wsocket.NewPlayerLeftMessage(client.GetUser().ToPublicUser())
+	playerMsg, err := newLeftMessage(client.GetUser().ToPublicUser())
 	if err != nil {
-		log.Printf("Error creating player left message: %v", err)
+		logger.Error("creating player left message", logging.Err(err))
 		return
 	}
 	jsonData, err := playerMsg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting player left message to JSON: %v", err)
+		logger.Error("encoding player left message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, jsonData, nil)
 
+	// The room is gone if it was the last player
+	if roomManager.GetRoom(roomID) == nil {
+		closeForSpectators(hub, room)
+	}
+
 	// Send confirmation to client
-	client.SendSystemMessage("You have left the room")
+	sendSystemReply(client, message, "You have left the room")
 }
 
 // handleStartGame processes game start request
 func handleStartGame(hub *wsocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, client *wsocket.Client, message *wsocket.Message) {
 	roomID := client.GetRoomID()
 	if roomID == "" {
-		sendClientError(client, "Not in a room", "NOT_IN_ROOM")
+		sendClientError(client, message, "Not in a room", "NOT_IN_ROOM")
 		return
 	}
 
 	room := roomManager.GetRoom(roomID)
 	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
+		sendClientError(client, message, "Room not found", "ROOM_NOT_FOUND")
 		return
 	}
 
 	if room.HostID != client.GetUser().ID {
-		sendClientError(client, "Only host can start game", "NOT_HOST")
+		sendClientError(client, message, "Only host can start game", "NOT_HOST")
 		return
 	}
 
 	if !room.CanStart() {
-		sendClientError(client, "Not enough players or not all ready", "CANNOT_START")
-		return
-	}
-
-	HandleGameStart(hub, roomManager, gameEngine, roomID)
-}
-
-// handleDrawStart processes start of a drawing action
-func handleDrawStart(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
-	roomID := client.GetRoomID()
-	if roomID == "" {
-		sendClientError(client, "Not in a room", "NOT_IN_ROOM")
+		sendClientError(client, message, "Not enough players or not all ready", "CANNOT_START")
 		return
 	}
 
-	room := roomManager.GetRoom(roomID)
-	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
+	if hub.Draining() {
+		sendClientError(client, message, "Server is shutting down", "SERVER_SHUTTING_DOWN")
 		return
 	}
 
-	if room.CurrentDrawer != client.GetUser().ID {
-		sendClientError(client, "Not your turn to draw", "NOT_DRAWER")
-		return
-	}
-
-	var data models.DrawStartData
-	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid draw data", "INVALID_DATA")
-		return
-	}
-
-	drawCmd := models.DrawCommand{
-		Type:  "start",
-		X:     data.X,
-		Y:     data.Y,
-		Color: data.Color,
-		Size:  data.Size,
-	}
-	room.AddDrawCommand(drawCmd)
-
-	drawMsg, err := wsocket.NewDrawDataMessage(wsocket.DrawDataMessage{
-		Type:   "start",
-		X:      data.X,
-		Y:      data.Y,
-		Color:  data.Color,
-		Size:   data.Size,
-		UserID: client.GetUser().ID,
-	})
-	if err != nil {
-		log.Printf("Error creating draw data message: %v", err)
-		return
-	}
-	jsonData, err := drawMsg.ToJSON()
-	if err != nil {
-		log.Printf("Error converting draw message to JSON: %v", err)
-		return
-	}
-	hub.BroadcastToRoom(roomID, jsonData, client)
+	HandleGameStart(hub, roomManager, gameEngine, roomID)
 }
 
-// handleDrawMove processes ongoing drawing action
-func handleDrawMove(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
+// handleDraw processes drawing commands from the current drawer, whether
+// sent as individual JSON messages or as a batched binary frame
+func handleDraw(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
 	roomID := client.GetRoomID()
 	if roomID == "" {
-		sendClientError(client, "Not in a room", "NOT_IN_ROOM")
+		sendClientError(client, message, "Not in a room", "NOT_IN_ROOM")
 		return
 	}
 
 	room := roomManager.GetRoom(roomID)
 	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
+		sendClientError(client, message, "Room not found", "ROOM_NOT_FOUND")
 		return
 	}
 
 	if room.CurrentDrawer != client.GetUser().ID {
-		sendClientError(client, "Not your turn to draw", "NOT_DRAWER")
-		return
-	}
-
-	var data models.DrawMoveData
-	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid draw data", "INVALID_DATA")
+		sendClientError(client, message, "Not your turn to draw", "NOT_DRAWER")
 		return
 	}
 
-	drawCmd := models.DrawCommand{
-		Type: "move",
-		X:    data.X,
-		Y:    data.Y,
-	}
-	room.AddDrawCommand(drawCmd)
-
-	drawMsg, err := wsocket.NewDrawDataMessage(wsocket.DrawDataMessage{
-		Type:   "move",
-		X:      data.X,
-		Y:      data.Y,
-		UserID: client.GetUser().ID,
-	})
-	if err != nil {
-		log.Printf("Error creating draw data message: %v", err)
-		return
-	}
-	jsonData, err := drawMsg.ToJSON()
+	commands, err := message.DrawCommands()
 	if err != nil {
-		log.Printf("Error converting draw message to JSON: %v", err)
-		return
-	}
-	hub.BroadcastToRoom(roomID, jsonData, client)
-}
-
-// handleDrawEnd processes end of drawing action
-func handleDrawEnd(hub *wsocket.Hub, roomManager *services.RoomManager, client *wsocket.Client, message *wsocket.Message) {
-	roomID := client.GetRoomID()
-	if roomID == "" {
-		sendClientError(client, "Not in a room", "NOT_IN_ROOM")
+		sendClientError(client, message, "Invalid draw data", "INVALID_DATA")
 		return
 	}
 
-	room := roomManager.GetRoom(roomID)
-	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
-		return
-	}
-
-	if room.CurrentDrawer != client.GetUser().ID {
-		sendClientError(client, "Not your turn to draw", "NOT_DRAWER")
-		return
+	// The round's drawing is kept for late joiners, so it is capped until
+	// the drawer clears the canvas
+	maxCommands := config.GetConfig().Game.MaxDrawCommandsPerRound
+	valid := make([]models.DrawCommand, 0, len(commands))
+	full := false
+	for _, cmd := range commands {
+		if !wsocket.ValidDrawCommand(cmd) {
+			continue
+		}
+		if !room.AddDrawCommand(cmd, maxCommands) {
+			full = true
+			continue
+		}
+		valid = append(valid, cmd)
 	}
-
-	var data models.DrawEndData
-	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid draw data", "INVALID_DATA")
-		return
+	if len(valid) > 0 {
+		hub.BroadcastDrawToRoom(roomID, client.GetUser().ID, valid, client)
 	}
-
-	drawCmd := models.DrawCommand{
-		Type: "end",
-		X:    data.X,
-		Y:    data.Y,
+	if full {
+		sendClientError(client, message, "Canvas is full; clear it to keep drawing", "CANVAS_FULL")
 	}
-	room.AddDrawCommand(drawCmd)
-
-	drawMsg, err := wsocket.NewDrawDataMessage(wsocket.DrawDataMessage{
-		Type:   "end",
-		X:      data.X,
-		Y:      data.Y,
-		UserID: client.GetUser().ID,
-	})
-	if err != nil {
-		log.Printf("Error creating draw data message: %v", err)
-		return
-	}
-	jsonData, err := drawMsg.ToJSON()
-	if err != nil {
-		log.Printf("Error converting draw message to JSON: %v", err)
-		return
-	}
-	hub.BroadcastToRoom(roomID, jsonData, client)
 }
 
 // handleSendGuess processes a player's guess
 func handleSendGuess(hub *wsocket.Hub, roomManager *services.RoomManager, gameEngine *services.GameEngine, client *wsocket.Client, message *wsocket.Message) {
 	roomID := client.GetRoomID()
 	if roomID == "" {
-		sendClientError(client, "Not in a room", "NOT_IN_ROOM")
+		sendClientError(client, message, "Not in a room", "NOT_IN_ROOM")
 		return
 	}
 
 	room := roomManager.GetRoom(roomID)
 	if room == nil {
-		sendClientError(client, "Room not found", "ROOM_NOT_FOUND")
+		sendClientError(client, message, "Room not found", "ROOM_NOT_FOUND")
+		return
+	}
+
+	if _, spectating := room.GetSpectator(client.GetUser().ID); spectating {
+		sendClientError(client, message, "Spectators can't guess", "SPECTATOR")
 		return
 	}
 
 	if room.State != models.GameStatePlaying || room.Phase != models.GamePhaseDrawing {
-		sendClientError(client, "Game not in progress", "INVALID_STATE")
+		sendClientError(client, message, "Game not in progress", "INVALID_STATE")
 		return
 	}
 
 	var data models.GuessData
 	if err := message.UnmarshalData(&data); err != nil {
-		sendClientError(client, "Invalid guess data", "INVALID_DATA")
+		sendClientError(client, message, "Invalid guess data", "INVALID_DATA")
 		return
 	}
 
@@ -461,18 +553,19 @@ func handleSendGuess(hub *wsocket.Hub, roomManager *services.RoomManager, gameEn
 	// Broadcast guess as chat message
 	chatMsg, err := wsocket.NewChatMessage(client.GetUser().Username, data.Guess, false)
 	if err != nil {
-		log.Printf("Error creating chat message: %v", err)
+		logger.Error("creating chat message", logging.Err(err))
 		return
 	}
 	chatJsonData, err := chatMsg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting chat message to JSON: %v", err)
+		logger.Error("encoding chat message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, chatJsonData, nil)
 
 	// Validate guess
 	result := gameEngine.ValidateGuess(room, client.GetUser().ID, data.Guess)
+	recordGuess(result.Correct)
 	if !result.Correct {
 		return
 	}
@@ -480,10 +573,10 @@ func handleSendGuess(hub *wsocket.Hub, roomManager *services.RoomManager, gameEn
 	// Send guess result to player
 	resultMsg, err := wsocket.NewGuessResultMessage(result)
 	if err != nil {
-		log.Printf("Error creating guess result message: %v", err)
+		logger.Error("creating guess result message", logging.Err(err))
 		return
 	}
-	client.SendMessage(resultMsg)
+	sendReply(hub, client, message, resultMsg, false)
 
 	// Broadcast points awarded
 	pointsMsg, err := wsocket.NewPointsMessage(
@@ -494,12 +587,12 @@ func handleSendGuess(hub *wsocket.Hub, roomManager *services.RoomManager, gameEn
 		"Correct guess",
 	)
 	if err != nil {
-		log.Printf("Error creating points message: %v", err)
+		logger.Error("creating points message", logging.Err(err))
 		return
 	}
 	pointsJsonData, err := pointsMsg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting points message to JSON: %v", err)
+		logger.Error("encoding points message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, pointsJsonData, nil)
@@ -507,12 +600,12 @@ func handleSendGuess(hub *wsocket.Hub, roomManager *services.RoomManager, gameEn
 	// Update leaderboard
 	leaderboardMsg, err := wsocket.NewLeaderboardMessage(getLeaderboard(room), room.CurrentRound, room.MaxRounds)
 	if err != nil {
-		log.Printf("Error creating leaderboard message: %v", err)
+		logger.Error("creating leaderboard message", logging.Err(err))
 		return
 	}
 	leaderboardJsonData, err := leaderboardMsg.ToJSON()
 	if err != nil {
-		log.Printf("Error converting leaderboard message to JSON: %v", err)
+		logger.Error("encoding leaderboard message", logging.Err(err))
 		return
 	}
 	hub.BroadcastToRoom(roomID, leaderboardJsonData, nil)
@@ -528,10 +621,10 @@ func handleListPublicRooms(hub *wsocket.Hub, roomManager *services.RoomManager,
 	rooms := roomManager.GetPublicRooms()
 	msg, err := wsocket.NewPublicRoomsListMessage(rooms)
 	if err != nil {
-		sendClientError(client, "Failed to list rooms", "LIST_ROOMS_FAILED")
+		sendClientError(client, message, "Failed to list rooms", "LIST_ROOMS_FAILED")
 		return
 	}
-	client.SendMessage(msg)
+	sendReply(hub, client, message, msg, false)
 }
 
 // getLeaderboard generates leaderboard from room players
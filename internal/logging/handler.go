@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// subsystemHandler filters records by its subsystem's level and writes them
+// to the output set by Setup. Calls to WithAttrs and WithGroup are recorded
+// and replayed on the output handler, so loggers created before Setup pick
+// up the configured output.
+type subsystemHandler struct {
+	subsystem string
+	steps     []handlerStep
+
+	// The output handler with the subsystem and steps applied, for the
+	// state it was derived from
+	derived atomic.Pointer[derivedHandler]
+}
+
+// handlerStep is one WithAttrs or WithGroup call
+type handlerStep struct {
+	attrs []slog.Attr
+	group string
+}
+
+type derivedHandler struct {
+	state   *state
+	handler slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= current.Load().levelFor(h.subsystem)
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	st := current.Load()
+	if r.Level < st.levelFor(h.subsystem) {
+		return nil
+	}
+
+	// Rebuild the record to reveal or hide sensitive values and add the
+	// request ID carried by the context
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if value, ok := a.Value.Any().(sensitive); ok {
+			if r.Level <= slog.LevelDebug {
+				a = slog.String(a.Key, string(value))
+			} else {
+				a = slog.String(a.Key, redacted)
+			}
+		}
+		out.AddAttrs(a)
+		return true
+	})
+	if id := RequestIDFrom(ctx); id != "" {
+		out.AddAttrs(RequestID(id))
+	}
+
+	return h.output(st).Handle(ctx, out)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(handlerStep{attrs: attrs})
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(handlerStep{group: name})
+}
+
+func (h *subsystemHandler) with(step handlerStep) *subsystemHandler {
+	steps := make([]handlerStep, len(h.steps), len(h.steps)+1)
+	copy(steps, h.steps)
+	return &subsystemHandler{
+		subsystem: h.subsystem,
+		steps:     append(steps, step),
+	}
+}
+
+// output returns the output handler with the subsystem and steps applied,
+// deriving it again when Setup has changed the output
+func (h *subsystemHandler) output(st *state) slog.Handler {
+	if derived := h.derived.Load(); derived != nil && derived.state == st {
+		return derived.handler
+	}
+
+	handler := st.handler.WithAttrs([]slog.Attr{slog.String(KeySubsystem, h.subsystem)})
+	for _, step := range h.steps {
+		if step.group != "" {
+			handler = handler.WithGroup(step.group)
+		} else {
+			handler = handler.WithAttrs(step.attrs)
+		}
+	}
+	h.derived.Store(&derivedHandler{state: st, handler: handler})
+	return handler
+}
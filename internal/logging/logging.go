@@ -0,0 +1,176 @@
+// Package logging provides structured logging on log/slog. Each subsystem
+// logs through its own logger whose level can be set separately, and
+// records share field names so they can be queried across subsystems.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+)
+
+// Field names shared by every subsystem
+const (
+	KeySubsystem   = "subsystem"
+	KeyRoomID      = "room_id"
+	KeyRoomCode    = "room_code"
+	KeyUserID      = "user_id"
+	KeyMessageType = "message_type"
+	KeyRequestID   = "request_id"
+	KeyError       = "error"
+)
+
+// Subsystems whose level can be configured
+var Subsystems = []string{"server", "http", "hub", "cluster", "game", "rooms", "admin"}
+
+// state is the output and levels set by Setup
+type state struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+// Until Setup is called everything at info and above is logged as JSON to
+// stderr, so loggers can be created in package variables
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+// Setup configures logging output and levels. It also routes the standard
+// library's log package through the "server" logger.
+func Setup(cfg config.LoggingConfig, w io.Writer) error {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	levels := make(map[string]slog.Level, len(cfg.Subsystems))
+	for subsystem, name := range cfg.Subsystems {
+		if !isSubsystem(subsystem) {
+			return fmt.Errorf("unknown logging subsystem %q", subsystem)
+		}
+		if levels[subsystem], err = ParseLevel(name); err != nil {
+			return err
+		}
+	}
+
+	// Levels are enforced per subsystem, so the output handler passes everything
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch cfg.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return fmt.Errorf("unknown logging format %q", cfg.Format)
+	}
+
+	current.Store(&state{handler: handler, level: level, levels: levels})
+	slog.SetDefault(For("server"))
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil || strings.ContainsAny(name, "+-") {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// For returns the logger for a subsystem. Its records carry the subsystem
+// name and are filtered by the subsystem's level.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem})
+}
+
+func isSubsystem(name string) bool {
+	for _, subsystem := range Subsystems {
+		if subsystem == name {
+			return true
+		}
+	}
+	return false
+}
+
+// levelFor returns the level a subsystem logs at
+func (s *state) levelFor(subsystem string) slog.Level {
+	if level, exists := s.levels[subsystem]; exists {
+		return level
+	}
+	return s.level
+}
+
+// RoomID is the room_id field
+func RoomID(id string) slog.Attr {
+	return slog.String(KeyRoomID, id)
+}
+
+// RoomCode is the room_code field
+func RoomCode(code string) slog.Attr {
+	return slog.String(KeyRoomCode, code)
+}
+
+// UserID is the user_id field
+func UserID(id string) slog.Attr {
+	return slog.String(KeyUserID, id)
+}
+
+// MessageType is the message_type field
+func MessageType[T ~string](msgType T) slog.Attr {
+	return slog.String(KeyMessageType, string(msgType))
+}
+
+// RequestID is the request_id field
+func RequestID(id string) slog.Attr {
+	return slog.String(KeyRequestID, id)
+}
+
+// Err is the error field
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// sensitive is a value only shown in debug records
+type sensitive string
+
+// LogValue keeps the value hidden from handlers other than this package's
+func (s sensitive) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+const redacted = "[redacted]"
+
+// Sensitive is a field whose value, such as the current word, is only
+// logged at debug level. It must be passed with the log call rather than
+// through Logger.With.
+func Sensitive(key, value string) slog.Attr {
+	return slog.Any(key, sensitive(value))
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID. Records logged
+// with the context get a request_id field.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID carried by a context, if any
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
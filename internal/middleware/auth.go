@@ -1,15 +1,17 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"crypto/subtle"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/services"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
-	"golang.org/x/time/rate"
 )
 
 // ApplyMiddleware applies all middleware to the router
@@ -23,38 +25,87 @@ func ApplyMiddleware(router *mux.Router, cfg *config.Config) http.Handler {
 	})
 
 	// Rate limiting middleware
-	limiter := rate.NewLimiter(rate.Limit(cfg.RateLimit.RequestsPerMinute/60), cfg.RateLimit.BurstSize)
+	limiter := newRateLimiter(cfg.RateLimit)
 
-	return corsMiddleware.Handler(rateLimitMiddleware(limiter, loggingMiddleware(router)))
+	// Resolve the caller's IP once for everything downstream
+	resolver := newIPResolver(cfg.RateLimit.TrustedProxies)
+
+	// Requests are logged outside the rate limiter so rejections are logged too
+	return corsMiddleware.Handler(clientIPMiddleware(resolver, loggingMiddleware(rateLimitMiddleware(limiter, router))))
 }
 
-// loggingMiddleware logs incoming requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-		log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
-	})
+type accountKey struct{}
+
+// AuthMiddleware authenticates requests carrying an access token in an
+// "Authorization: Bearer" header; their account is available through
+// AccountFrom. Requests without a token pass through as guests, and those
+// with an invalid or expired one are rejected.
+func AuthMiddleware(accounts *services.AccountManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := BearerToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			account, err := accounts.Authenticate(token)
+			if err != nil {
+				logger.DebugContext(r.Context(), "rejected access token", "client_ip", ClientIP(r), logging.Err(err))
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accountKey{}, account)))
+		})
+	}
 }
 
-// rateLimitMiddleware applies rate limiting
-func rateLimitMiddleware(limiter *rate.Limiter, next http.Handler) http.Handler {
+// RequireAccount only lets through requests authenticated by AuthMiddleware
+func RequireAccount(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := limiter.Wait(r.Context()); err != nil {
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		if _, ok := AccountFrom(r.Context()); !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// AuthMiddleware checks for valid authentication (simplified for guest users)
-func AuthMiddleware() func(http.Handler) http.Handler {
+// AccountFrom returns the account a request was authenticated as
+func AccountFrom(ctx context.Context) (*models.Account, bool) {
+	account, ok := ctx.Value(accountKey{}).(*models.Account)
+	return account, ok
+}
+
+// BearerToken returns the token in a request's "Authorization: Bearer"
+// header, or an empty string
+func BearerToken(r *http.Request) string {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return ""
+	}
+	return token
+}
+
+// AdminAuth only lets through requests bearing the admin token. With no
+// token configured the admin API is disabled and answers 404.
+func AdminAuth(token string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// In a real application, check for JWT or session token
-			// For this example, we'll allow guest access
+			if token == "" {
+				http.NotFound(w, r)
+				return
+			}
+
+			presented, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				logger.WarnContext(r.Context(), "rejected admin request", "client_ip", ClientIP(r), "method", r.Method, "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
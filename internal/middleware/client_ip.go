@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ipResolver works out the caller's IP, believing forwarding headers only
+// from trusted proxies
+type ipResolver struct {
+	trustedProxies []*net.IPNet
+}
+
+// newIPResolver parses trusted proxy IPs and CIDRs. Invalid entries are
+// rejected by config validation and skipped here.
+func newIPResolver(trustedProxies []string) *ipResolver {
+	resolver := &ipResolver{}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			resolver.trustedProxies = append(resolver.trustedProxies, network)
+		}
+	}
+	return resolver
+}
+
+// clientIPMiddleware records the caller's IP on the request for ClientIP
+func clientIPMiddleware(resolver *ipResolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, resolver.clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP returns the caller's IP as resolved by the middleware chain, or
+// the connection's address if the request didn't go through it
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// clientIP returns the caller's IP. Forwarding headers are only used when
+// the connection comes from a trusted proxy, and X-Forwarded-For is read
+// from the right so clients can't spoof their way past the proxy.
+func (ir *ipResolver) clientIP(r *http.Request) string {
+	host := remoteHost(r)
+	if !ir.isTrusted(host) {
+		return host
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !ir.isTrusted(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return host
+}
+
+// isTrusted reports whether an address belongs to a trusted proxy
+func (ir *ipResolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range ir.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns the host part of the connection's address
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
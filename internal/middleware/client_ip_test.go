@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver := newIPResolver([]string{"10.0.0.0/8", "192.168.1.1", "::1", "not-an-ip"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"spoofed XFF from untrusted peer", "203.0.113.7:5000", []string{"1.2.3.4"}, "", "203.0.113.7"},
+		{"spoofed X-Real-IP from untrusted peer", "203.0.113.7:5000", nil, "1.2.3.4", "203.0.113.7"},
+		{"one trusted hop", "10.0.0.1:5000", []string{"1.2.3.4"}, "", "1.2.3.4"},
+		{"trusted single IP", "192.168.1.1:5000", []string{"1.2.3.4"}, "", "1.2.3.4"},
+		{"several trusted hops", "10.0.0.1:5000", []string{"1.2.3.4, 10.0.0.2, 10.0.0.3"}, "", "1.2.3.4"},
+		{"client-supplied hops left of the client", "10.0.0.1:5000", []string{"9.9.9.9, 1.2.3.4, 10.0.0.2"}, "", "1.2.3.4"},
+		{"hops across headers", "10.0.0.1:5000", []string{"9.9.9.9", "1.2.3.4"}, "", "1.2.3.4"},
+		{"only trusted hops", "10.0.0.1:5000", []string{"10.0.0.5, 10.0.0.2"}, "", "10.0.0.5"},
+		{"malformed hop", "10.0.0.1:5000", []string{"1.2.3.4, garbage, 10.0.0.2"}, "", "10.0.0.1"},
+		{"malformed hop falls back to X-Real-IP", "10.0.0.1:5000", []string{"garbage"}, "5.5.5.5", "5.5.5.5"},
+		{"empty hop", "10.0.0.1:5000", []string{"1.2.3.4,,"}, "", "10.0.0.1"},
+		{"X-Real-IP", "10.0.0.1:5000", nil, "5.5.5.5", "5.5.5.5"},
+		{"invalid X-Real-IP", "10.0.0.1:5000", nil, "5.5.5.5, 6.6.6.6", "10.0.0.1"},
+		{"XFF preferred to X-Real-IP", "10.0.0.1:5000", []string{"1.2.3.4"}, "5.5.5.5", "1.2.3.4"},
+		{"IPv6 proxy", "[::1]:5000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"untrusted IPv6 peer", "[2001:db8::2]:5000", []string{"1.2.3.4"}, "", "2001:db8::2"},
+		{"address without port", "203.0.113.7", nil, "", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for _, value := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := resolver.clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	resolver := newIPResolver([]string{"10.0.0.0/8"})
+
+	var got string
+	handler := clientIPMiddleware(resolver, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "1.2.3.4" {
+		t.Errorf("ClientIP behind middleware = %q, want %q", got, "1.2.3.4")
+	}
+
+	// Without the middleware only the connection's address is known
+	if ip := ClientIP(r); ip != "10.0.0.1" {
+		t.Errorf("ClientIP without middleware = %q, want %q", ip, "10.0.0.1")
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiter keeps a token bucket per client and route
+type rateLimiter struct {
+	defaultRoute routeLimit
+	routes       []routeLimit // Longest prefix first
+
+	apiKeyHeader string
+	apiKeys      map[string]bool
+	idleTimeout  time.Duration
+
+	buckets   map[string]*clientBucket
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// routeLimit is the rate applied to paths under prefix
+type routeLimit struct {
+	prefix    string
+	perMinute int
+	burst     int
+	exempt    bool
+}
+
+// clientBucket is one client's token bucket for one route
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiter creates a keyed rate limiter from configuration
+func newRateLimiter(cfg config.RateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{
+		defaultRoute: routeLimit{perMinute: cfg.RequestsPerMinute, burst: cfg.BurstSize},
+		apiKeyHeader: cfg.APIKeyHeader,
+		apiKeys:      make(map[string]bool),
+		idleTimeout:  cfg.IdleTimeout,
+		buckets:      make(map[string]*clientBucket),
+		lastSweep:    time.Now(),
+	}
+
+	for _, route := range cfg.Routes {
+		rl.routes = append(rl.routes, routeLimit{
+			prefix:    route.PathPrefix,
+			perMinute: route.RequestsPerMinute,
+			burst:     route.BurstSize,
+			exempt:    route.Exempt,
+		})
+	}
+	sort.SliceStable(rl.routes, func(i, j int) bool {
+		return len(rl.routes[i].prefix) > len(rl.routes[j].prefix)
+	})
+
+	for _, key := range cfg.APIKeys {
+		rl.apiKeys[key] = true
+	}
+	return rl
+}
+
+// route returns the limit for a request path
+func (rl *rateLimiter) route(path string) routeLimit {
+	for _, route := range rl.routes {
+		if strings.HasPrefix(path, route.prefix) {
+			return route
+		}
+	}
+	return rl.defaultRoute
+}
+
+// allow takes a token from a client's bucket for a route. It returns
+// whether the request may proceed, the whole tokens left, and how long until
+// the next token and until the bucket is full again.
+func (rl *rateLimiter) allow(client string, route routeLimit, now time.Time) (bool, int, time.Duration, time.Duration) {
+	limiter := rl.bucket(route.prefix+" "+client, route, now)
+
+	allowed := limiter.AllowN(now, 1)
+	tokens := limiter.TokensAt(now)
+	perSecond := float64(limiter.Limit())
+
+	var retryAfter time.Duration
+	if tokens < 1 {
+		retryAfter = time.Duration((1 - tokens) / perSecond * float64(time.Second))
+	}
+	reset := time.Duration((float64(route.burst) - tokens) / perSecond * float64(time.Second))
+	return allowed, int(math.Max(0, math.Floor(tokens))), retryAfter, reset
+}
+
+// bucket returns a client's limiter for a route, creating it as needed
+func (rl *rateLimiter) bucket(key string, route routeLimit, now time.Time) *rate.Limiter {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if now.Sub(rl.lastSweep) >= rl.idleTimeout {
+		rl.sweep(now)
+	}
+
+	bucket, exists := rl.buckets[key]
+	if !exists {
+		bucket = &clientBucket{
+			limiter: rate.NewLimiter(rate.Limit(float64(route.perMinute)/60), route.burst),
+		}
+		rl.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+	return bucket.limiter
+}
+
+// sweep drops buckets that have been idle for the idle timeout. A bucket
+// idle that long has refilled, so forgetting it changes nothing. Caller
+// must hold rl.mutex.
+func (rl *rateLimiter) sweep(now time.Time) {
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.lastSeen) >= rl.idleTimeout {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// clientKey identifies the caller: by API key if it sent a known one,
+// otherwise by IP
+func (rl *rateLimiter) clientKey(r *http.Request) string {
+	if rl.apiKeyHeader != "" {
+		if key := r.Header.Get(rl.apiKeyHeader); key != "" && rl.apiKeys[key] {
+			return "key:" + key
+		}
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// rateLimitMiddleware rejects callers over their limit with 429 Too Many
+// Requests. Every limited response carries X-RateLimit-* headers.
+func rateLimitMiddleware(limiter *rateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := limiter.route(r.URL.Path)
+		if route.exempt {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining, retryAfter, reset := limiter.allow(limiter.clientKey(r), route, time.Now())
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(route.perMinute))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
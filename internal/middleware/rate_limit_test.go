@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+)
+
+func testRateLimitConfig() config.RateLimitConfig {
+	return config.RateLimitConfig{
+		RequestsPerMinute: 60,
+		BurstSize:         3,
+		APIKeyHeader:      "X-API-Key",
+		APIKeys:           []string{"partner"},
+		IdleTimeout:       time.Minute,
+		Routes: []config.RouteRateLimit{
+			{PathPrefix: "/health", Exempt: true},
+			{PathPrefix: "/api", RequestsPerMinute: 120, BurstSize: 2},
+			{PathPrefix: "/api/auth", RequestsPerMinute: 6, BurstSize: 1},
+		},
+	}
+}
+
+func TestRateLimiterRoute(t *testing.T) {
+	rl := newRateLimiter(testRateLimitConfig())
+
+	tests := []struct {
+		path      string
+		perMinute int
+		exempt    bool
+	}{
+		{"/", 60, false},
+		{"/ws", 60, false},
+		{"/health", 0, true},
+		{"/api/rooms", 120, false},
+		{"/api/auth/login", 6, false},
+	}
+
+	for _, tt := range tests {
+		route := rl.route(tt.path)
+		if route.perMinute != tt.perMinute || route.exempt != tt.exempt {
+			t.Errorf("route(%q) = %+v, want %d per minute, exempt %v", tt.path, route, tt.perMinute, tt.exempt)
+		}
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	rl := newRateLimiter(testRateLimitConfig())
+	route := rl.route("/")
+	now := time.Now()
+
+	// One token a second with a burst of three
+	tests := []struct {
+		name       string
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{"first", 0, true, 2, 0, time.Second},
+		{"second", 0, true, 1, 0, 2 * time.Second},
+		{"third", 0, true, 0, time.Second, 3 * time.Second},
+		{"burst used up", 0, false, 0, time.Second, 3 * time.Second},
+		{"half refilled", 500 * time.Millisecond, false, 0, 500 * time.Millisecond, 2500 * time.Millisecond},
+		{"refilled one", time.Second, true, 0, time.Second, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		allowed, remaining, retryAfter, reset := rl.allow("ip:1.2.3.4", route, now.Add(tt.at))
+		if allowed != tt.allowed || remaining != tt.remaining {
+			t.Errorf("%s: allowed, remaining = %v, %d, want %v, %d", tt.name, allowed, remaining, tt.allowed, tt.remaining)
+		}
+		if !closeTo(retryAfter, tt.retryAfter) || !closeTo(reset, tt.reset) {
+			t.Errorf("%s: retry after, reset = %v, %v, want %v, %v", tt.name, retryAfter, reset, tt.retryAfter, tt.reset)
+		}
+	}
+
+	// Other clients and routes have their own buckets
+	if allowed, _, _, _ := rl.allow("ip:5.6.7.8", route, now); !allowed {
+		t.Error("another client was limited by the first client's bucket")
+	}
+	if allowed, _, _, _ := rl.allow("ip:1.2.3.4", rl.route("/api/rooms"), now); !allowed {
+		t.Error("another route was limited by the default route's bucket")
+	}
+}
+
+// closeTo compares durations computed from float token counts
+func closeTo(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Millisecond && diff < time.Millisecond
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	rl := newRateLimiter(testRateLimitConfig())
+	route := rl.route("/")
+	now := time.Now()
+
+	rl.allow("ip:1.2.3.4", route, now)
+	rl.allow("ip:5.6.7.8", route, now.Add(30*time.Second))
+	if len(rl.buckets) != 2 {
+		t.Fatalf("buckets = %d, want 2", len(rl.buckets))
+	}
+
+	// The sweep runs once the idle timeout has passed since the last one
+	// and only drops buckets idle for that long
+	rl.allow("ip:9.9.9.9", route, now.Add(time.Minute))
+	if _, exists := rl.buckets[" ip:1.2.3.4"]; exists {
+		t.Error("idle bucket was not swept")
+	}
+	if _, exists := rl.buckets[" ip:5.6.7.8"]; !exists {
+		t.Error("recently used bucket was swept")
+	}
+	if len(rl.buckets) != 2 {
+		t.Errorf("buckets = %d, want 2", len(rl.buckets))
+	}
+}
+
+func TestRateLimiterClientKey(t *testing.T) {
+	rl := newRateLimiter(testRateLimitConfig())
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{"no key", "", "ip:1.2.3.4"},
+		{"known key", "partner", "key:partner"},
+		{"unknown key", "guess", "ip:1.2.3.4"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "1.2.3.4:5000"
+		if tt.apiKey != "" {
+			r.Header.Set("X-API-Key", tt.apiKey)
+		}
+		if got := rl.clientKey(r); got != tt.want {
+			t.Errorf("%s: clientKey = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := rateLimitMiddleware(newRateLimiter(testRateLimitConfig()), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(path, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Exempt routes are never limited and carry no headers
+	for i := 0; i < 10; i++ {
+		w := request("/health", "1.2.3.4:5000", "")
+		if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("/health request %d: status %d, limit header %q", i, w.Code, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		apiKey     string
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{"first", "1.2.3.4:5000", "", http.StatusOK, "1", ""},
+		{"second", "1.2.3.4:5001", "", http.StatusOK, "0", ""},
+		{"burst used up", "1.2.3.4:5002", "", http.StatusTooManyRequests, "0", "1"},
+		{"other IP", "5.6.7.8:5000", "", http.StatusOK, "1", ""},
+		{"API key from limited IP", "1.2.3.4:5000", "partner", http.StatusOK, "1", ""},
+		{"API key from another IP", "5.6.7.8:5000", "partner", http.StatusOK, "0", ""},
+		{"API key burst used up", "9.9.9.9:5000", "partner", http.StatusTooManyRequests, "0", "1"},
+	}
+
+	for _, tt := range tests {
+		w := request("/api/rooms", tt.remoteAddr, tt.apiKey)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "120" {
+			t.Errorf("%s: X-RateLimit-Limit = %q, want 120", tt.name, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("%s: X-RateLimit-Remaining = %q, want %q", tt.name, got, tt.remaining)
+		}
+		if got := w.Header().Get("X-RateLimit-Reset"); got != "1" {
+			t.Errorf("%s: X-RateLimit-Reset = %q, want 1", tt.name, got)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.retryAfter)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Account is a registered player. Its ID is the user ID the player plays
+// under, so a guest who registers keeps their ID, room and score.
+type Account struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Avatar       string    `json:"avatar"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Bumped whenever tokens are issued; only a refresh token carrying the
+	// current generation can be exchanged
+	TokenGeneration int `json:"token_generation"`
+}
+
+// PublicAccount is account data that can be shown to its owner and others
+type PublicAccount struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ToPublicAccount returns the account without its password hash
+func (a *Account) ToPublicAccount() *PublicAccount {
+	return &PublicAccount{
+		ID:        a.ID,
+		Username:  a.Username,
+		Avatar:    a.Avatar,
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+// NewUser creates the user an account plays as on a new connection
+func (a *Account) NewUser() *User {
+	user := NewUser(a.Username, a.Avatar)
+	user.ID = a.ID
+	user.GuestUser = false
+	return user
+}
+
+// SignupData is the body of a signup request
+type SignupData struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Avatar   string `json:"avatar"`
+}
+
+// LoginData is the body of a login request
+type LoginData struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RefreshData is the body of a token refresh request
+type RefreshData struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RegisterData turns the connected guest into an account. Username
+// defaults to the guest's current name.
+type RegisterData struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password"`
+}
+
+// AuthenticatedData is sent on signup, login, refresh and register
+type AuthenticatedData struct {
+	Account      *PublicAccount `json:"account"`
+	AccessToken  string         `json:"access_token"`
+	RefreshToken string         `json:"refresh_token"`
+	ExpiresAt    time.Time      `json:"expires_at"` // When the access token expires
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// LeaderboardWindow is the period a global leaderboard covers
+type LeaderboardWindow string
+
+const (
+	LeaderboardDaily   LeaderboardWindow = "daily"    // Since midnight
+	LeaderboardWeekly  LeaderboardWindow = "weekly"   // Since Monday midnight
+	LeaderboardAllTime LeaderboardWindow = "all_time" // Never rolls over
+)
+
+// LeaderboardCategory is the statistic a global leaderboard is ranked by
+type LeaderboardCategory string
+
+const (
+	LeaderboardByScore          LeaderboardCategory = "score"
+	LeaderboardByWins           LeaderboardCategory = "wins"
+	LeaderboardByCorrectGuesses LeaderboardCategory = "correct_guesses"
+	LeaderboardByDrawerPoints   LeaderboardCategory = "drawer_points" // Best drawer
+)
+
+// LeaderboardEntry is a player's totals over a leaderboard's window
+type LeaderboardEntry struct {
+	Rank           int    `json:"rank"`
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	Avatar         string `json:"avatar"`
+	Guest          bool   `json:"guest"`
+	GamesPlayed    int    `json:"games_played"`
+	Wins           int    `json:"wins"`
+	Score          int    `json:"score"`
+	CorrectGuesses int    `json:"correct_guesses"`
+	DrawerPoints   int    `json:"drawer_points"`
+}
+
+// Value returns the entry's statistic for a category
+func (e *LeaderboardEntry) Value(category LeaderboardCategory) int {
+	switch category {
+	case LeaderboardByWins:
+		return e.Wins
+	case LeaderboardByCorrectGuesses:
+		return e.CorrectGuesses
+	case LeaderboardByDrawerPoints:
+		return e.DrawerPoints
+	default:
+		return e.Score
+	}
+}
+
+// GetGlobalLeaderboardData requests a global leaderboard. Category
+// defaults to score.
+type GetGlobalLeaderboardData struct {
+	Window   LeaderboardWindow   `json:"window"`
+	Category LeaderboardCategory `json:"category,omitempty"`
+	Limit    int                 `json:"limit,omitempty"`
+}
+
+// GlobalLeaderboardData is a global leaderboard's top players
+type GlobalLeaderboardData struct {
+	Window   LeaderboardWindow   `json:"window"`
+	Category LeaderboardCategory `json:"category"`
+	StartsAt time.Time           `json:"starts_at"`
+	ResetsAt *time.Time          `json:"resets_at,omitempty"` // Not set for all-time
+	Entries  []*LeaderboardEntry `json:"entries"`
+}
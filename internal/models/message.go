@@ -12,6 +12,14 @@ const (
 	// Connection messages
 	MessageTypeConnect     MessageType = "connect"
 	MessageTypeDisconnect  MessageType = "disconnect"
+	MessageTypeSession     MessageType = "session"
+	MessageTypeConnected   MessageType = "connected"
+	
+	// Account messages
+	MessageTypeRegister      MessageType = "register"
+	MessageTypeAuthenticated MessageType = "authenticated"
+	MessageTypeGetProfile    MessageType = "get_profile"
+	MessageTypeProfile       MessageType = "profile"
 	
 	// Room messages
 	MessageTypeCreateRoom       MessageType = "create_room"
@@ -22,9 +30,22 @@ const (
 	MessageTypeRoomLeft        MessageType = "room_left"
 	MessageTypePlayerJoined    MessageType = "player_joined"
 	MessageTypePlayerLeft      MessageType = "player_left"
+	MessageTypePlayerDisconnected MessageType = "player_disconnected"
+	MessageTypePlayerReconnected  MessageType = "player_reconnected"
 	MessageTypeListPublicRooms MessageType = "list_public_rooms"
 	MessageTypePublicRoomsList MessageType = "public_rooms_list"
 	
+	// Spectator messages
+	MessageTypePromoteSpectator  MessageType = "promote_spectator"
+	MessageTypeSpectatorJoined   MessageType = "spectator_joined"
+	MessageTypeSpectatorLeft     MessageType = "spectator_left"
+	MessageTypeSpectatorPromoted MessageType = "spectator_promoted"
+	
+	// Matchmaking messages
+	MessageTypeQuickPlay       MessageType = "quick_play"
+	MessageTypeCancelQuickPlay MessageType = "cancel_quick_play"
+	MessageTypeQuickPlayStatus MessageType = "quick_play_status"
+	
 	// Game messages
 	MessageTypeStartGame    MessageType = "start_game"
 	MessageTypeGameStarted  MessageType = "game_started"
@@ -37,7 +58,9 @@ const (
 	MessageTypeDrawMove  MessageType = "draw_move"
 	MessageTypeDrawEnd   MessageType = "draw_end"
 	MessageTypeDrawData  MessageType = "draw_data"
+	MessageTypeDrawBatch MessageType = "draw_batch"
 	MessageTypeClearCanvas MessageType = "clear_canvas"
+	MessageTypeCanvasSnapshot MessageType = "canvas_snapshot"
 	
 	// Chat and guessing messages
 	MessageTypeSendGuess   MessageType = "send_guess"
@@ -50,6 +73,14 @@ const (
 	MessageTypePointsAwarded MessageType = "points_awarded"
 	MessageTypeTimer        MessageType = "timer"
 	MessageTypeLeaderboard  MessageType = "leaderboard"
+	MessageTypeAnnouncement MessageType = "announcement"
+	MessageTypeRoomClosed   MessageType = "room_closed"
+
+	MessageTypeServerShuttingDown MessageType = "server_shutting_down"
+
+	// Global leaderboard messages
+	MessageTypeGetGlobalLeaderboard MessageType = "get_global_leaderboard"
+	MessageTypeGlobalLeaderboard    MessageType = "global_leaderboard"
 )
 
 // Message represents a WebSocket message
@@ -59,6 +90,7 @@ type Message struct {
 	Timestamp time.Time       `json:"timestamp"`
 	UserID    string          `json:"user_id,omitempty"`
 	RoomID    string          `json:"room_id,omitempty"`
+	RequestID string          `json:"request_id,omitempty"` // Optional, set by the client and echoed on direct replies
 }
 
 // NewMessage creates a new message with the current timestamp
@@ -105,8 +137,28 @@ func (m *Message) UnmarshalData(v interface{}) error {
 
 // Connect message data
 type ConnectData struct {
-	Username string `json:"username"`
-	Avatar   string `json:"avatar"`
+	Username        string   `json:"username"`
+	Avatar          string   `json:"avatar"`
+	ProtocolVersion int      `json:"protocol_version,omitempty"` // Omitted by legacy clients
+	Capabilities    []string `json:"capabilities,omitempty"`
+	AccessToken     string   `json:"access_token,omitempty"` // Plays as the account instead of a guest
+}
+
+// Connected data acknowledges a connect message
+type ConnectedData struct {
+	ProtocolVersion int         `json:"protocol_version"`
+	Capabilities    []string    `json:"capabilities"` // Capabilities the server enabled
+	User            *PublicUser `json:"user"`
+}
+
+// Session data sent on every new connection
+type SessionData struct {
+	UserID          string `json:"user_id"`
+	ResumeToken     string `json:"resume_token"`
+	GracePeriod     int    `json:"grace_period"` // seconds a dropped connection can be resumed
+	Resumed         bool   `json:"resumed"`
+	RoomID          string `json:"room_id,omitempty"`
+	ReplayTruncated bool   `json:"replay_truncated,omitempty"`
 }
 
 // Room creation data
@@ -117,12 +169,36 @@ type CreateRoomData struct {
 	RoundTime   int    `json:"round_time"`
 	MaxRounds   int    `json:"max_rounds"`
 	Difficulty  string `json:"difficulty"` // "easy", "medium", "hard"
+	Language    string `json:"language,omitempty"` // e.g. "en"; empty leaves it open
 	CustomWords []string `json:"custom_words,omitempty"`
 }
 
 // Room join data
 type JoinRoomData struct {
-	RoomCode string `json:"room_code"`
+	RoomCode  string `json:"room_code"`
+	Spectator bool   `json:"spectator,omitempty"` // Watch without playing
+}
+
+// Promote spectator data, sent by the host between games
+type PromoteSpectatorData struct {
+	UserID string `json:"user_id"`
+}
+
+// Quick play data puts the player in the matchmaking queue. Empty
+// preferences match anything.
+type QuickPlayData struct {
+	Difficulty string `json:"difficulty,omitempty"` // "easy", "medium", "hard"
+	Language   string `json:"language,omitempty"`
+	MaxPlayers int    `json:"max_players,omitempty"`
+}
+
+// Quick play status data, sent while a player waits in the matchmaking queue
+type QuickPlayStatusData struct {
+	State     string `json:"state"` // "queued" or "cancelled"
+	Position  int    `json:"position,omitempty"`
+	QueueSize int    `json:"queue_size,omitempty"`
+	Waited    int    `json:"waited,omitempty"` // seconds
+	ETA       int    `json:"eta,omitempty"`    // seconds, estimated
 }
 
 // Drawing data structures
@@ -143,6 +219,32 @@ type DrawEndData struct {
 	Y float64 `json:"y"`
 }
 
+// DrawBatchData carries several drawing commands in one message. Binary
+// drawing frames are decoded into the same command list.
+type DrawBatchData struct {
+	Commands []DrawCommand `json:"commands"`
+}
+
+// CanvasCommand is a drawing command in a canvas snapshot
+type CanvasCommand struct {
+	Type  string  `json:"type"` // "start", "move", "end"
+	X     float64 `json:"x,omitempty"`
+	Y     float64 `json:"y,omitempty"`
+	Color string  `json:"color,omitempty"`
+	Size  float64 `json:"size,omitempty"`
+}
+
+// CanvasSnapshotData is one chunk of the current round's drawing, sent to
+// players who join or come back mid-round. Chunks are numbered from 1; the
+// first one replaces whatever the client's canvas shows.
+type CanvasSnapshotData struct {
+	Round    int             `json:"round"`
+	DrawerID string          `json:"drawer_id"`
+	Chunk    int             `json:"chunk"`
+	Chunks   int             `json:"chunks"`
+	Commands []CanvasCommand `json:"commands"`
+}
+
 // Guess data
 type GuessData struct {
 	Guess string `json:"guess"`
@@ -174,4 +276,21 @@ type TimerData struct {
 type ErrorData struct {
 	Message string `json:"message"`
 	Code    string `json:"code,omitempty"`
+}
+
+// Announcement data, broadcast to every connected client by operators
+type AnnouncementData struct {
+	Message string `json:"message"`
+}
+
+// Room closed data, sent to a room's players when operators close it
+type RoomClosedData struct {
+	RoomID string `json:"room_id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Server shutting down data, broadcast every second while the server drains
+type ServerShuttingDownData struct {
+	SecondsLeft int       `json:"seconds_left"`
+	ShutdownAt  time.Time `json:"shutdown_at"`
 }
\ No newline at end of file
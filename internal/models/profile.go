@@ -0,0 +1,123 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Favorite words shown on a profile
+const favoriteWordsCount = 5
+
+// Profile holds an account's lifetime statistics, built up from every round
+// and game it finishes
+type Profile struct {
+	UserID          string         `json:"user_id"`
+	Username        string         `json:"username"`
+	Avatar          string         `json:"avatar"`
+	Rating          int            `json:"rating"`
+	GamesPlayed     int            `json:"games_played"`
+	Wins            int            `json:"wins"`
+	TotalScore      int            `json:"total_score"`
+	BestScore       int            `json:"best_score"`
+	RoundsPlayed    int            `json:"rounds_played"`
+	TotalGuesses    int            `json:"total_guesses"`
+	CorrectGuesses  int            `json:"correct_guesses"`
+	GuessSeconds    int            `json:"guess_seconds"` // Total time taken by correct guesses
+	TimesDrawer     int            `json:"times_drawer"`
+	DrawingsGuessed int            `json:"drawings_guessed"` // Drawer turns where someone guessed the word
+	Words           map[string]int `json:"words"`            // Words drawn or guessed, with how often
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// NewProfile creates an empty profile for an account starting at rating
+func NewProfile(userID, username, avatar string, rating int) *Profile {
+	return &Profile{
+		UserID:    userID,
+		Username:  username,
+		Avatar:    avatar,
+		Rating:    rating,
+		Words:     make(map[string]int),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Accuracy returns the percentage of guesses that were correct
+func (p *Profile) Accuracy() float64 {
+	if p.TotalGuesses == 0 {
+		return 0.0
+	}
+	return float64(p.CorrectGuesses) / float64(p.TotalGuesses) * 100.0
+}
+
+// AverageGuessTime returns the average seconds taken by a correct guess
+func (p *Profile) AverageGuessTime() float64 {
+	if p.CorrectGuesses == 0 {
+		return 0.0
+	}
+	return float64(p.GuessSeconds) / float64(p.CorrectGuesses)
+}
+
+// FavoriteWords returns the words the player has drawn or guessed most,
+// most frequent first
+func (p *Profile) FavoriteWords() []string {
+	words := make([]string, 0, len(p.Words))
+	for word := range p.Words {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if p.Words[words[i]] != p.Words[words[j]] {
+			return p.Words[words[i]] > p.Words[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > favoriteWordsCount {
+		words = words[:favoriteWordsCount]
+	}
+	return words
+}
+
+// ToPublicProfile returns the profile with its derived statistics
+func (p *Profile) ToPublicProfile() *PublicProfile {
+	return &PublicProfile{
+		UserID:           p.UserID,
+		Username:         p.Username,
+		Avatar:           p.Avatar,
+		Rating:           p.Rating,
+		GamesPlayed:      p.GamesPlayed,
+		Wins:             p.Wins,
+		TotalScore:       p.TotalScore,
+		BestScore:        p.BestScore,
+		RoundsPlayed:     p.RoundsPlayed,
+		CorrectGuesses:   p.CorrectGuesses,
+		Accuracy:         p.Accuracy(),
+		AverageGuessTime: p.AverageGuessTime(),
+		TimesDrawer:      p.TimesDrawer,
+		DrawingsGuessed:  p.DrawingsGuessed,
+		FavoriteWords:    p.FavoriteWords(),
+	}
+}
+
+// PublicProfile is profile data that can be shown to anyone
+type PublicProfile struct {
+	UserID           string   `json:"user_id"`
+	Username         string   `json:"username"`
+	Avatar           string   `json:"avatar"`
+	Rating           int      `json:"rating"`
+	GamesPlayed      int      `json:"games_played"`
+	Wins             int      `json:"wins"`
+	TotalScore       int      `json:"total_score"`
+	BestScore        int      `json:"best_score"`
+	RoundsPlayed     int      `json:"rounds_played"`
+	CorrectGuesses   int      `json:"correct_guesses"`
+	Accuracy         float64  `json:"accuracy"`
+	AverageGuessTime float64  `json:"average_guess_time"` // seconds
+	TimesDrawer      int      `json:"times_drawer"`
+	DrawingsGuessed  int      `json:"drawings_guessed"`
+	FavoriteWords    []string `json:"favorite_words"`
+}
+
+// GetProfileData requests a player's profile. UserID defaults to the
+// requesting player.
+type GetProfileData struct {
+	UserID string `json:"user_id,omitempty"`
+}
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
 )
 
 // RoomType represents the type of room
@@ -58,7 +60,9 @@ type Room struct {
 	RoundTime    int        `json:"round_time"`    // seconds
 	MaxRounds    int        `json:"max_rounds"`
 	Difficulty   Difficulty `json:"difficulty"`
+	Language     string     `json:"language,omitempty"` // Players are expected to chat and guess in it
 	CustomWords  []string   `json:"custom_words,omitempty"`
+	AutoStart    bool       `json:"auto_start,omitempty"` // Starts on its own once full, for quick play rooms
 	
 	// Current game state
 	State        GameState `json:"state"`
@@ -70,6 +74,10 @@ type Room struct {
 	Players      map[string]*User `json:"players"`
 	PlayerOrder  []string         `json:"player_order"` // For drawer rotation
 	
+	// Spectators watch without playing, in the order they joined
+	Spectators    []*User `json:"spectators,omitempty"`
+	MaxSpectators int     `json:"max_spectators"`
+	
 	// Current round data
 	CurrentDrawer   string    `json:"current_drawer,omitempty"`
 	CurrentWord     string    `json:"current_word,omitempty"`
@@ -110,6 +118,7 @@ func NewRoom(hostID string, roomType RoomType, roomName string, settings CreateR
 		RoundTime:   settings.RoundTime,
 		MaxRounds:   settings.MaxRounds,
 		Difficulty:  Difficulty(settings.Difficulty),
+		Language:    settings.Language,
 		CustomWords: settings.CustomWords,
 		
 		State:        GameStateLobby,
@@ -133,7 +142,7 @@ func (r *Room) AddPlayer(user *User) bool {
 		return false
 	}
 	
-	if _, exists := r.Players[user.ID]; exists {
+	if _, exists := r.Players[user.ID]; exists || r.spectatorIndex(user.ID) >= 0 {
 		return false
 	}
 	
@@ -180,6 +189,101 @@ func (r *Room) RemovePlayer(userID string) bool {
 	return true
 }
 
+// AddSpectator adds a spectator to the room. Returns false if the room has
+// no spectator slots left or the user is already in it.
+func (r *Room) AddSpectator(user *User) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if len(r.Spectators) >= r.MaxSpectators {
+		return false
+	}
+	if _, exists := r.Players[user.ID]; exists || r.spectatorIndex(user.ID) >= 0 {
+		return false
+	}
+	
+	r.Spectators = append(r.Spectators, user)
+	r.LastActivity = time.Now()
+	return true
+}
+
+// RemoveSpectator removes a spectator from the room
+func (r *Room) RemoveSpectator(userID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	i := r.spectatorIndex(userID)
+	if i < 0 {
+		return false
+	}
+	r.Spectators = append(r.Spectators[:i], r.Spectators[i+1:]...)
+	r.LastActivity = time.Now()
+	return true
+}
+
+// PromoteSpectator makes a spectator a player. Only possible between games
+// and while the room has a free seat.
+func (r *Room) PromoteSpectator(userID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	i := r.spectatorIndex(userID)
+	if i < 0 || r.State != GameStateLobby || len(r.Players) >= r.MaxPlayers {
+		return false
+	}
+	user := r.Spectators[i]
+	r.Spectators = append(r.Spectators[:i], r.Spectators[i+1:]...)
+	
+	user.SetReady(false)
+	r.Players[user.ID] = user
+	r.PlayerOrder = append(r.PlayerOrder, user.ID)
+	
+	r.LastActivity = time.Now()
+	return true
+}
+
+// GetSpectator returns a spectator by ID
+func (r *Room) GetSpectator(userID string) (*User, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	i := r.spectatorIndex(userID)
+	if i < 0 {
+		return nil, false
+	}
+	return r.Spectators[i], true
+}
+
+// GetSpectatorIDs returns the IDs of the room's spectators
+func (r *Room) GetSpectatorIDs() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	spectatorIDs := make([]string, len(r.Spectators))
+	for i, spectator := range r.Spectators {
+		spectatorIDs[i] = spectator.ID
+	}
+	return spectatorIDs
+}
+
+// GetSpectatorCount returns the current number of spectators
+func (r *Room) GetSpectatorCount() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.Spectators)
+}
+
+// spectatorIndex returns a spectator's position in the list, or -1.
+// Caller must hold r.mutex.
+func (r *Room) spectatorIndex(userID string) int {
+	for i, spectator := range r.Spectators {
+		if spectator.ID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
 // GetPlayer returns a player by ID
 func (r *Room) GetPlayer(userID string) (*User, bool) {
 	r.mutex.RLock()
@@ -189,6 +293,13 @@ func (r *Room) GetPlayer(userID string) (*User, bool) {
 	return player, exists
 }
 
+// GetPlayerIDs returns the IDs of the room's players in drawing order
+func (r *Room) GetPlayerIDs() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]string(nil), r.PlayerOrder...)
+}
+
 // GetPlayerCount returns the current number of players
 func (r *Room) GetPlayerCount() int {
 	r.mutex.RLock()
@@ -319,6 +430,20 @@ func (r *Room) AddGuess(userID string) bool {
 	return true
 }
 
+// GetState returns the room's game state
+func (r *Room) GetState() GameState {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.State
+}
+
+// IsDrawingRound reports whether the game is playing and drawing the given round
+func (r *Room) IsDrawingRound(round int) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.State == GameStatePlaying && r.Phase == GamePhaseDrawing && r.CurrentRound == round
+}
+
 // GetTimeLeft returns seconds left in current round
 func (r *Room) GetTimeLeft() int {
 	r.mutex.RLock()
@@ -336,14 +461,65 @@ func (r *Room) GetTimeLeft() int {
 	return int(timeLeft)
 }
 
-// AddDrawCommand adds a drawing command to the room
-func (r *Room) AddDrawCommand(cmd DrawCommand) {
+// AddDrawCommand adds a drawing command to the room. A clear drops what was
+// drawn before it. Returns false if the round already holds limit commands.
+func (r *Room) AddDrawCommand(cmd DrawCommand, limit int) bool {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+
+	if cmd.Type == "clear" {
+		r.DrawingData = r.DrawingData[:0]
+	} else if len(r.DrawingData) >= limit {
+		return false
+	}
 	
 	cmd.Timestamp = time.Now()
 	r.DrawingData = append(r.DrawingData, cmd)
 	r.LastActivity = time.Now()
+	return true
+}
+
+// GetDrawingData returns a copy of the current round's drawing commands
+func (r *Room) GetDrawingData() []DrawCommand {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	commands := make([]DrawCommand, len(r.DrawingData))
+	copy(commands, r.DrawingData)
+	return commands
+}
+
+// GetCanvas returns the current round and drawer together with the round's
+// drawing compacted to what is still on the canvas: everything up to the
+// last clear is dropped, as are moves that repeat the previous point
+func (r *Room) GetCanvas() (round int, drawerID string, commands []CanvasCommand) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	start := 0
+	for i, cmd := range r.DrawingData {
+		if cmd.Type == "clear" {
+			start = i + 1
+		}
+	}
+
+	commands = make([]CanvasCommand, 0, len(r.DrawingData)-start)
+	for _, cmd := range r.DrawingData[start:] {
+		if cmd.Type == "move" && len(commands) > 0 {
+			last := commands[len(commands)-1]
+			if last.Type != "end" && last.X == cmd.X && last.Y == cmd.Y {
+				continue
+			}
+		}
+		commands = append(commands, CanvasCommand{
+			Type:  cmd.Type,
+			X:     cmd.X,
+			Y:     cmd.Y,
+			Color: cmd.Color,
+			Size:  cmd.Size,
+		})
+	}
+	return r.CurrentRound, r.CurrentDrawer, commands
 }
 
 // ClearDrawing clears all drawing data
@@ -364,23 +540,54 @@ func (r *Room) GetPublicRoomInfo() *PublicRoomInfo {
 	for _, player := range r.Players {
 		playerList = append(playerList, player.ToPublicUser())
 	}
+	spectatorList := make([]*PublicUser, 0, len(r.Spectators))
+	for _, spectator := range r.Spectators {
+		spectatorList = append(spectatorList, spectator.ToPublicUser())
+	}
 	
 	return &PublicRoomInfo{
-		ID:           r.ID,
-		Code:         r.Code,
-		Name:         r.Name,
-		Type:         string(r.Type),
-		PlayerCount:  len(r.Players),
-		MaxPlayers:   r.MaxPlayers,
-		State:        string(r.State),
-		Phase:        string(r.Phase),
-		CurrentRound: r.CurrentRound,
-		MaxRounds:    r.MaxRounds,
-		RoundTime:    r.RoundTime,
-		Difficulty:   string(r.Difficulty),
-		Players:      playerList,
-		TimeLeft:     r.GetTimeLeft(),
-		CanJoin:      r.State == GameStateLobby && !r.IsFull(),
+		ID:             r.ID,
+		Code:           r.Code,
+		Name:           r.Name,
+		Type:           string(r.Type),
+		PlayerCount:    len(r.Players),
+		MaxPlayers:     r.MaxPlayers,
+		State:          string(r.State),
+		Phase:          string(r.Phase),
+		CurrentRound:   r.CurrentRound,
+		MaxRounds:      r.MaxRounds,
+		RoundTime:      r.RoundTime,
+		Difficulty:     string(r.Difficulty),
+		Language:       r.Language,
+		Players:        playerList,
+		SpectatorCount: len(r.Spectators),
+		MaxSpectators:  r.MaxSpectators,
+		Spectators:     spectatorList,
+		TimeLeft:       r.GetTimeLeft(),
+		CanJoin:        r.State == GameStateLobby && !r.IsFull(),
+	}
+}
+
+// GetRoomState returns the room's full state, including the current word.
+// It is meant for operators and must never be sent to players.
+func (r *Room) GetRoomState() *RoomState {
+	info := r.GetPublicRoomInfo()
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return &RoomState{
+		PublicRoomInfo: info,
+		HostID:         r.HostID,
+		CreatedAt:      r.CreatedAt,
+		LastActivity:   r.LastActivity,
+		RoundEndTime:   r.RoundEndTime,
+		CurrentDrawer:  r.CurrentDrawer,
+		CurrentWord:    r.CurrentWord,
+		WordHint:       r.WordHint,
+		PlayerOrder:    append([]string(nil), r.PlayerOrder...),
+		GuessedPlayers: append([]string(nil), r.GuessedPlayers...),
+		DrawCommands:   len(r.DrawingData),
 	}
 }
 
@@ -391,6 +598,16 @@ func (r *Room) IsActive(timeout time.Duration) bool {
 	return time.Since(r.LastActivity) <= timeout
 }
 
+// EnsureHost makes the first player host if the host isn't one of the
+// room's players
+func (r *Room) EnsureHost() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.Players[r.HostID]; !exists {
+		r.assignNewHost()
+	}
+}
+
 // Helper methods
 
 func (r *Room) assignNewHost() {
@@ -422,38 +639,68 @@ func (r *Room) selectNextDrawer() {
 		}
 	}
 	
-	// Move to next player, wrapping around
+	// Move to the next connected player, wrapping around. Players who
+	// dropped are skipped until they reconnect.
+	for step := 1; step <= len(r.PlayerOrder); step++ {
+		playerID := r.PlayerOrder[(currentIndex+step)%len(r.PlayerOrder)]
+		if player, exists := r.Players[playerID]; exists && player.Connected() {
+			r.CurrentDrawer = playerID
+			return
+		}
+	}
+	
+	// Nobody is connected; keep rotating
 	nextIndex := (currentIndex + 1) % len(r.PlayerOrder)
 	r.CurrentDrawer = r.PlayerOrder[nextIndex]
 }
 
 // PublicRoomInfo represents room information that can be shared publicly
 type PublicRoomInfo struct {
-	ID           string        `json:"id"`
-	Code         string        `json:"code"`
-	Name         string        `json:"name"`
-	Type         string        `json:"type"`
-	PlayerCount  int           `json:"player_count"`
-	MaxPlayers   int           `json:"max_players"`
-	State        string        `json:"state"`
-	Phase        string        `json:"phase"`
-	CurrentRound int           `json:"current_round"`
-	MaxRounds    int           `json:"max_rounds"`
-	RoundTime    int           `json:"round_time"`
-	Difficulty   string        `json:"difficulty"`
-	Players      []*PublicUser `json:"players"`
-	TimeLeft     int           `json:"time_left"`
-	CanJoin      bool          `json:"can_join"`
+	ID             string        `json:"id"`
+	Code           string        `json:"code"`
+	Name           string        `json:"name"`
+	Type           string        `json:"type"`
+	PlayerCount    int           `json:"player_count"`
+	MaxPlayers     int           `json:"max_players"`
+	State          string        `json:"state"`
+	Phase          string        `json:"phase"`
+	CurrentRound   int           `json:"current_round"`
+	MaxRounds      int           `json:"max_rounds"`
+	RoundTime      int           `json:"round_time"`
+	Difficulty     string        `json:"difficulty"`
+	Language       string        `json:"language,omitempty"`
+	Players        []*PublicUser `json:"players"`
+	SpectatorCount int           `json:"spectator_count"`
+	MaxSpectators  int           `json:"max_spectators"`
+	Spectators     []*PublicUser `json:"spectators"`
+	TimeLeft       int           `json:"time_left"`
+	CanJoin        bool          `json:"can_join"`
+}
+
+// RoomState is everything known about a room, for operators
+type RoomState struct {
+	*PublicRoomInfo
+	HostID         string    `json:"host_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivity   time.Time `json:"last_activity"`
+	RoundEndTime   time.Time `json:"round_end_time"`
+	CurrentDrawer  string    `json:"current_drawer,omitempty"`
+	CurrentWord    string    `json:"current_word,omitempty"`
+	WordHint       string    `json:"word_hint,omitempty"`
+	PlayerOrder    []string  `json:"player_order"`
+	GuessedPlayers []string  `json:"guessed_players"`
+	DrawCommands   int       `json:"draw_commands"`
 }
 
 // Helper functions for room creation
 func generateRoomID() string {
-	return "room_" + generateRandomString(12)
+	return "room_" + utils.GenerateRandomString(12)
 }
 
 func generateRoomCode() string {
-	// Generate 6-character alphanumeric code
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// Generate 6-character alphanumeric code, using the same characters
+	// utils.ValidateRoomCode accepts (no 0, O or 1)
+	const charset = "ABCDEFGHIJKLMNPQRSTUVWXYZ23456789"
 	var code strings.Builder
 	for i := 0; i < 6; i++ {
 		code.WriteByte(charset[generateRandomInt(len(charset))])
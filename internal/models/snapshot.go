@@ -0,0 +1,171 @@
+package models
+
+import "time"
+
+// RoomSnapshot is a room's persisted state, written so rooms survive a
+// restart of the server
+type RoomSnapshot struct {
+	ID           string    `json:"id"`
+	Code         string    `json:"code"`
+	Name         string    `json:"name"`
+	Type         RoomType  `json:"type"`
+	HostID       string    `json:"host_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActivity time.Time `json:"last_activity"`
+
+	MaxPlayers  int        `json:"max_players"`
+	RoundTime   int        `json:"round_time"`
+	MaxRounds   int        `json:"max_rounds"`
+	Difficulty  Difficulty `json:"difficulty"`
+	Language    string     `json:"language,omitempty"`
+	CustomWords []string   `json:"custom_words,omitempty"`
+	AutoStart   bool       `json:"auto_start,omitempty"`
+
+	State          GameState `json:"state"`
+	Phase          GamePhase `json:"phase"`
+	CurrentRound   int       `json:"current_round"`
+	RoundStartTime time.Time `json:"round_start_time"`
+	RoundEndTime   time.Time `json:"round_end_time"`
+
+	Players     []*User  `json:"players"` // In drawing order
+	PlayerOrder []string `json:"player_order"`
+
+	// Spectators aren't saved; they hold no seat and join again
+	MaxSpectators int `json:"max_spectators"`
+
+	CurrentDrawer  string        `json:"current_drawer,omitempty"`
+	CurrentWord    string        `json:"current_word,omitempty"`
+	WordHint       string        `json:"word_hint,omitempty"`
+	GuessedPlayers []string      `json:"guessed_players,omitempty"`
+	DrawingData    []DrawCommand `json:"drawing_data,omitempty"`
+
+	// Players' resume tokens, keyed by user ID, so they can resume their
+	// sessions after a restart
+	ResumeTokens map[string]string `json:"resume_tokens,omitempty"`
+
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Snapshot returns the room's current state for persisting
+func (r *Room) Snapshot() *RoomSnapshot {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	players := make([]*User, 0, len(r.Players))
+	for _, userID := range r.PlayerOrder {
+		if player, exists := r.Players[userID]; exists {
+			players = append(players, player.Copy())
+		}
+	}
+
+	return &RoomSnapshot{
+		ID:           r.ID,
+		Code:         r.Code,
+		Name:         r.Name,
+		Type:         r.Type,
+		HostID:       r.HostID,
+		CreatedAt:    r.CreatedAt,
+		LastActivity: r.LastActivity,
+
+		MaxPlayers:  r.MaxPlayers,
+		RoundTime:   r.RoundTime,
+		MaxRounds:   r.MaxRounds,
+		Difficulty:  r.Difficulty,
+		Language:    r.Language,
+		CustomWords: append([]string(nil), r.CustomWords...),
+		AutoStart:   r.AutoStart,
+
+		State:          r.State,
+		Phase:          r.Phase,
+		CurrentRound:   r.CurrentRound,
+		RoundStartTime: r.RoundStartTime,
+		RoundEndTime:   r.RoundEndTime,
+
+		Players:     players,
+		PlayerOrder: append([]string(nil), r.PlayerOrder...),
+
+		MaxSpectators: r.MaxSpectators,
+
+		CurrentDrawer:  r.CurrentDrawer,
+		CurrentWord:    r.CurrentWord,
+		WordHint:       r.WordHint,
+		GuessedPlayers: append([]string(nil), r.GuessedPlayers...),
+		DrawingData:    append([]DrawCommand(nil), r.DrawingData...),
+
+		SavedAt: time.Now(),
+	}
+}
+
+// RestoreRoom rebuilds a room from a snapshot. Every player starts out
+// disconnected, and the round clock is moved on by the time the server was
+// down so a round in progress keeps the time it had left.
+func RestoreRoom(snapshot *RoomSnapshot) *Room {
+	downtime := time.Since(snapshot.SavedAt)
+	if downtime < 0 {
+		downtime = 0
+	}
+
+	room := &Room{
+		ID:           snapshot.ID,
+		Code:         snapshot.Code,
+		Name:         snapshot.Name,
+		Type:         snapshot.Type,
+		HostID:       snapshot.HostID,
+		CreatedAt:    snapshot.CreatedAt,
+		LastActivity: time.Now(),
+
+		MaxPlayers:  snapshot.MaxPlayers,
+		RoundTime:   snapshot.RoundTime,
+		MaxRounds:   snapshot.MaxRounds,
+		Difficulty:  snapshot.Difficulty,
+		Language:    snapshot.Language,
+		CustomWords: snapshot.CustomWords,
+		AutoStart:   snapshot.AutoStart,
+
+		State:        snapshot.State,
+		Phase:        snapshot.Phase,
+		CurrentRound: snapshot.CurrentRound,
+
+		Players:     make(map[string]*User, len(snapshot.Players)),
+		PlayerOrder: make([]string, 0, len(snapshot.PlayerOrder)),
+
+		MaxSpectators: snapshot.MaxSpectators,
+
+		CurrentDrawer:  snapshot.CurrentDrawer,
+		CurrentWord:    snapshot.CurrentWord,
+		WordHint:       snapshot.WordHint,
+		GuessedPlayers: snapshot.GuessedPlayers,
+		DrawingData:    snapshot.DrawingData,
+	}
+	if !snapshot.RoundStartTime.IsZero() {
+		room.RoundStartTime = snapshot.RoundStartTime.Add(downtime)
+	}
+	if !snapshot.RoundEndTime.IsZero() {
+		room.RoundEndTime = snapshot.RoundEndTime.Add(downtime)
+	}
+	if room.GuessedPlayers == nil {
+		room.GuessedPlayers = make([]string, 0)
+	}
+	if room.DrawingData == nil {
+		room.DrawingData = make([]DrawCommand, 0)
+	}
+
+	for _, player := range snapshot.Players {
+		player.IsConnected = false
+		if !player.GuessTime.IsZero() {
+			player.GuessTime = player.GuessTime.Add(downtime)
+		}
+		room.Players[player.ID] = player
+	}
+	for _, userID := range snapshot.PlayerOrder {
+		if _, exists := room.Players[userID]; exists {
+			room.PlayerOrder = append(room.PlayerOrder, userID)
+		}
+	}
+
+	// A spectator's room comes back without them
+	if _, exists := room.Players[room.HostID]; !exists {
+		room.assignNewHost()
+	}
+	return room
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"crypto/rand"
+	"math/big"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
 )
 
 // User represents a player in the game
@@ -21,6 +26,7 @@ type User struct {
 	HasGuessedThisRound bool      `json:"has_guessed_this_round"`
 	GuessTime          time.Time `json:"guess_time,omitempty"`
 	GuessOrder         int       `json:"guess_order,omitempty"`
+	GuessesThisRound   int       `json:"guesses_this_round,omitempty"`
 	
 	// Statistics
 	RoundsWon        int `json:"rounds_won"`
@@ -91,6 +97,28 @@ func (u *User) SetConnected(connected bool) {
 	}
 }
 
+// IsGuest reports whether the user plays without an account
+func (u *User) IsGuest() bool {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	return u.GuestUser
+}
+
+// Register marks a guest as playing under a new account with username
+func (u *User) Register(username string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.Username = username
+	u.GuestUser = false
+}
+
+// Connected reports whether the user currently has a live connection
+func (u *User) Connected() bool {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	return u.IsConnected
+}
+
 // RecordGuess records that the user made a guess in this round
 func (u *User) RecordGuess(correct bool, guessOrder int) {
 	u.mutex.Lock()
@@ -99,6 +127,7 @@ func (u *User) RecordGuess(correct bool, guessOrder int) {
 	u.HasGuessedThisRound = true
 	u.GuessTime = time.Now()
 	u.TotalGuesses++
+	u.GuessesThisRound++
 	
 	if correct {
 		u.CorrectGuesses++
@@ -121,9 +150,17 @@ func (u *User) ResetRoundData() {
 	u.HasGuessedThisRound = false
 	u.GuessTime = time.Time{}
 	u.GuessOrder = 0
+	u.GuessesThisRound = 0
 	u.IsReady = false
 }
 
+// RoundGuesses returns how many guesses the user made this round
+func (u *User) RoundGuesses() int {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	return u.GuessesThisRound
+}
+
 // GetAccuracy returns the user's guess accuracy as a percentage
 func (u *User) GetAccuracy() float64 {
 	u.mutex.RLock()
@@ -157,6 +194,35 @@ func (u *User) ToPublicUser() *PublicUser {
 		HasGuessedThisRound: u.HasGuessedThisRound,
 		RoundsWon:           u.RoundsWon,
 		Accuracy:            u.GetAccuracy(),
+		Guest:               u.GuestUser,
+	}
+}
+
+// Copy returns a copy of the user that is safe to read without its lock
+func (u *User) Copy() *User {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return &User{
+		ID:           u.ID,
+		Username:     u.Username,
+		Avatar:       u.Avatar,
+		Score:        u.Score,
+		IsReady:      u.IsReady,
+		IsConnected:  u.IsConnected,
+		LastActivity: u.LastActivity,
+		JoinedAt:     u.JoinedAt,
+		GuestUser:    u.GuestUser,
+
+		HasGuessedThisRound: u.HasGuessedThisRound,
+		GuessTime:           u.GuessTime,
+		GuessOrder:          u.GuessOrder,
+		GuessesThisRound:    u.GuessesThisRound,
+
+		RoundsWon:      u.RoundsWon,
+		TotalGuesses:   u.TotalGuesses,
+		CorrectGuesses: u.CorrectGuesses,
+		TimesDrawer:    u.TimesDrawer,
 	}
 }
 
@@ -171,12 +237,13 @@ type PublicUser struct {
 	HasGuessedThisRound bool    `json:"has_guessed_this_round"`
 	RoundsWon           int     `json:"rounds_won"`
 	Accuracy            float64 `json:"accuracy"`
+	Guest               bool    `json:"guest"`
 }
 
 // Helper functions for user creation
 func generateUserID() string {
 	// Generate a unique user ID (timestamp + random string)
-	return "user_" + generateRandomString(8) + "_" + generateTimestamp()
+	return "user_" + utils.GenerateRandomString(8) + "_" + generateTimestamp()
 }
 
 func generateGuestUsername() string {
@@ -199,22 +266,21 @@ func generateRandomAvatar() string {
 }
 
 // Utility functions (these would normally be in a utils package)
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	// Implementation would use crypto/rand for secure random string
-	return "abc123de" // Placeholder
-}
-
 func generateTimestamp() string {
-	return "1234567890" // Placeholder - would use actual timestamp
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
 }
 
 func generateRandomInt(max int) int {
-	// Would use crypto/rand for secure random number
-	return 0 // Placeholder
+	if max <= 0 {
+		return 0
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return int(time.Now().UnixNano() % int64(max))
+	}
+	return int(n.Int64())
 }
 
 func generateIntToString(num int) string {
-	// Would convert int to string
-	return "123" // Placeholder
-}
\ No newline at end of file
+	return strconv.Itoa(num)
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/auth"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+var (
+	// ErrAccountsDisabled is returned by every operation while no JWT
+	// secret is configured
+	ErrAccountsDisabled = errors.New("accounts are disabled")
+
+	// ErrUsernameTaken is returned when signing up with a username that
+	// already has an account, ignoring case
+	ErrUsernameTaken = errors.New("username is taken")
+
+	// ErrAccountExists is returned when a user ID already has an account
+	ErrAccountExists = errors.New("account already exists")
+
+	// ErrInvalidCredentials is returned for an unknown username or a wrong
+	// password, without saying which
+	ErrInvalidCredentials = errors.New("invalid username or password")
+
+	// ErrHashingBusy is returned when too many passwords are already being
+	// hashed; the caller should try again later
+	ErrHashingBusy = errors.New("too many password checks in progress")
+)
+
+// Password length limits
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+// Passwords hashed at once. Each hash keeps a core busy for a while, so
+// requests beyond this are turned away instead of queued.
+const maxConcurrentHashes = 4
+
+// AccountManager keeps registered accounts and issues their tokens.
+// Accounts are held in memory and, when a file is configured, saved to it
+// as a JSON array after every change.
+type AccountManager struct {
+	accounts   map[string]*models.Account // By ID
+	byUsername map[string]*models.Account // By lowercased username
+
+	tokens     *auth.TokenIssuer // Nil while accounts are disabled
+	iterations int
+	hashing    chan struct{} // Semaphore bounding concurrent hashes
+
+	path  string
+	mutex sync.RWMutex
+}
+
+// NewAccountManager creates an account manager from the auth config,
+// loading the accounts already saved to its file
+func NewAccountManager(cfg config.AuthConfig) (*AccountManager, error) {
+	am := &AccountManager{
+		accounts:   make(map[string]*models.Account),
+		byUsername: make(map[string]*models.Account),
+		iterations: cfg.PasswordIterations,
+		hashing:    make(chan struct{}, maxConcurrentHashes),
+		path:       cfg.AccountsFile,
+	}
+	if cfg.JWTSecret == "" {
+		return am, nil
+	}
+	am.tokens = auth.NewTokenIssuer(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+
+	if am.path == "" {
+		return am, nil
+	}
+	data, err := os.ReadFile(am.path)
+	if os.IsNotExist(err) {
+		return am, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accounts: %w", err)
+	}
+	var accounts []*models.Account
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+	for _, account := range accounts {
+		am.accounts[account.ID] = account
+		am.byUsername[strings.ToLower(account.Username)] = account
+	}
+	logger.Info("loaded accounts", "file", am.path, "accounts", len(accounts))
+	return am, nil
+}
+
+// Enabled reports whether accounts are enabled
+func (am *AccountManager) Enabled() bool {
+	return am.tokens != nil
+}
+
+// Create registers a new account under userID. A guest registering passes
+// their own user ID so they keep it.
+func (am *AccountManager) Create(userID, username, password, avatar string) (*models.Account, error) {
+	if !am.Enabled() {
+		return nil, ErrAccountsDisabled
+	}
+
+	// Turn away taken names and IDs before paying for the hash
+	key := strings.ToLower(username)
+	if err := am.available(userID, key); err != nil {
+		return nil, err
+	}
+
+	// Hash before taking the lock; it is deliberately slow
+	if !am.acquireHash() {
+		return nil, ErrHashingBusy
+	}
+	hash, err := auth.HashPassword(password, am.iterations)
+	am.releaseHash()
+	if err != nil {
+		return nil, err
+	}
+	account := &models.Account{
+		ID:           userID,
+		Username:     username,
+		Avatar:       avatar,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	// Someone may have taken them while the password was hashed
+	if err := am.checkAvailable(userID, key); err != nil {
+		return nil, err
+	}
+	am.accounts[account.ID] = account
+	am.byUsername[key] = account
+
+	if err := am.save(); err != nil {
+		delete(am.accounts, account.ID)
+		delete(am.byUsername, key)
+		return nil, err
+	}
+	logger.Info("account created", logging.UserID(account.ID))
+	return account, nil
+}
+
+// Login checks a username and password and returns the account
+func (am *AccountManager) Login(username, password string) (*models.Account, error) {
+	if !am.Enabled() {
+		return nil, ErrAccountsDisabled
+	}
+
+	am.mutex.RLock()
+	account, exists := am.byUsername[strings.ToLower(username)]
+	am.mutex.RUnlock()
+	if !exists {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !am.acquireHash() {
+		return nil, ErrHashingBusy
+	}
+	matches := auth.CheckPassword(account.PasswordHash, password)
+	am.releaseHash()
+	if !matches {
+		return nil, ErrInvalidCredentials
+	}
+	return account, nil
+}
+
+// available checks that a user ID and lowercased username have no account
+func (am *AccountManager) available(userID, key string) error {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+	return am.checkAvailable(userID, key)
+}
+
+// checkAvailable is available for callers holding am.mutex
+func (am *AccountManager) checkAvailable(userID, key string) error {
+	if _, exists := am.accounts[userID]; exists {
+		return ErrAccountExists
+	}
+	if _, exists := am.byUsername[key]; exists {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// acquireHash takes a hashing slot without waiting. Returns false if all
+// slots are in use.
+func (am *AccountManager) acquireHash() bool {
+	select {
+	case am.hashing <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseHash frees a slot taken by acquireHash
+func (am *AccountManager) releaseHash() {
+	<-am.hashing
+}
+
+// Get returns an account by ID
+func (am *AccountManager) Get(accountID string) (*models.Account, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+
+	account, exists := am.accounts[accountID]
+	return account, exists
+}
+
+// IssueTokens creates a new access and refresh token for an account. The
+// account's previous refresh token stops working.
+func (am *AccountManager) IssueTokens(account *models.Account) (*auth.TokenPair, error) {
+	if !am.Enabled() {
+		return nil, ErrAccountsDisabled
+	}
+
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	return am.rotate(account)
+}
+
+// Authenticate returns the account an access token was issued to
+func (am *AccountManager) Authenticate(accessToken string) (*models.Account, error) {
+	return am.verify(accessToken, auth.TokenTypeAccess)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh
+// token works once; presenting it again, or after a newer pair was issued,
+// fails with auth.ErrInvalidToken.
+func (am *AccountManager) Refresh(refreshToken string) (*models.Account, *auth.TokenPair, error) {
+	if !am.Enabled() {
+		return nil, nil, ErrAccountsDisabled
+	}
+	claims, err := am.tokens.Verify(refreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Checked and bumped under one lock so a token can't be exchanged twice
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+
+	account, exists := am.accounts[claims.Subject]
+	if !exists || claims.Generation != account.TokenGeneration {
+		return nil, nil, auth.ErrInvalidToken
+	}
+	tokens, err := am.rotate(account)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, tokens, nil
+}
+
+// rotate bumps an account's token generation, saves it and issues tokens
+// for the new generation. Caller must hold am.mutex.
+func (am *AccountManager) rotate(account *models.Account) (*auth.TokenPair, error) {
+	account.TokenGeneration++
+	if err := am.save(); err != nil {
+		account.TokenGeneration--
+		return nil, err
+	}
+	return am.tokens.Issue(account.ID, account.Username, account.TokenGeneration)
+}
+
+// verify checks a token and looks up the account it was issued to
+func (am *AccountManager) verify(token, tokenType string) (*models.Account, error) {
+	if !am.Enabled() {
+		return nil, ErrAccountsDisabled
+	}
+	claims, err := am.tokens.Verify(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	account, exists := am.Get(claims.Subject)
+	if !exists {
+		return nil, auth.ErrInvalidToken
+	}
+	return account, nil
+}
+
+// save writes every account to the accounts file, replacing it atomically.
+// Caller must hold am.mutex.
+func (am *AccountManager) save() error {
+	if am.path == "" {
+		return nil
+	}
+
+	accounts := make([]*models.Account, 0, len(am.accounts))
+	for _, account := range am.accounts {
+		accounts = append(accounts, account)
+	}
+	data, err := json.Marshal(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to encode accounts: %w", err)
+	}
+
+	// The file holds password hashes; the temporary file it is written
+	// through is created private
+	if err := writeFileAtomic(am.path, data); err != nil {
+		return fmt.Errorf("failed to save accounts: %w", err)
+	}
+	return nil
+}
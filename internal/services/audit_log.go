@@ -0,0 +1,118 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+)
+
+var auditLogger = logging.For("admin")
+
+// AuditEntry records one operator action
+type AuditEntry struct {
+	Time    time.Time         `json:"time"`
+	Actor   string            `json:"actor"`  // Who acted, e.g. the caller's IP
+	Action  string            `json:"action"` // What they did, e.g. "kick_player"
+	Target  string            `json:"target,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// AuditLog keeps a trail of operator actions. Recent entries are held in
+// memory; when a file is configured every entry is also appended to it as a
+// JSON line, so the trail survives restarts.
+type AuditLog struct {
+	entries []AuditEntry // Ring buffer of the most recent entries
+	next    int
+	full    bool
+
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewAuditLog creates an audit log holding up to size entries in memory.
+// If path is not empty entries are also appended to that file.
+func NewAuditLog(path string, size int) (*AuditLog, error) {
+	auditLog := &AuditLog{
+		entries: make([]AuditEntry, size),
+	}
+	if path == "" {
+		return auditLog, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	auditLog.file = file
+	return auditLog, nil
+}
+
+// Record adds an entry to the trail
+func (a *AuditLog) Record(actor, action, target string, details map[string]string) {
+	entry := AuditEntry{
+		Time:    time.Now(),
+		Actor:   actor,
+		Action:  action,
+		Target:  target,
+		Details: details,
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.entries[a.next] = entry
+	a.next = (a.next + 1) % len(a.entries)
+	if a.next == 0 {
+		a.full = true
+	}
+
+	auditLogger.Info("audit", "actor", actor, "action", action, "target", target)
+	if a.file == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		auditLogger.Error("encoding audit entry", logging.Err(err))
+		return
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		auditLogger.Error("writing audit entry", logging.Err(err))
+	}
+}
+
+// Recent returns up to limit of the most recent entries, newest first
+func (a *AuditLog) Recent(limit int) []AuditEntry {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	count := a.next
+	if a.full {
+		count = len(a.entries)
+	}
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+
+	entries := make([]AuditEntry, 0, limit)
+	for i := 1; i <= limit; i++ {
+		entries = append(entries, a.entries[(a.next-i+len(a.entries))%len(a.entries)])
+	}
+	return entries
+}
+
+// Close closes the audit log file
+func (a *AuditLog) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ban keeps a user or an IP address from connecting
+type Ban struct {
+	Kind      string    `json:"kind"`  // "user" or "ip"
+	Value     string    `json:"value"` // The banned user ID or IP
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Zero for a permanent ban
+}
+
+// Ban kinds
+const (
+	BanKindUser = "user"
+	BanKindIP   = "ip"
+)
+
+// BanList holds the bans in force on this node
+type BanList struct {
+	bans  map[string]*Ban // Keyed by kind and value
+	mutex sync.RWMutex
+}
+
+// NewBanList creates an empty ban list
+func NewBanList() *BanList {
+	return &BanList{
+		bans: make(map[string]*Ban),
+	}
+}
+
+// Add bans a user ID or IP for duration, or permanently if duration is
+// zero. A new ban replaces an existing one for the same value.
+func (bl *BanList) Add(kind, value, reason string, duration time.Duration) *Ban {
+	ban := &Ban{
+		Kind:      kind,
+		Value:     value,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if duration > 0 {
+		ban.ExpiresAt = ban.CreatedAt.Add(duration)
+	}
+
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+	bl.bans[banKey(kind, value)] = ban
+	return ban
+}
+
+// Remove lifts a ban. Returns false if there was none.
+func (bl *BanList) Remove(kind, value string) bool {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	key := banKey(kind, value)
+	if _, exists := bl.bans[key]; !exists {
+		return false
+	}
+	delete(bl.bans, key)
+	return true
+}
+
+// Check returns the ban in force for a user ID or IP, if any. Empty
+// values are never banned.
+func (bl *BanList) Check(userID, ip string) (*Ban, bool) {
+	now := time.Now()
+
+	bl.mutex.RLock()
+	defer bl.mutex.RUnlock()
+
+	for _, key := range []string{banKey(BanKindUser, userID), banKey(BanKindIP, ip)} {
+		if ban, exists := bl.bans[key]; exists && ban.Value != "" && ban.activeAt(now) {
+			return ban, true
+		}
+	}
+	return nil, false
+}
+
+// List returns the bans in force, newest first. Expired bans are dropped.
+func (bl *BanList) List() []*Ban {
+	now := time.Now()
+
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	bans := make([]*Ban, 0, len(bl.bans))
+	for key, ban := range bl.bans {
+		if !ban.activeAt(now) {
+			delete(bl.bans, key)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.After(bans[j].CreatedAt)
+	})
+	return bans
+}
+
+func (b *Ban) activeAt(now time.Time) bool {
+	return b.ExpiresAt.IsZero() || now.Before(b.ExpiresAt)
+}
+
+func banKey(kind, value string) string {
+	return kind + ":" + value
+}
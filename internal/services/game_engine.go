@@ -2,9 +2,11 @@ package services
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
 )
@@ -13,6 +15,14 @@ import (
 type GameEngine struct {
 	wordBank *WordBank
 	config   *config.Config
+	history  *GameHistory
+	profiles *ProfileStore
+
+	leaderboards *Leaderboards
+
+	// Closed when the server stops, ending every round timer
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewGameEngine creates a new game engine
@@ -20,12 +30,54 @@ func NewGameEngine(wordBank *WordBank, config *config.Config) *GameEngine {
 	return &GameEngine{
 		wordBank: wordBank,
 		config:   config,
+		stop:     make(chan struct{}),
 	}
 }
 
+// Stop stops every round timer
+func (ge *GameEngine) Stop() {
+	ge.stopOnce.Do(func() {
+		close(ge.stop)
+	})
+}
+
+// Stopped is closed once Stop is called
+func (ge *GameEngine) Stopped() <-chan struct{} {
+	return ge.stop
+}
+
+// SetHistory makes the engine record finished games in history
+func (ge *GameEngine) SetHistory(history *GameHistory) {
+	ge.history = history
+}
+
+// SetProfiles makes the engine add finished rounds and games to players'
+// profiles
+func (ge *GameEngine) SetProfiles(profiles *ProfileStore) {
+	ge.profiles = profiles
+}
+
+// SetLeaderboards makes the engine add finished games to the global
+// leaderboards. Games are only added while a history is set.
+func (ge *GameEngine) SetLeaderboards(leaderboards *Leaderboards) {
+	ge.leaderboards = leaderboards
+}
+
 // StartGame initializes a new game
 func (ge *GameEngine) StartGame(room *models.Room) {
 	room.StartGame()
+	if ge.history != nil {
+		ge.history.StartGame(room)
+	}
+	if ge.profiles != nil {
+		ge.profiles.StartGame(room)
+	}
+}
+
+// HasEnoughPlayers checks whether a room still has enough players to keep
+// a game going
+func (ge *GameEngine) HasEnoughPlayers(room *models.Room) bool {
+	return room.GetPlayerCount() >= ge.config.Game.MinPlayersToStart
 }
 
 // ValidateGuess checks if a guess is correct and calculates points
@@ -113,11 +165,36 @@ func (ge *GameEngine) CalculateDrawerPoints(totalGuessers, totalPlayers, roundDu
 	return points
 }
 
-// EndRound ends the current round
-func (ge *GameEngine) EndRound(room *models.Room) {
+// EndRound ends the current round and adds its results to the game's history
+// and the players' profiles
+func (ge *GameEngine) EndRound(room *models.Room, results websocket.RoundEndData) {
+	if ge.history != nil {
+		ge.history.RecordRound(room, room.CurrentRound, results)
+	}
+	if ge.profiles != nil {
+		ge.profiles.RecordRound(room, results)
+	}
 	room.EndRound()
 }
 
+// EndGame ends the game, records it in the history and returns the players'
+// updated profiles
+func (ge *GameEngine) EndGame(room *models.Room, results websocket.GameEndData) []*models.PublicProfile {
+	if ge.history != nil {
+		record := ge.history.EndGame(room, results)
+		logger.Info("game recorded", logging.RoomID(room.ID), "game_id", record.ID, "rounds", len(record.Rounds))
+		if ge.leaderboards != nil {
+			ge.leaderboards.RecordGame(record)
+		}
+	}
+	var profiles []*models.PublicProfile
+	if ge.profiles != nil {
+		profiles = ge.profiles.RecordGame(room, results)
+	}
+	room.EndGame()
+	return profiles
+}
+
 // GetRandomWord selects a random word and hint
 func (ge *GameEngine) GetRandomWord(difficulty string) (string, string) {
 	word := ge.wordBank.GetRandomWord(difficulty)
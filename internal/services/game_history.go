@@ -0,0 +1,355 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// GameRecord is a finished game kept in the history
+type GameRecord struct {
+	ID          string               `json:"id"`
+	RoomID      string               `json:"room_id"`
+	RoomCode    string               `json:"room_code"`
+	RoomName    string               `json:"room_name"`
+	Difficulty  models.Difficulty    `json:"difficulty"`
+	MaxRounds   int                  `json:"max_rounds"`
+	StartedAt   time.Time            `json:"started_at"`
+	EndedAt     time.Time            `json:"ended_at"`
+	Partial     bool                 `json:"partial,omitempty"` // Rounds played before a restart are missing
+	PlayerIDs   []string             `json:"player_ids"`        // Everyone who played, including those who left early
+	Winner      *models.PublicUser   `json:"winner"`
+	Leaderboard []*models.PublicUser `json:"leaderboard"`
+	Stats       websocket.GameStats  `json:"stats"`
+	Rounds      []RoundRecord        `json:"rounds"`
+}
+
+// RoundRecord is one finished round of a recorded game
+type RoundRecord struct {
+	Round        int                       `json:"round"`
+	Word         string                    `json:"word"`
+	DrawerID     string                    `json:"drawer_id"`
+	DrawerName   string                    `json:"drawer_name"`
+	DrawerPoints int                       `json:"drawer_points"`
+	Guessers     []websocket.GuesserResult `json:"guessers"`
+	EndedAt      time.Time                 `json:"ended_at"`
+}
+
+// pendingGame is a game still being played
+type pendingGame struct {
+	record  *GameRecord
+	updated time.Time
+}
+
+// Games with no round recorded for this long are assumed abandoned, e.g.
+// because their room closed mid-game
+const abandonedGameTimeout = 24 * time.Hour
+
+// GameHistory keeps finished games. Rounds are collected per room while a
+// game runs and the game is recorded when it ends. The most recent games are
+// held in memory; when a file is configured every game is also appended to it
+// as a JSON line and loaded back on start.
+type GameHistory struct {
+	games    []*GameRecord // Oldest first, at most size
+	size     int
+	byID     map[string]*GameRecord
+	byRoom   map[string][]*GameRecord
+	byPlayer map[string][]*GameRecord
+
+	// Games in progress by room ID
+	pending map[string]*pendingGame
+
+	file  *os.File
+	mutex sync.RWMutex
+}
+
+// NewGameHistory creates a history holding up to size games in memory.
+// If path is not empty the games already in that file are loaded and new
+// ones are appended to it.
+func NewGameHistory(path string, size int) (*GameHistory, error) {
+	history := &GameHistory{
+		size:     size,
+		byID:     make(map[string]*GameRecord),
+		byRoom:   make(map[string][]*GameRecord),
+		byPlayer: make(map[string][]*GameRecord),
+		pending:  make(map[string]*pendingGame),
+	}
+	if path == "" {
+		return history, nil
+	}
+
+	if err := history.load(path); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open game history: %w", err)
+	}
+	history.file = file
+	return history, nil
+}
+
+// load reads the games in path, keeping the most recent. Lines that can't be
+// decoded, such as one cut short by a crash, are skipped.
+func (h *GameHistory) load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read game history: %w", err)
+	}
+	defer file.Close()
+
+	var games []*GameRecord
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record := &GameRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil || record.ID == "" {
+			skipped++
+			continue
+		}
+		games = append(games, record)
+		if len(games) > 2*h.size {
+			games = append(games[:0], games[len(games)-h.size:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read game history: %w", err)
+	}
+
+	if len(games) > h.size {
+		games = games[len(games)-h.size:]
+	}
+	for _, record := range games {
+		h.add(record)
+	}
+	if skipped > 0 {
+		logger.Warn("skipped unreadable game history records", "file", path, "count", skipped)
+	}
+	logger.Info("loaded game history", "file", path, "games", len(games))
+	return nil
+}
+
+// StartGame begins collecting rounds for a room's new game
+func (h *GameHistory) StartGame(room *models.Room) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for roomID, game := range h.pending {
+		if time.Since(game.updated) > abandonedGameTimeout {
+			delete(h.pending, roomID)
+		}
+	}
+	h.pending[room.ID] = &pendingGame{
+		record:  newGameRecord(room),
+		updated: time.Now(),
+	}
+}
+
+// RecordRound adds a finished round to a room's game in progress
+func (h *GameHistory) RecordRound(room *models.Room, round int, data websocket.RoundEndData) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	game := h.currentGame(room)
+	game.updated = time.Now()
+	game.record.Rounds = append(game.record.Rounds, RoundRecord{
+		Round:        round,
+		Word:         data.Word,
+		DrawerID:     data.DrawerID,
+		DrawerName:   data.DrawerName,
+		DrawerPoints: data.DrawerPoints,
+		Guessers:     data.Guessers,
+		EndedAt:      game.updated,
+	})
+}
+
+// EndGame records a room's finished game and returns the record
+func (h *GameHistory) EndGame(room *models.Room, data websocket.GameEndData) *GameRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	record := h.currentGame(room).record
+	delete(h.pending, room.ID)
+	record.EndedAt = time.Now()
+	record.Winner = data.Winner
+	record.Leaderboard = data.Leaderboard
+	record.Stats = data.GameStats
+	record.PlayerIDs = gamePlayerIDs(record)
+
+	h.add(record)
+	if len(h.games) > h.size {
+		h.remove(h.games[0])
+	}
+
+	if h.file != nil {
+		line, err := json.Marshal(record)
+		if err != nil {
+			logger.Error("encoding game record", logging.Err(err))
+			return record
+		}
+		if _, err := h.file.Write(append(line, '\n')); err != nil {
+			logger.Error("writing game record", logging.Err(err))
+		}
+	}
+	return record
+}
+
+// currentGame returns a room's game in progress. A game that started before
+// a restart is picked up from where it was, without its earlier rounds.
+// Caller must hold h.mutex.
+func (h *GameHistory) currentGame(room *models.Room) *pendingGame {
+	game, exists := h.pending[room.ID]
+	if !exists {
+		game = &pendingGame{
+			record:  newGameRecord(room),
+			updated: time.Now(),
+		}
+		game.record.Partial = true
+		h.pending[room.ID] = game
+	}
+	return game
+}
+
+// newGameRecord creates the record of a game starting in room
+func newGameRecord(room *models.Room) *GameRecord {
+	return &GameRecord{
+		ID:         "game_" + utils.GenerateRandomString(12),
+		RoomID:     room.ID,
+		RoomCode:   room.Code,
+		RoomName:   room.Name,
+		Difficulty: room.Difficulty,
+		MaxRounds:  room.MaxRounds,
+		StartedAt:  time.Now(),
+		Rounds:     make([]RoundRecord, 0, room.MaxRounds),
+	}
+}
+
+// Get returns a game by ID
+func (h *GameHistory) Get(gameID string) (*GameRecord, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	record, exists := h.byID[gameID]
+	return record, exists
+}
+
+// ListByRoom returns up to limit of a room's games, newest first
+func (h *GameHistory) ListByRoom(roomID string, limit int) []*GameRecord {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return newestFirst(h.byRoom[roomID], limit)
+}
+
+// ListByPlayer returns up to limit of the games a player took part in,
+// newest first
+func (h *GameHistory) ListByPlayer(userID string, limit int) []*GameRecord {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return newestFirst(h.byPlayer[userID], limit)
+}
+
+// Recent returns up to limit of the most recent games, newest first
+func (h *GameHistory) Recent(limit int) []*GameRecord {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return newestFirst(h.games, limit)
+}
+
+// Close closes the history file
+func (h *GameHistory) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// add indexes a record. Caller must hold h.mutex.
+func (h *GameHistory) add(record *GameRecord) {
+	h.games = append(h.games, record)
+	h.byID[record.ID] = record
+	h.byRoom[record.RoomID] = append(h.byRoom[record.RoomID], record)
+	for _, userID := range record.PlayerIDs {
+		h.byPlayer[userID] = append(h.byPlayer[userID], record)
+	}
+}
+
+// remove drops the oldest record from memory. Caller must hold h.mutex.
+func (h *GameHistory) remove(record *GameRecord) {
+	h.games = h.games[1:]
+	delete(h.byID, record.ID)
+	h.byRoom[record.RoomID] = withoutGame(h.byRoom[record.RoomID], record)
+	if len(h.byRoom[record.RoomID]) == 0 {
+		delete(h.byRoom, record.RoomID)
+	}
+	for _, userID := range record.PlayerIDs {
+		h.byPlayer[userID] = withoutGame(h.byPlayer[userID], record)
+		if len(h.byPlayer[userID]) == 0 {
+			delete(h.byPlayer, userID)
+		}
+	}
+}
+
+// gamePlayerIDs lists everyone on the leaderboard or in any round of a game
+func gamePlayerIDs(record *GameRecord) []string {
+	seen := make(map[string]bool)
+	var userIDs []string
+	addPlayer := func(userID string) {
+		if userID != "" && !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	for _, player := range record.Leaderboard {
+		addPlayer(player.ID)
+	}
+	for _, round := range record.Rounds {
+		addPlayer(round.DrawerID)
+		for _, guesser := range round.Guessers {
+			addPlayer(guesser.UserID)
+		}
+	}
+	return userIDs
+}
+
+// withoutGame removes a record from a list kept oldest first
+func withoutGame(games []*GameRecord, record *GameRecord) []*GameRecord {
+	for i, game := range games {
+		if game == record {
+			return append(games[:i:i], games[i+1:]...)
+		}
+	}
+	return games
+}
+
+// newestFirst returns up to limit of games kept oldest first, newest first
+func newestFirst(games []*GameRecord, limit int) []*GameRecord {
+	if limit <= 0 || limit > len(games) {
+		limit = len(games)
+	}
+	result := make([]*GameRecord, 0, limit)
+	for i := len(games) - 1; i >= len(games)-limit; i-- {
+		result = append(result, games[i])
+	}
+	return result
+}
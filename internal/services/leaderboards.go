@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// The windows players are ranked over
+var leaderboardWindows = []models.LeaderboardWindow{
+	models.LeaderboardDaily,
+	models.LeaderboardWeekly,
+	models.LeaderboardAllTime,
+}
+
+// leaderboard is the players' totals over one window
+type leaderboard struct {
+	StartsAt time.Time                           `json:"starts_at"`
+	ResetsAt time.Time                           `json:"resets_at"` // Zero for all-time
+	Entries  map[string]*models.LeaderboardEntry `json:"entries"`   // By user ID
+}
+
+// Leaderboards ranks players server-wide over daily, weekly and all-time
+// windows, fed from finished games. Daily and weekly windows roll over at
+// midnight and Monday midnight in the configured time zone. When a file is
+// configured the leaderboards are saved to it after every game and loaded
+// back on start.
+type Leaderboards struct {
+	windows       map[models.LeaderboardWindow]*leaderboard
+	includeGuests bool
+	location      *time.Location
+
+	path  string
+	stop  chan struct{}
+	mutex sync.RWMutex
+}
+
+// NewLeaderboards creates the leaderboards from their config, loading the
+// ones saved to its file. Windows that ended while the server was down
+// start over.
+func NewLeaderboards(cfg config.LeaderboardConfig) (*Leaderboards, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leaderboard time zone: %w", err)
+	}
+	lb := &Leaderboards{
+		windows:       make(map[models.LeaderboardWindow]*leaderboard),
+		includeGuests: cfg.IncludeGuests,
+		location:      location,
+		path:          cfg.File,
+		stop:          make(chan struct{}),
+	}
+
+	if lb.path != "" {
+		data, err := os.ReadFile(lb.path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read leaderboards: %w", err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &lb.windows); err != nil {
+				return nil, fmt.Errorf("failed to decode leaderboards: %w", err)
+			}
+			logger.Info("loaded leaderboards", "file", lb.path)
+		}
+	}
+
+	now := time.Now()
+	for _, window := range leaderboardWindows {
+		if board, exists := lb.windows[window]; !exists || board.Entries == nil {
+			lb.windows[window] = lb.newLeaderboard(window, now)
+		}
+	}
+	lb.rollover(now)
+	return lb, nil
+}
+
+// Run rolls the daily and weekly leaderboards over as their windows end,
+// until Stop is called
+func (lb *Leaderboards) Run() {
+	for {
+		timer := time.NewTimer(time.Until(lb.nextReset()))
+		select {
+		case <-timer.C:
+			lb.mutex.Lock()
+			lb.rollover(time.Now())
+			lb.mutex.Unlock()
+		case <-lb.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Stop stops rolling leaderboards over
+func (lb *Leaderboards) Stop() {
+	close(lb.stop)
+}
+
+// RecordGame adds a finished game to every window. Only the players still
+// in the room when it ended are counted.
+func (lb *Leaderboards) RecordGame(record *GameRecord) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	// A game finishing right at a window's end counts toward the next one
+	lb.rollover(record.EndedAt)
+
+	totals := make(map[string]*models.LeaderboardEntry, len(record.Leaderboard))
+	for _, player := range record.Leaderboard {
+		if player.Guest && !lb.includeGuests {
+			continue
+		}
+		totals[player.ID] = &models.LeaderboardEntry{
+			UserID:      player.ID,
+			Username:    player.Username,
+			Avatar:      player.Avatar,
+			Guest:       player.Guest,
+			GamesPlayed: 1,
+		}
+	}
+	if len(totals) == 0 {
+		return
+	}
+	if record.Winner != nil {
+		if winner, exists := totals[record.Winner.ID]; exists {
+			winner.Wins = 1
+		}
+	}
+	for _, round := range record.Rounds {
+		if drawer, exists := totals[round.DrawerID]; exists {
+			drawer.Score += round.DrawerPoints
+			drawer.DrawerPoints += round.DrawerPoints
+		}
+		for _, result := range round.Guessers {
+			if guesser, exists := totals[result.UserID]; exists {
+				guesser.Score += result.Points
+				if result.Guessed {
+					guesser.CorrectGuesses++
+				}
+			}
+		}
+	}
+
+	for _, board := range lb.windows {
+		for userID, game := range totals {
+			entry, exists := board.Entries[userID]
+			if !exists {
+				entry = &models.LeaderboardEntry{UserID: userID}
+				board.Entries[userID] = entry
+			}
+			entry.Username = game.Username
+			entry.Avatar = game.Avatar
+			entry.Guest = game.Guest
+			entry.GamesPlayed += game.GamesPlayed
+			entry.Wins += game.Wins
+			entry.Score += game.Score
+			entry.CorrectGuesses += game.CorrectGuesses
+			entry.DrawerPoints += game.DrawerPoints
+		}
+	}
+
+	if err := lb.save(); err != nil {
+		logger.Error("saving leaderboards", logging.Err(err))
+	}
+}
+
+// Top returns up to limit of a window's players, best first by category.
+// Returns false for an unknown window.
+func (lb *Leaderboards) Top(window models.LeaderboardWindow, category models.LeaderboardCategory, limit int) (*models.GlobalLeaderboardData, bool) {
+	lb.mutex.RLock()
+	defer lb.mutex.RUnlock()
+
+	board, exists := lb.windows[window]
+	if !exists {
+		return nil, false
+	}
+
+	entries := make([]*models.LeaderboardEntry, 0, len(board.Entries))
+	for _, entry := range board.Entries {
+		if entry.Guest && !lb.includeGuests {
+			continue
+		}
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if a, b := entries[i].Value(category), entries[j].Value(category); a != b {
+			return a > b
+		}
+		if entries[i].GamesPlayed != entries[j].GamesPlayed {
+			return entries[i].GamesPlayed < entries[j].GamesPlayed
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for i, entry := range entries {
+		entry.Rank = i + 1
+	}
+
+	data := &models.GlobalLeaderboardData{
+		Window:   window,
+		Category: category,
+		StartsAt: board.StartsAt,
+		Entries:  entries,
+	}
+	if !board.ResetsAt.IsZero() {
+		resetsAt := board.ResetsAt
+		data.ResetsAt = &resetsAt
+	}
+	return data, true
+}
+
+// Close saves the leaderboards
+func (lb *Leaderboards) Close() error {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	return lb.save()
+}
+
+// rollover starts over every window that has ended by now.
+// Caller must hold lb.mutex.
+func (lb *Leaderboards) rollover(now time.Time) {
+	rolled := false
+	for window, board := range lb.windows {
+		if board.ResetsAt.IsZero() || now.Before(board.ResetsAt) {
+			continue
+		}
+		logger.Info("leaderboard rolled over", "window", window, "players", len(board.Entries))
+		lb.windows[window] = lb.newLeaderboard(window, now)
+		rolled = true
+	}
+	if !rolled {
+		return
+	}
+	if err := lb.save(); err != nil {
+		logger.Error("saving leaderboards", logging.Err(err))
+	}
+}
+
+// nextReset returns when the next window ends
+func (lb *Leaderboards) nextReset() time.Time {
+	lb.mutex.RLock()
+	defer lb.mutex.RUnlock()
+
+	var next time.Time
+	for _, board := range lb.windows {
+		if !board.ResetsAt.IsZero() && (next.IsZero() || board.ResetsAt.Before(next)) {
+			next = board.ResetsAt
+		}
+	}
+	return next
+}
+
+// newLeaderboard creates an empty leaderboard for the window containing now
+func (lb *Leaderboards) newLeaderboard(window models.LeaderboardWindow, now time.Time) *leaderboard {
+	board := &leaderboard{
+		StartsAt: now,
+		Entries:  make(map[string]*models.LeaderboardEntry),
+	}
+
+	year, month, day := now.In(lb.location).Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, lb.location)
+	switch window {
+	case models.LeaderboardDaily:
+		board.StartsAt = midnight
+		board.ResetsAt = midnight.AddDate(0, 0, 1)
+	case models.LeaderboardWeekly:
+		// Weeks start on Monday
+		daysSinceMonday := (int(midnight.Weekday()) + 6) % 7
+		board.StartsAt = midnight.AddDate(0, 0, -daysSinceMonday)
+		board.ResetsAt = board.StartsAt.AddDate(0, 0, 7)
+	}
+	return board
+}
+
+// save writes the leaderboards to their file, replacing it atomically.
+// Caller must hold lb.mutex.
+func (lb *Leaderboards) save() error {
+	if lb.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(lb.windows)
+	if err != nil {
+		return fmt.Errorf("failed to encode leaderboards: %w", err)
+	}
+	if err := writeFileAtomic(lb.path, data); err != nil {
+		return fmt.Errorf("failed to save leaderboards: %w", err)
+	}
+	return nil
+}
@@ -2,19 +2,61 @@ package services
 
 import (
 	"sort"
+	"time"
 
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 )
 
-// FindBestPublicRoom finds the best public room to join
-func (rm *RoomManager) FindBestPublicRoom(maxPlayers int, difficulty string) *models.PublicRoomInfo {
+// SetRatings sets how the manager looks up a player's skill rating for
+// skill-based matchmaking. Must be called before the manager is used.
+func (rm *RoomManager) SetRatings(rating func(userID string) int) {
+	rm.rating = rating
+}
+
+// FindMatch finds the best public room for a player who has been waiting
+// for waited, using the configured matchmaking mode
+func (rm *RoomManager) FindMatch(userID string, waited time.Duration, maxPlayers int, difficulty, language string) *models.PublicRoomInfo {
+	if !rm.SkillMatchmaking() {
+		return rm.FindBestPublicRoom(maxPlayers, difficulty, language)
+	}
+	return rm.FindRatedPublicRoom(rm.rating(userID), rm.AllowedRatingGap(waited), maxPlayers, difficulty, language)
+}
+
+// SkillMatchmaking reports whether players are matched by skill rating
+func (rm *RoomManager) SkillMatchmaking() bool {
+	return rm.config.Matchmaking.Mode == config.MatchmakingSkill && rm.rating != nil
+}
+
+// Rating returns a player's skill rating
+func (rm *RoomManager) Rating(userID string) int {
+	if rm.rating == nil {
+		return rm.config.Rating.Initial
+	}
+	return rm.rating(userID)
+}
+
+// AllowedRatingGap returns the widest gap allowed between a player's rating
+// and a room's average rating once they have waited for waited
+func (rm *RoomManager) AllowedRatingGap(waited time.Duration) int {
+	cfg := rm.config.Matchmaking
+	gap := cfg.RatingGap + int(waited/time.Second)*cfg.RatingGapGrowth
+	if gap > cfg.MaxRatingGap {
+		gap = cfg.MaxRatingGap
+	}
+	return gap
+}
+
+// FindBestPublicRoom finds the best public room to join. Empty difficulty
+// and language match any room.
+func (rm *RoomManager) FindBestPublicRoom(maxPlayers int, difficulty, language string) *models.PublicRoomInfo {
 	rm.mutex.RLock()
 	defer rm.mutex.RUnlock()
 
 	var candidates []*models.PublicRoomInfo
 	for _, room := range rm.rooms {
 		if room.Type == models.RoomTypePublic && room.State == models.GameStateLobby && !room.IsFull() && room.IsActive(rm.config.Game.InactiveRoomTimeout) {
-			if room.MaxPlayers <= maxPlayers && (difficulty == "" || string(room.Difficulty) == difficulty) {
+			if room.MaxPlayers <= maxPlayers && (difficulty == "" || string(room.Difficulty) == difficulty) && (language == "" || room.Language == language) {
 				candidates = append(candidates, room.GetPublicRoomInfo())
 			}
 		}
@@ -37,19 +79,109 @@ func (rm *RoomManager) FindBestPublicRoom(maxPlayers int, difficulty string) *mo
 	return candidates[0]
 }
 
-// AutoJoinPublicRoom automatically joins a player to a suitable public room
-func (rm *RoomManager) AutoJoinPublicRoom(userID string, user *models.User) *models.PublicRoomInfo {
-	roomInfo := rm.FindBestPublicRoom(rm.config.Game.MaxPlayersPerRoom, "")
+// FindRatedPublicRoom finds the public room whose players' average rating is
+// closest to rating, within maxGap. Rooms equally close are ranked like
+// FindBestPublicRoom.
+func (rm *RoomManager) FindRatedPublicRoom(rating, maxGap, maxPlayers int, difficulty, language string) *models.PublicRoomInfo {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	var candidates []*models.Room
+	gaps := make(map[string]int)
+	for _, room := range rm.rooms {
+		if room.Type != models.RoomTypePublic || room.State != models.GameStateLobby || room.IsFull() || !room.IsActive(rm.config.Game.InactiveRoomTimeout) {
+			continue
+		}
+		if room.MaxPlayers > maxPlayers || (difficulty != "" && string(room.Difficulty) != difficulty) {
+			continue
+		}
+		if language != "" && room.Language != language {
+			continue
+		}
+		gap := rm.averageRating(room) - rating
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap <= maxGap {
+			candidates = append(candidates, room)
+			gaps[room.ID] = gap
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if gaps[a.ID] != gaps[b.ID] {
+			return gaps[a.ID] < gaps[b.ID]
+		}
+		if a.GetPlayerCount() != b.GetPlayerCount() {
+			return a.GetPlayerCount() > b.GetPlayerCount()
+		}
+		return a.LastActivity.After(b.LastActivity)
+	})
+
+	return candidates[0].GetPublicRoomInfo()
+}
+
+// averageRating returns the average skill rating of a room's players.
+// Caller must hold rm.mutex.
+func (rm *RoomManager) averageRating(room *models.Room) int {
+	playerIDs := room.GetPlayerIDs()
+	if len(playerIDs) == 0 {
+		return rm.config.Rating.Initial
+	}
+	total := 0
+	for _, userID := range playerIDs {
+		total += rm.Rating(userID)
+	}
+	return total / len(playerIDs)
+}
+
+// CreateMatchRoom creates a public room for matched players with their
+// preferences. Unset preferences fall back to easy words and the configured
+// room size. The room starts on its own once full. It returns nil if the
+// room couldn't be created.
+func (rm *RoomManager) CreateMatchRoom(hostID string, prefs models.QuickPlayData) *models.Room {
+	if prefs.Difficulty == "" {
+		prefs.Difficulty = string(models.DifficultyEasy)
+	}
+	if prefs.MaxPlayers == 0 {
+		prefs.MaxPlayers = rm.config.Game.MaxPlayersPerRoom
+	}
+
+	room := rm.CreateRoom(hostID, models.RoomTypePublic, "Quick Play Room", models.CreateRoomData{
+		RoomName:   "Quick Play Room",
+		RoomType:   "public",
+		MaxPlayers: prefs.MaxPlayers,
+		RoundTime:  int(rm.config.Game.RoundDuration.Seconds()),
+		MaxRounds:  rm.config.Game.MaxRounds,
+		Difficulty: prefs.Difficulty,
+		Language:   prefs.Language,
+	})
+	if room == nil {
+		return nil
+	}
+	room.AutoStart = true
+	return room
+}
+
+// AutoJoinPublicRoom immediately joins a player to a suitable public room,
+// creating one with their preferences if none fits
+func (rm *RoomManager) AutoJoinPublicRoom(userID string, user *models.User, prefs models.QuickPlayData) *models.PublicRoomInfo {
+	maxPlayers := prefs.MaxPlayers
+	if maxPlayers == 0 {
+		maxPlayers = rm.config.Game.MaxPlayersPerRoom
+	}
+	roomInfo := rm.FindMatch(userID, 0, maxPlayers, prefs.Difficulty, prefs.Language)
 	if roomInfo == nil {
 		// Create new room if none suitable
-		room := rm.CreateRoom(userID, models.RoomTypePublic, "Auto-Created Room", models.CreateRoomData{
-			RoomName:   "Auto-Created Room",
-			RoomType:   "public",
-			MaxPlayers: rm.config.Game.MaxPlayersPerRoom,
-			RoundTime:  int(rm.config.Game.RoundDuration.Seconds()),
-			MaxRounds:  rm.config.Game.MaxRounds,
-			Difficulty: "easy",
-		})
+		room := rm.CreateMatchRoom(userID, prefs)
+		if room == nil {
+			return nil
+		}
 		roomInfo = room.GetPublicRoomInfo()
 	}
 
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/websocket"
+)
+
+// ProfileStore keeps the lifetime statistics and skill ratings of registered
+// players. Guests have no profile. Rounds are added as they end and games
+// when they finish.
+// When a file is configured every profile is saved to it as a JSON array
+// after each game and loaded back on start.
+type ProfileStore struct {
+	profiles map[string]*models.Profile // By user ID
+	rating   config.RatingConfig
+
+	// Points scored in each room's game in progress, by room ID
+	gameScores map[string]*gameScores
+
+	path  string
+	dirty bool // Changed since the last save
+	mutex sync.RWMutex
+}
+
+// gameScores are the points each player scored in a game in progress
+type gameScores struct {
+	points  map[string]int // By user ID
+	updated time.Time
+
+	// Everyone who played in the game, by user ID, so players who leave
+	// before it ends are still rated
+	players map[string]*models.User
+}
+
+// newGameScores starts an empty score sheet
+func newGameScores() *gameScores {
+	return &gameScores{
+		points:  make(map[string]int),
+		updated: time.Now(),
+		players: make(map[string]*models.User),
+	}
+}
+
+// addPlayer notes that a player took part in the game
+func (g *gameScores) addPlayer(player *models.User) {
+	g.players[player.ID] = player.Copy()
+}
+
+// NewProfileStore creates a profile store rating players as configured. If
+// path is not empty the profiles already saved to it are loaded.
+func NewProfileStore(path string, rating config.RatingConfig) (*ProfileStore, error) {
+	ps := &ProfileStore{
+		profiles:   make(map[string]*models.Profile),
+		rating:     rating,
+		gameScores: make(map[string]*gameScores),
+		path:       path,
+	}
+	if path == "" {
+		return ps, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ps, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var profiles []*models.Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to decode profiles: %w", err)
+	}
+	for _, profile := range profiles {
+		if profile.Words == nil {
+			profile.Words = make(map[string]int)
+		}
+		if profile.Rating == 0 {
+			profile.Rating = rating.Initial
+		}
+		ps.profiles[profile.UserID] = profile
+	}
+	logger.Info("loaded profiles", "file", path, "profiles", len(profiles))
+	return ps, nil
+}
+
+// StartGame starts counting the points scored in a room's new game
+func (ps *ProfileStore) StartGame(room *models.Room) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	for roomID, game := range ps.gameScores {
+		if time.Since(game.updated) > abandonedGameTimeout {
+			delete(ps.gameScores, roomID)
+		}
+	}
+	game := newGameScores()
+	for _, userID := range room.GetPlayerIDs() {
+		if player, exists := room.GetPlayer(userID); exists {
+			game.addPlayer(player)
+		}
+	}
+	ps.gameScores[room.ID] = game
+}
+
+// RecordRound adds a finished round to the profiles of the registered
+// players who took part in it
+func (ps *ProfileStore) RecordRound(room *models.Room, data websocket.RoundEndData) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	game, exists := ps.gameScores[room.ID]
+	if !exists {
+		// The game started before a restart
+		game = newGameScores()
+		ps.gameScores[room.ID] = game
+	}
+	game.updated = time.Now()
+	scores := game.points
+	word := strings.ToLower(strings.TrimSpace(data.Word))
+
+	guessed := false
+	for _, result := range data.Guessers {
+		scores[result.UserID] += result.Points
+		if result.Guessed {
+			guessed = true
+		}
+
+		player, exists := room.GetPlayer(result.UserID)
+		if !exists {
+			continue
+		}
+		game.addPlayer(player)
+		if player.IsGuest() {
+			continue
+		}
+		profile := ps.profile(player)
+		profile.RoundsPlayed++
+		profile.TotalGuesses += player.RoundGuesses()
+		if result.Guessed {
+			profile.CorrectGuesses++
+			profile.GuessSeconds += result.GuessTime
+			profile.Words[word]++
+		}
+	}
+
+	scores[data.DrawerID] += data.DrawerPoints
+	drawer, exists := room.GetPlayer(data.DrawerID)
+	if exists {
+		game.addPlayer(drawer)
+	}
+	if exists && !drawer.IsGuest() {
+		profile := ps.profile(drawer)
+		profile.RoundsPlayed++
+		profile.TimesDrawer++
+		if guessed {
+			profile.DrawingsGuessed++
+		}
+		profile.Words[word]++
+	}
+}
+
+// RecordGame adds a finished game to the profiles of the registered players
+// who played in it, rates them by their placements and saves them. Players
+// who left before the end are rated as finishing last. Guests take part in
+// the rating at the initial rating. Returns the updated profiles.
+func (ps *ProfileStore) RecordGame(room *models.Room, data websocket.GameEndData) []*models.PublicProfile {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	game, exists := ps.gameScores[room.ID]
+	if !exists {
+		game = newGameScores()
+	}
+	delete(ps.gameScores, room.ID)
+	scores := game.points
+
+	// Players still in the room are placed by their scores
+	players := make(map[string]*models.User, len(game.players))
+	standings := make(map[string]int, len(game.players))
+	lowest := 0
+	for _, entry := range data.Leaderboard {
+		player, exists := room.GetPlayer(entry.ID)
+		if !exists {
+			continue
+		}
+		if len(players) == 0 || scores[entry.ID] < lowest {
+			lowest = scores[entry.ID]
+		}
+		players[entry.ID] = player
+		standings[entry.ID] = scores[entry.ID]
+	}
+	// Everyone who left is placed below them, whatever they had scored
+	for userID, player := range game.players {
+		if _, stayed := players[userID]; stayed {
+			continue
+		}
+		players[userID] = player
+		standings[userID] = lowest - 1
+	}
+
+	ratings := make(map[string]int, len(players))
+	for userID := range players {
+		ratings[userID] = ps.ratingLocked(userID)
+	}
+	changes := ratingChanges(ratings, standings, ps.rating.KFactor)
+
+	updated := make([]*models.PublicProfile, 0, len(players))
+	for userID, player := range players {
+		if player.IsGuest() {
+			continue
+		}
+		profile := ps.profile(player)
+		profile.Rating += changes[userID]
+		profile.GamesPlayed++
+		if data.Winner != nil && data.Winner.ID == userID {
+			profile.Wins++
+		}
+		score := scores[userID]
+		profile.TotalScore += score
+		if score > profile.BestScore {
+			profile.BestScore = score
+		}
+		updated = append(updated, profile.ToPublicProfile())
+	}
+
+	if err := ps.save(); err != nil {
+		logger.Error("saving profiles", logging.RoomID(room.ID), logging.Err(err))
+	}
+	return updated
+}
+
+// Get returns a player's profile
+func (ps *ProfileStore) Get(userID string) (*models.PublicProfile, bool) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	profile, exists := ps.profiles[userID]
+	if !exists {
+		return nil, false
+	}
+	return profile.ToPublicProfile(), true
+}
+
+// Rating returns a player's skill rating. Guests and players without a
+// profile have the initial rating.
+func (ps *ProfileStore) Rating(userID string) int {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	return ps.ratingLocked(userID)
+}
+
+// ratingLocked returns a player's skill rating.
+// Caller must hold ps.mutex.
+func (ps *ProfileStore) ratingLocked(userID string) int {
+	if profile, exists := ps.profiles[userID]; exists {
+		return profile.Rating
+	}
+	return ps.rating.Initial
+}
+
+// Close saves any rounds recorded since the last finished game
+func (ps *ProfileStore) Close() error {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	return ps.save()
+}
+
+// profile returns a player's profile, creating it on their first round,
+// and marks it changed. Caller must hold ps.mutex.
+func (ps *ProfileStore) profile(player *models.User) *models.Profile {
+	user := player.Copy()
+	profile, exists := ps.profiles[user.ID]
+	if !exists {
+		profile = models.NewProfile(user.ID, user.Username, user.Avatar, ps.rating.Initial)
+		ps.profiles[user.ID] = profile
+	}
+	profile.Username = user.Username
+	profile.Avatar = user.Avatar
+	profile.UpdatedAt = time.Now()
+	ps.dirty = true
+	return profile
+}
+
+// save writes every profile to the profiles file, replacing it atomically.
+// Caller must hold ps.mutex.
+func (ps *ProfileStore) save() error {
+	if ps.path == "" || !ps.dirty {
+		return nil
+	}
+
+	profiles := make([]*models.Profile, 0, len(ps.profiles))
+	for _, profile := range ps.profiles {
+		profiles = append(profiles, profile)
+	}
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+
+	if err := writeFileAtomic(ps.path, data); err != nil {
+		return fmt.Errorf("failed to save profiles: %w", err)
+	}
+	ps.dirty = false
+	return nil
+}
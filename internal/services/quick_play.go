@@ -0,0 +1,380 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// How often queued players are matched and sent their status
+const quickPlayInterval = time.Second
+
+// QuickPlayTicket is a player's place in the quick play queue
+type QuickPlayTicket struct {
+	UserID      string
+	Preferences models.QuickPlayData
+	QueuedAt    time.Time
+}
+
+// QuickPlayMatch is a room picked for queued players, who still have to be
+// joined to it. Created is set when the room was made for them.
+type QuickPlayMatch struct {
+	Room    *models.Room
+	Tickets []*QuickPlayTicket
+	Created bool
+}
+
+// QuickPlayQueue holds players waiting for quick play, oldest first. Each
+// pass fills public rooms that suit them, groups compatible players into
+// new rooms once enough are queued, and gives players who waited too long a
+// room of their own for others to join.
+type QuickPlayQueue struct {
+	tickets     []*QuickPlayTicket
+	roomManager *RoomManager
+	config      config.MatchmakingConfig
+	maxPlayers  int
+
+	// Moving average of how long matched players waited, for estimates
+	averageWait time.Duration
+
+	stop  chan struct{}
+	mutex sync.Mutex
+	// Serializes Match, which works on a copy of the tickets
+	matching sync.Mutex
+}
+
+// NewQuickPlayQueue creates an empty quick play queue
+func NewQuickPlayQueue(roomManager *RoomManager) *QuickPlayQueue {
+	cfg := config.GetConfig()
+	return &QuickPlayQueue{
+		roomManager: roomManager,
+		config:      cfg.Matchmaking,
+		maxPlayers:  cfg.Game.MaxPlayersPerRoom,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Run calls match every quickPlayInterval until Stop is called
+func (q *QuickPlayQueue) Run(match func()) {
+	ticker := time.NewTicker(quickPlayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			match()
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// Stop stops matching queued players
+func (q *QuickPlayQueue) Stop() {
+	close(q.stop)
+}
+
+// Join queues a player with their preferences. Returns false if they are
+// already queued.
+func (q *QuickPlayQueue) Join(userID string, prefs models.QuickPlayData) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.position(userID) >= 0 {
+		return false
+	}
+	q.tickets = append(q.tickets, &QuickPlayTicket{
+		UserID:      userID,
+		Preferences: prefs,
+		QueuedAt:    time.Now(),
+	})
+	return true
+}
+
+// Leave takes a player out of the queue. Returns false if they weren't queued.
+func (q *QuickPlayQueue) Leave(userID string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	i := q.position(userID)
+	if i < 0 {
+		return false
+	}
+	q.tickets = append(q.tickets[:i], q.tickets[i+1:]...)
+	return true
+}
+
+// Requeue puts back a matched player who couldn't be joined to their room,
+// keeping their place in the queue
+func (q *QuickPlayQueue) Requeue(ticket *QuickPlayTicket) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.position(ticket.UserID) >= 0 {
+		return
+	}
+	i := sort.Search(len(q.tickets), func(i int) bool {
+		return q.tickets[i].QueuedAt.After(ticket.QueuedAt)
+	})
+	q.tickets = append(q.tickets, nil)
+	copy(q.tickets[i+1:], q.tickets[i:])
+	q.tickets[i] = ticket
+}
+
+// Queued returns the IDs of the queued players, oldest first
+func (q *QuickPlayQueue) Queued() []string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	userIDs := make([]string, len(q.tickets))
+	for i, ticket := range q.tickets {
+		userIDs[i] = ticket.UserID
+	}
+	return userIDs
+}
+
+// Status returns a queued player's place in the queue and how much longer
+// they are expected to wait. Returns false if they aren't queued.
+func (q *QuickPlayQueue) Status(userID string, now time.Time) (*models.QuickPlayStatusData, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	i := q.position(userID)
+	if i < 0 {
+		return nil, false
+	}
+	waited := now.Sub(q.tickets[i].QueuedAt)
+
+	// Nobody waits past the max wait, so no estimate goes beyond it
+	expected := q.averageWait
+	if expected == 0 || expected > q.config.QuickPlayMaxWait {
+		expected = q.config.QuickPlayMaxWait
+	}
+	eta := expected - waited
+	if eta < 0 {
+		eta = 0
+	}
+
+	return &models.QuickPlayStatusData{
+		State:     "queued",
+		Position:  i + 1,
+		QueueSize: len(q.tickets),
+		Waited:    int(waited / time.Second),
+		ETA:       int(eta / time.Second),
+	}, true
+}
+
+// Match takes the players that can be placed out of the queue, oldest
+// first, and returns the rooms picked for them. Rooms are found and created
+// without holding q.mutex, since the room manager takes its own lock; a
+// player who leaves the queue meanwhile is left out of their match.
+func (q *QuickPlayQueue) Match(now time.Time) []*QuickPlayMatch {
+	q.matching.Lock()
+	defer q.matching.Unlock()
+
+	q.mutex.Lock()
+	tickets := append([]*QuickPlayTicket(nil), q.tickets...)
+	q.mutex.Unlock()
+
+	var matches []*QuickPlayMatch
+	matched := make(map[*QuickPlayTicket]bool)
+	byRoom := make(map[string]*QuickPlayMatch)
+	// Seats promised to matched players, by room ID
+	reserved := make(map[string]int)
+
+	for i, ticket := range tickets {
+		if matched[ticket] {
+			continue
+		}
+		waited := now.Sub(ticket.QueuedAt)
+
+		// Fill rooms that are already waiting for players first
+		prefs := ticket.Preferences
+		if room := q.findRoom(ticket, waited, reserved); room != nil {
+			match, exists := byRoom[room.ID]
+			if !exists {
+				match = &QuickPlayMatch{Room: room}
+				byRoom[room.ID] = match
+				matches = append(matches, match)
+			}
+			match.Tickets = append(match.Tickets, ticket)
+			matched[ticket] = true
+			reserved[room.ID]++
+			continue
+		}
+
+		// Then group compatible players into a room of their own
+		group := q.group(tickets, i, matched, now)
+		if group == nil && waited < q.config.QuickPlayMaxWait {
+			continue
+		}
+		if group == nil {
+			group = []*QuickPlayTicket{ticket}
+		}
+		for _, member := range group[1:] {
+			prefs = mergePreferences(prefs, member.Preferences)
+		}
+		prefs.MaxPlayers = q.roomSize(prefs)
+
+		room := q.roomManager.CreateMatchRoom(ticket.UserID, prefs)
+		if room == nil {
+			// The group stays queued for the next pass
+			continue
+		}
+		match := &QuickPlayMatch{Room: room, Tickets: group, Created: true}
+		byRoom[room.ID] = match
+		matches = append(matches, match)
+		reserved[room.ID] = len(group)
+		for _, member := range group {
+			matched[member] = true
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	queued := make(map[*QuickPlayTicket]bool, len(q.tickets))
+	remaining := q.tickets[:0]
+	for _, ticket := range q.tickets {
+		if !matched[ticket] {
+			remaining = append(remaining, ticket)
+			continue
+		}
+		queued[ticket] = true
+		waited := now.Sub(ticket.QueuedAt)
+		if q.averageWait == 0 {
+			q.averageWait = waited
+		} else {
+			q.averageWait = (q.averageWait*4 + waited) / 5
+		}
+	}
+	q.tickets = remaining
+
+	for _, match := range matches {
+		stillQueued := match.Tickets[:0]
+		for _, ticket := range match.Tickets {
+			if queued[ticket] {
+				stillQueued = append(stillQueued, ticket)
+			}
+		}
+		match.Tickets = stillQueued
+	}
+	return matches
+}
+
+// findRoom returns an existing public room with a free seat that suits a
+// queued player, if there is one
+func (q *QuickPlayQueue) findRoom(ticket *QuickPlayTicket, waited time.Duration, reserved map[string]int) *models.Room {
+	prefs := ticket.Preferences
+	maxPlayers := prefs.MaxPlayers
+	if maxPlayers == 0 {
+		maxPlayers = q.maxPlayers
+	}
+	roomInfo := q.roomManager.FindMatch(ticket.UserID, waited, maxPlayers, prefs.Difficulty, prefs.Language)
+	if roomInfo == nil {
+		return nil
+	}
+	room := q.roomManager.GetRoom(roomInfo.ID)
+	if room == nil || room.GetPlayerCount()+reserved[room.ID] >= room.MaxPlayers {
+		return nil
+	}
+	return room
+}
+
+// group returns enough unmatched players compatible with the ticket at i to
+// fill a new room, starting with that ticket, or nil if there aren't enough
+// yet
+func (q *QuickPlayQueue) group(tickets []*QuickPlayTicket, i int, matched map[*QuickPlayTicket]bool, now time.Time) []*QuickPlayTicket {
+	first := tickets[i]
+	group := []*QuickPlayTicket{first}
+	prefs := first.Preferences
+
+	// In skill mode, players are grouped with others rated close to the one
+	// who has waited longest
+	skill := q.roomManager.SkillMatchmaking()
+	var rating, maxGap int
+	if skill {
+		rating = q.roomManager.Rating(first.UserID)
+		maxGap = q.roomManager.AllowedRatingGap(now.Sub(first.QueuedAt))
+	}
+
+	for _, ticket := range tickets[i+1:] {
+		if len(group) >= q.roomSize(prefs) {
+			break
+		}
+		if matched[ticket] || !compatiblePreferences(prefs, ticket.Preferences) {
+			continue
+		}
+		merged := mergePreferences(prefs, ticket.Preferences)
+		if q.roomSize(merged) < len(group)+1 {
+			continue
+		}
+		if skill {
+			gap := q.roomManager.Rating(ticket.UserID) - rating
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap > maxGap {
+				continue
+			}
+		}
+		group = append(group, ticket)
+		prefs = merged
+	}
+
+	if len(group) < q.roomSize(prefs) {
+		return nil
+	}
+	return group
+}
+
+// roomSize returns the size of a new room for players with these
+// preferences, which is also how many queued players are grouped into it:
+// the configured group size, or smaller if they asked for it
+func (q *QuickPlayQueue) roomSize(prefs models.QuickPlayData) int {
+	if prefs.MaxPlayers != 0 && prefs.MaxPlayers < q.config.QuickPlayGroupSize {
+		return prefs.MaxPlayers
+	}
+	return q.config.QuickPlayGroupSize
+}
+
+// position returns the index of a player's ticket, or -1 if they aren't
+// queued. Caller must hold q.mutex.
+func (q *QuickPlayQueue) position(userID string) int {
+	for i, ticket := range q.tickets {
+		if ticket.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// compatiblePreferences reports whether two players' preferences can share a
+// room. Unset preferences match anything.
+func compatiblePreferences(a, b models.QuickPlayData) bool {
+	if a.Difficulty != "" && b.Difficulty != "" && a.Difficulty != b.Difficulty {
+		return false
+	}
+	return a.Language == "" || b.Language == "" || a.Language == b.Language
+}
+
+// mergePreferences returns preferences that satisfy two compatible players
+func mergePreferences(a, b models.QuickPlayData) models.QuickPlayData {
+	if a.Difficulty == "" {
+		a.Difficulty = b.Difficulty
+	}
+	if a.Language == "" {
+		a.Language = b.Language
+	}
+	if a.MaxPlayers == 0 || (b.MaxPlayers != 0 && b.MaxPlayers < a.MaxPlayers) {
+		a.MaxPlayers = b.MaxPlayers
+	}
+	return a
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+// ratingChanges returns how far each player's rating moves after a game.
+// The game counts as a one-on-one result between every pair of players,
+// decided by their scores in it (Elo extended to several players), so
+// beating higher rated players gains more. The changes are scaled so one
+// game moves a rating by at most kFactor.
+func ratingChanges(ratings, scores map[string]int, kFactor float64) map[string]int {
+	changes := make(map[string]int, len(ratings))
+	if len(ratings) < 2 {
+		return changes
+	}
+
+	// Sorted so rounding is the same every time
+	players := make([]string, 0, len(ratings))
+	for userID := range ratings {
+		players = append(players, userID)
+	}
+	sort.Strings(players)
+
+	scale := kFactor / float64(len(players)-1)
+	for _, player := range players {
+		total := 0.0
+		for _, opponent := range players {
+			if opponent == player {
+				continue
+			}
+			expected := 1 / (1 + math.Pow(10, float64(ratings[opponent]-ratings[player])/400))
+			actual := 0.5
+			if scores[player] > scores[opponent] {
+				actual = 1
+			} else if scores[player] < scores[opponent] {
+				actual = 0
+			}
+			total += actual - expected
+		}
+		changes[player] = int(math.Round(scale * total))
+	}
+	return changes
+}
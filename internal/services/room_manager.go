@@ -1,14 +1,16 @@
 package services
 
 import (
-	"log"
 	"sync"
 	"time"
 
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 )
 
+var logger = logging.For("rooms")
+
 // RoomManager manages game rooms
 type RoomManager struct {
 	rooms       map[string]*models.Room
@@ -16,8 +18,32 @@ type RoomManager struct {
 	mutex       sync.RWMutex
 	config      *config.Config
 	cleanupStop chan struct{}
+
+	// Reports whether this node owns a room ID or code; nil means it owns all
+	ownsKey func(key string) bool
+
+	// Pending removals of disconnected players, keyed by room and user ID
+	removals      map[string]*time.Timer
+	removalsMutex sync.Mutex
+
+	// Persists room snapshots; nil when persistence is off
+	store RoomStore
+
+	// Returns a player's current resume token, for snapshots
+	resumeToken func(userID string) string
+
+	// Returns a player's skill rating, for matchmaking; nil rates everyone
+	// the same
+	rating func(userID string) int
 }
 
+// Attempts at generating a free room ID and code owned by this node before
+// giving up on creating the room
+const maxRoomKeyAttempts = 1000
+
+// How soon a removal that couldn't be carried out is tried again
+const removalRetryDelay = time.Second
+
 // NewRoomManager creates a new room manager
 func NewRoomManager() *RoomManager {
 	return &RoomManager{
@@ -25,22 +51,57 @@ func NewRoomManager() *RoomManager {
 		roomByCode: make(map[string]*models.Room),
 		cleanupStop: make(chan struct{}),
 		config:     config.GetConfig(),
+		removals:   make(map[string]*time.Timer),
 	}
 }
 
-// CreateRoom creates a new room
+// SetOwnershipCheck sets how the manager tells whether this node owns a
+// room ID or code. New rooms are only given IDs and codes this node owns.
+func (rm *RoomManager) SetOwnershipCheck(ownsKey func(key string) bool) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.ownsKey = ownsKey
+}
+
+// SetStore sets where room snapshots are persisted, and how a player's
+// resume token is looked up so it can be saved with them. Must be called
+// before the manager is used.
+func (rm *RoomManager) SetStore(store RoomStore, resumeToken func(userID string) string) {
+	rm.store = store
+	rm.resumeToken = resumeToken
+}
+
+// CreateRoom creates a new room. It returns nil if no free room code owned
+// by this node could be found.
 func (rm *RoomManager) CreateRoom(hostID string, roomType models.RoomType, roomName string, settings models.CreateRoomData) *models.Room {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
 	room := models.NewRoom(hostID, roomType, roomName, settings)
+	for attempt := 1; attempt < maxRoomKeyAttempts && !rm.canUseKeys(room); attempt++ {
+		room = models.NewRoom(hostID, roomType, roomName, settings)
+	}
+	if !rm.canUseKeys(room) {
+		logger.Warn("no free room code owned by this node", logging.UserID(hostID))
+		return nil
+	}
+	room.MaxSpectators = rm.config.Game.MaxSpectatorsPerRoom
 	rm.rooms[room.ID] = room
 	rm.roomByCode[room.Code] = room
 
-	log.Printf("Created room %s (%s) by host %s", room.ID, room.Code, hostID)
+	logger.Info("created room", logging.RoomID(room.ID), logging.RoomCode(room.Code), logging.UserID(hostID))
 	return room
 }
 
+// canUseKeys reports whether a new room's code is free and its ID and code
+// are owned by this node. Caller must hold rm.mutex.
+func (rm *RoomManager) canUseKeys(room *models.Room) bool {
+	if rm.roomByCode[room.Code] != nil {
+		return false
+	}
+	return rm.ownsKey == nil || (rm.ownsKey(room.ID) && rm.ownsKey(room.Code))
+}
+
 // GetRoom returns a room by ID
 func (rm *RoomManager) GetRoom(roomID string) *models.Room {
 	rm.mutex.RLock()
@@ -88,8 +149,8 @@ func (rm *RoomManager) JoinRoom(roomID, userID string, user *models.User) bool {
 	return room.AddPlayer(user)
 }
 
-// LeaveRoom removes a player from a room
-func (rm *RoomManager) LeaveRoom(roomID, userID string) bool {
+// SpectateRoom adds a user to a room as a spectator
+func (rm *RoomManager) SpectateRoom(roomID, userID string, user *models.User) bool {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
@@ -98,16 +159,165 @@ func (rm *RoomManager) LeaveRoom(roomID, userID string) bool {
 		return false
 	}
 
-	if room.RemovePlayer(userID) {
-		// If room is empty, remove it
-		if room.GetPlayerCount() == 0 {
-			delete(rm.rooms, roomID)
-			delete(rm.roomByCode, room.Code)
-			log.Printf("Removed empty room %s (%s)", room.ID, room.Code)
+	return room.AddSpectator(user)
+}
+
+// LeaveRoom removes a player or spectator from a room. The room itself is
+// removed when its last player leaves, even if spectators remain.
+func (rm *RoomManager) LeaveRoom(roomID, userID string) bool {
+	rm.mutex.Lock()
+	room := rm.rooms[roomID]
+	if room == nil {
+		rm.mutex.Unlock()
+		return false
+	}
+
+	wasPlayer := room.RemovePlayer(userID)
+	if !wasPlayer && !room.RemoveSpectator(userID) {
+		rm.mutex.Unlock()
+		return false
+	}
+
+	// Remove the room once its last player leaves; spectators alone don't
+	// keep it open
+	removed := room.GetPlayerCount() == 0 && (wasPlayer || room.GetSpectatorCount() == 0)
+	if removed {
+		delete(rm.rooms, roomID)
+		delete(rm.roomByCode, room.Code)
+	}
+	rm.mutex.Unlock()
+
+	if removed {
+		rm.deleteSnapshot(roomID)
+		logger.Info("removed empty room", logging.RoomID(room.ID), logging.RoomCode(room.Code))
+	}
+	return true
+}
+
+// ScheduleRemoval calls remove once a disconnected player has been gone for
+// the session grace period, unless CancelRemoval is called first. The seat
+// is held exactly as long as the player could resume their session.
+func (rm *RoomManager) ScheduleRemoval(roomID, userID string, remove func()) {
+	rm.scheduleRemoval(roomID, userID, rm.config.Session.ResumeGracePeriod, remove)
+}
+
+// RetryRemoval schedules a removal that came due but couldn't be carried
+// out to be tried again shortly. CancelRemoval still stops it.
+func (rm *RoomManager) RetryRemoval(roomID, userID string, remove func()) {
+	rm.scheduleRemoval(roomID, userID, removalRetryDelay, remove)
+}
+
+func (rm *RoomManager) scheduleRemoval(roomID, userID string, delay time.Duration, remove func()) {
+	key := removalKey(roomID, userID)
+
+	rm.removalsMutex.Lock()
+	defer rm.removalsMutex.Unlock()
+
+	if timer, exists := rm.removals[key]; exists {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		rm.removalsMutex.Lock()
+		current := rm.removals[key] == timer
+		if current {
+			delete(rm.removals, key)
+		}
+		rm.removalsMutex.Unlock()
+
+		// A newer schedule or a cancel superseded this one
+		if current {
+			remove()
+		}
+	})
+	rm.removals[key] = timer
+}
+
+// CancelRemoval stops a pending removal. Returns false if none was pending.
+func (rm *RoomManager) CancelRemoval(roomID, userID string) bool {
+	key := removalKey(roomID, userID)
+
+	rm.removalsMutex.Lock()
+	defer rm.removalsMutex.Unlock()
+
+	timer, exists := rm.removals[key]
+	if !exists {
+		return false
+	}
+	timer.Stop()
+	delete(rm.removals, key)
+	return true
+}
+
+func removalKey(roomID, userID string) string {
+	return roomID + "/" + userID
+}
+
+// GetAllRooms returns every room this node holds
+func (rm *RoomManager) GetAllRooms() []*models.Room {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	rooms := make([]*models.Room, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// FindPlayerRoom returns the room a player is seated in, if any
+func (rm *RoomManager) FindPlayerRoom(userID string) *models.Room {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	for _, room := range rm.rooms {
+		if _, exists := room.GetPlayer(userID); exists {
+			return room
 		}
-		return true
 	}
-	return false
+	return nil
+}
+
+// CloseRoom removes a room regardless of who is in it, cancelling any
+// pending removals of its players. Returns false if the room doesn't exist.
+func (rm *RoomManager) CloseRoom(roomID string) bool {
+	rm.mutex.Lock()
+	room := rm.rooms[roomID]
+	if room != nil {
+		delete(rm.rooms, roomID)
+		delete(rm.roomByCode, room.Code)
+	}
+	rm.mutex.Unlock()
+
+	if room == nil {
+		return false
+	}
+
+	rm.deleteSnapshot(roomID)
+
+	for _, userID := range room.GetPlayerIDs() {
+		rm.CancelRemoval(roomID, userID)
+	}
+	logger.Info("closed room", logging.RoomID(room.ID), logging.RoomCode(room.Code))
+	return true
+}
+
+// CountByState returns how many rooms are in each game state
+func (rm *RoomManager) CountByState() map[models.GameState]int {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	counts := map[models.GameState]int{
+		models.GameStateLobby:    0,
+		models.GameStateStarting: 0,
+		models.GameStatePlaying:  0,
+		models.GameStateEnded:    0,
+	}
+	for _, room := range rm.rooms {
+		counts[room.GetState()]++
+	}
+	return counts
 }
 
 // IsRoomFull checks if a room is full
@@ -132,15 +342,26 @@ func (rm *RoomManager) UpdateRoomActivity(roomID string) {
 	}
 }
 
-// Cleanup removes inactive rooms
+// Cleanup removes inactive rooms and, when persistence is on, snapshots
+// every room periodically
 func (rm *RoomManager) Cleanup() {
 	ticker := time.NewTicker(rm.config.Game.RoomCleanupInterval)
 	defer ticker.Stop()
 
+	// A nil channel never fires, leaving snapshots to state changes
+	var snapshots <-chan time.Time
+	if rm.store != nil {
+		snapshotTicker := time.NewTicker(rm.config.Storage.SnapshotInterval)
+		defer snapshotTicker.Stop()
+		snapshots = snapshotTicker.C
+	}
+
 	for {
 		select {
 		case <-ticker.C:
 			rm.cleanupInactiveRooms()
+		case <-snapshots:
+			rm.SaveAll()
 		case <-rm.cleanupStop:
 			return
 		}
@@ -150,18 +371,146 @@ func (rm *RoomManager) Cleanup() {
 // cleanupInactiveRooms removes rooms that have been inactive for too long
 func (rm *RoomManager) cleanupInactiveRooms() {
 	rm.mutex.Lock()
-	defer rm.mutex.Unlock()
-
+	var removed []*models.Room
 	for roomID, room := range rm.rooms {
 		if !room.IsActive(rm.config.Game.InactiveRoomTimeout) {
 			delete(rm.rooms, roomID)
 			delete(rm.roomByCode, room.Code)
-			log.Printf("Cleaned up inactive room %s (%s)", roomID, room.Code)
+			removed = append(removed, room)
 		}
 	}
+	rm.mutex.Unlock()
+
+	for _, room := range removed {
+		rm.deleteSnapshot(room.ID)
+		logger.Info("cleaned up inactive room", logging.RoomID(room.ID), logging.RoomCode(room.Code))
+	}
 }
 
 // StopCleanup stops the cleanup routine
 func (rm *RoomManager) StopCleanup() {
 	close(rm.cleanupStop)
-}
\ No newline at end of file
+}
+
+// Stop stops the cleanup routine and every pending player removal
+func (rm *RoomManager) Stop() {
+	rm.StopCleanup()
+
+	rm.removalsMutex.Lock()
+	defer rm.removalsMutex.Unlock()
+	for key, timer := range rm.removals {
+		timer.Stop()
+		delete(rm.removals, key)
+	}
+}
+
+// SaveRoom snapshots a room to the store, if persistence is on
+func (rm *RoomManager) SaveRoom(roomID string) {
+	if rm.store == nil {
+		return
+	}
+
+	// The snapshot is taken under the lock but written after it is
+	// released; the store ignores snapshots older than the room's removal
+	rm.mutex.RLock()
+	room, exists := rm.rooms[roomID]
+	var snapshot *models.RoomSnapshot
+	if exists {
+		snapshot = rm.snapshot(room)
+	}
+	rm.mutex.RUnlock()
+
+	if snapshot != nil {
+		rm.saveSnapshot(snapshot)
+	}
+}
+
+// SaveAll snapshots every room to the store, if persistence is on
+func (rm *RoomManager) SaveAll() {
+	if rm.store == nil {
+		return
+	}
+
+	rm.mutex.RLock()
+	snapshots := make([]*models.RoomSnapshot, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		snapshots = append(snapshots, rm.snapshot(room))
+	}
+	rm.mutex.RUnlock()
+
+	for _, snapshot := range snapshots {
+		rm.saveSnapshot(snapshot)
+	}
+}
+
+// RestoreRooms loads the rooms saved in the store. Rooms this node no longer
+// owns are dropped. Returns the restored rooms' snapshots.
+func (rm *RoomManager) RestoreRooms() ([]*models.RoomSnapshot, error) {
+	if rm.store == nil {
+		return nil, nil
+	}
+
+	snapshots, err := rm.store.LoadAll()
+
+	// Deferred first so it runs once rm.mutex is released
+	var dropped []string
+	defer func() {
+		for _, roomID := range dropped {
+			rm.deleteSnapshot(roomID)
+		}
+	}()
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	restored := make([]*models.RoomSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		room := models.RestoreRoom(snapshot)
+		if !rm.canUseKeys(room) || len(room.Players) == 0 {
+			logger.Warn("dropping room snapshot", logging.RoomID(room.ID), logging.RoomCode(room.Code))
+			dropped = append(dropped, room.ID)
+			continue
+		}
+
+		rm.rooms[room.ID] = room
+		rm.roomByCode[room.Code] = room
+		restored = append(restored, snapshot)
+		logger.Info("restored room", logging.RoomID(room.ID), logging.RoomCode(room.Code),
+			"state", room.State, "players", len(room.Players))
+	}
+	return restored, err
+}
+
+// snapshot captures a room's state for the store, with its players' resume
+// tokens. Caller must hold rm.mutex.
+func (rm *RoomManager) snapshot(room *models.Room) *models.RoomSnapshot {
+	snapshot := room.Snapshot()
+	if rm.resumeToken != nil {
+		snapshot.ResumeTokens = make(map[string]string, len(snapshot.Players))
+		for _, player := range snapshot.Players {
+			if token := rm.resumeToken(player.ID); token != "" {
+				snapshot.ResumeTokens[player.ID] = token
+			}
+		}
+	}
+	return snapshot
+}
+
+// saveSnapshot writes a room's snapshot to the store
+func (rm *RoomManager) saveSnapshot(snapshot *models.RoomSnapshot) {
+	if err := rm.store.Save(snapshot); err != nil {
+		logger.Error("saving room snapshot", logging.RoomID(snapshot.ID), logging.Err(err))
+	}
+}
+
+// deleteSnapshot removes a room's snapshot. Called after rm.mutex is
+// released, so file I/O doesn't hold up other rooms; the store ignores
+// snapshots taken before the deletion that are written after it.
+func (rm *RoomManager) deleteSnapshot(roomID string) {
+	if rm.store == nil {
+		return
+	}
+	if err := rm.store.Delete(roomID); err != nil {
+		logger.Error("deleting room snapshot", logging.RoomID(roomID), logging.Err(err))
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// RoomStore persists room snapshots so rooms survive a restart
+type RoomStore interface {
+	// Save writes a room's snapshot, replacing any older one
+	Save(snapshot *models.RoomSnapshot) error
+
+	// Delete removes a room's snapshot
+	Delete(roomID string) error
+
+	// LoadAll returns every saved snapshot
+	LoadAll() ([]*models.RoomSnapshot, error)
+}
+
+// FileRoomStore keeps each room's snapshot as a JSON file in a directory.
+// Files are replaced atomically, so a crash mid-write leaves the previous
+// snapshot in place.
+type FileRoomStore struct {
+	dir string
+
+	// When each room's saved snapshot was taken, so a slow writer can't
+	// replace a newer snapshot with an older one
+	savedAt map[string]time.Time
+
+	// When recently removed rooms were deleted, so a snapshot taken before
+	// the removal but written after it can't bring the room back
+	deletedAt map[string]time.Time
+
+	mutex sync.Mutex
+}
+
+// Suffix of snapshot files
+const roomSnapshotExt = ".json"
+
+// How long a removed room's snapshots keep being ignored. Snapshots are
+// written right after they are taken, so this only has to outlast a slow
+// write.
+const roomTombstoneTTL = time.Minute
+
+// NewFileRoomStore creates a store in dir, creating the directory if needed
+func NewFileRoomStore(dir string) (*FileRoomStore, error) {
+	// Snapshots hold resume tokens, so keep them private
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create room snapshot directory: %w", err)
+	}
+	return &FileRoomStore{
+		dir:       dir,
+		savedAt:   make(map[string]time.Time),
+		deletedAt: make(map[string]time.Time),
+	}, nil
+}
+
+// Save writes a room's snapshot
+func (s *FileRoomStore) Save(snapshot *models.RoomSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode room snapshot: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if snapshot.SavedAt.Before(s.savedAt[snapshot.ID]) {
+		return nil
+	}
+	if deletedAt, deleted := s.deletedAt[snapshot.ID]; deleted && !snapshot.SavedAt.After(deletedAt) {
+		return nil
+	}
+
+	if err := writeFileAtomic(s.path(snapshot.ID), data); err != nil {
+		return fmt.Errorf("failed to write room snapshot: %w", err)
+	}
+
+	s.savedAt[snapshot.ID] = snapshot.SavedAt
+	return nil
+}
+
+// Delete removes a room's snapshot. Deleting a room with no snapshot is not
+// an error.
+func (s *FileRoomStore) Delete(roomID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for id, deletedAt := range s.deletedAt {
+		if now.Sub(deletedAt) > roomTombstoneTTL {
+			delete(s.deletedAt, id)
+		}
+	}
+	s.deletedAt[roomID] = now
+
+	delete(s.savedAt, roomID)
+	if err := os.Remove(s.path(roomID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete room snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadAll reads every snapshot in the directory. Unreadable snapshots are
+// skipped and reported in the returned error alongside the rest.
+func (s *FileRoomStore) LoadAll() ([]*models.RoomSnapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read room snapshot directory: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var snapshots []*models.RoomSnapshot
+	var failed []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), roomSnapshotExt) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			failed = append(failed, entry.Name())
+			continue
+		}
+		snapshot := &models.RoomSnapshot{}
+		if err := json.Unmarshal(data, snapshot); err != nil || snapshot.ID == "" {
+			failed = append(failed, entry.Name())
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot)
+		s.savedAt[snapshot.ID] = snapshot.SavedAt
+	}
+
+	if len(failed) > 0 {
+		return snapshots, fmt.Errorf("failed to load room snapshots: %s", strings.Join(failed, ", "))
+	}
+	return snapshots, nil
+}
+
+func (s *FileRoomStore) path(roomID string) string {
+	return filepath.Join(s.dir, filepath.Base(roomID)+roomSnapshotExt)
+}
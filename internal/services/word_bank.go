@@ -3,9 +3,9 @@ package services
 import (
 	"encoding/json"
 	"io/ioutil"
-	"log"
 
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 )
 
 // WordBank manages word lists
@@ -56,7 +56,7 @@ func (wb *WordBank) LoadWords(filePath string) error {
 	wb.mediumWords = append(wb.mediumWords, words.Medium...)
 	wb.hardWords = append(wb.hardWords, words.Hard...)
 
-	log.Printf("Loaded %d easy, %d medium, %d hard words", len(wb.easyWords), len(wb.mediumWords), len(wb.hardWords))
+	logger.Info("loaded words", "file", filePath, "easy", len(wb.easyWords), "medium", len(wb.mediumWords), "hard", len(wb.hardWords))
 	return nil
 }
 
@@ -98,5 +98,5 @@ func (wb *WordBank) GetRandomWord(difficulty string) string {
 func (wb *WordBank) AddCustomWords(roomID string, words []string) {
 	// In a real implementation, store custom words per room
 	wb.easyWords = append(wb.easyWords, words...) // Add to easy for simplicity
-	log.Printf("Added %d custom words for room %s", len(words), roomID)
+	logger.Info("added custom words", logging.RoomID(roomID), "words", len(words))
 }
\ No newline at end of file
@@ -0,0 +1,287 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter is a count that only goes up
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// CounterVec is a family of counters told apart by label values
+type CounterVec struct {
+	metricName string
+	help       string
+	labels     []string
+
+	series map[string]*counterSeries
+	mutex  sync.RWMutex
+}
+
+type counterSeries struct {
+	values  []string
+	counter Counter
+}
+
+// NewCounter registers a counter without labels
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+// NewCounterVec registers a counter family with the given label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	vec := &CounterVec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		series:     make(map[string]*counterSeries),
+	}
+	r.register(vec)
+	return vec
+}
+
+// WithLabelValues returns the counter for the given label values, in the
+// order the labels were declared
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := seriesKey(values)
+
+	v.mutex.RLock()
+	series, exists := v.series[key]
+	v.mutex.RUnlock()
+	if exists {
+		return &series.counter
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if series, exists = v.series[key]; !exists {
+		series = &counterSeries{values: append([]string(nil), values...)}
+		v.series[key] = series
+	}
+	return &series.counter
+}
+
+func (v *CounterVec) name() string {
+	return v.metricName
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	writeHeader(w, v.metricName, v.help, "counter")
+
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	for _, key := range sortedKeys(v.series) {
+		series := v.series[key]
+		writeSample(w, v.metricName, v.labels, series.values, float64(series.counter.Value()))
+	}
+}
+
+// gaugeFunc is a gauge whose value is read when metrics are scraped
+type gaugeFunc struct {
+	metricName string
+	help       string
+	label      string
+	read       func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge that calls read on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, read func() float64) {
+	r.register(&gaugeFunc{
+		metricName: name,
+		help:       help,
+		read: func() map[string]float64 {
+			return map[string]float64{"": read()}
+		},
+	})
+}
+
+// NewGaugeVecFunc registers a gauge family with one label whose values are
+// read on every scrape, keyed by label value
+func (r *Registry) NewGaugeVecFunc(name, help, label string, read func() map[string]float64) {
+	r.register(&gaugeFunc{
+		metricName: name,
+		help:       help,
+		label:      label,
+		read:       read,
+	})
+}
+
+func (g *gaugeFunc) name() string {
+	return g.metricName
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	writeHeader(w, g.metricName, g.help, "gauge")
+
+	values := g.read()
+	for _, key := range sortedKeys(values) {
+		if g.label == "" {
+			writeSample(w, g.metricName, nil, nil, values[key])
+			continue
+		}
+		writeSample(w, g.metricName, []string{g.label}, []string{key}, values[key])
+	}
+}
+
+// DefaultLatencyBuckets suit request handling times, in seconds
+var DefaultLatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Histogram counts observations into buckets
+type Histogram struct {
+	bounds []float64
+	counts []uint64 // Per bucket, not cumulative; the last is +Inf
+	sum    float64
+	count  uint64
+	mutex  sync.Mutex
+}
+
+// Observe records one value
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.bounds, value)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// ObserveSince records the time elapsed since start, in seconds
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// HistogramVec is a family of histograms told apart by label values
+type HistogramVec struct {
+	metricName string
+	help       string
+	labels     []string
+	bounds     []float64
+
+	series map[string]*histogramSeries
+	mutex  sync.RWMutex
+}
+
+type histogramSeries struct {
+	values    []string
+	histogram *Histogram
+}
+
+// NewHistogramVec registers a histogram family with the given upper bucket
+// bounds, in increasing order, and label names
+func (r *Registry) NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	vec := &HistogramVec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		bounds:     bounds,
+		series:     make(map[string]*histogramSeries),
+	}
+	r.register(vec)
+	return vec
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := seriesKey(values)
+
+	v.mutex.RLock()
+	series, exists := v.series[key]
+	v.mutex.RUnlock()
+	if exists {
+		return series.histogram
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if series, exists = v.series[key]; !exists {
+		series = &histogramSeries{
+			values: append([]string(nil), values...),
+			histogram: &Histogram{
+				bounds: v.bounds,
+				counts: make([]uint64, len(v.bounds)+1),
+			},
+		}
+		v.series[key] = series
+	}
+	return series.histogram
+}
+
+func (v *HistogramVec) name() string {
+	return v.metricName
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	writeHeader(w, v.metricName, v.help, "histogram")
+
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	bucketLabels := append(append([]string(nil), v.labels...), "le")
+	for _, key := range sortedKeys(v.series) {
+		series := v.series[key]
+		h := series.histogram
+
+		h.mutex.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum, count := h.sum, h.count
+		h.mutex.Unlock()
+
+		var cumulative uint64
+		for i, bound := range append(append([]float64(nil), v.bounds...), math.Inf(1)) {
+			cumulative += counts[i]
+			le := "+Inf"
+			if !math.IsInf(bound, 1) {
+				le = formatValue(bound)
+			}
+			values := append(append([]string(nil), series.values...), le)
+			writeSample(w, v.metricName+"_bucket", bucketLabels, values, float64(cumulative))
+		}
+		writeSample(w, v.metricName+"_sum", v.labels, series.values, sum)
+		writeSample(w, v.metricName+"_count", v.labels, series.values, float64(count))
+	}
+}
+
+// seriesKey joins label values into a map key
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys returns a map's keys in order, for stable output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
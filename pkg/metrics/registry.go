@@ -0,0 +1,119 @@
+// Package metrics is a small metrics registry that serves the Prometheus
+// text exposition format. It covers the counters, gauges and histograms the
+// server needs without pulling in the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry the server's metrics are registered with
+var Default = NewRegistry()
+
+// collector is a metric that can write itself in the text format
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metrics and renders them for scraping
+type Registry struct {
+	collectors map[string]collector
+	mutex      sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]collector),
+	}
+}
+
+// register adds a metric. Names must be unique; registering one twice is a
+// programming error.
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.collectors[c.name()]; exists {
+		panic(fmt.Sprintf("metrics: %s registered twice", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write renders every metric in the Prometheus text format, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mutex.RUnlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(buffered)
+	}
+	return buffered.Flush()
+}
+
+// Handler serves the registry's metrics over HTTP
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// writeHeader writes a metric's HELP and TYPE lines
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// writeSample writes one sample line
+func writeSample(w io.Writer, name string, labels []string, values []string, value float64) {
+	io.WriteString(w, name)
+	if len(labels) > 0 {
+		io.WriteString(w, formatLabels(labels, values))
+	}
+	io.WriteString(w, " ")
+	io.WriteString(w, formatValue(value))
+	io.WriteString(w, "\n")
+}
+
+// formatLabels renders a label set, e.g. {type="draw_data"}
+func formatLabels(labels []string, values []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatValue renders a sample value the way Prometheus expects
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
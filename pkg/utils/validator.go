@@ -29,6 +29,14 @@ func ValidateUserName(name string) bool {
 	return true
 }
 
+// languageTag matches language tags such as "en" or "pt-BR"
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+// ValidateLanguage validates a language tag such as "en" or "pt-BR"
+func ValidateLanguage(language string) bool {
+	return languageTag.MatchString(language)
+}
+
 // SanitizeInput cleans user input
 func SanitizeInput(input string) string {
 	// Remove potentially dangerous characters
@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// EnvelopeKind identifies what a broadcaster envelope asks nodes to do
+type EnvelopeKind string
+
+const (
+	// Deliver to every local client
+	EnvelopeAll EnvelopeKind = "all"
+
+	// Deliver to local clients in a room
+	EnvelopeRoom EnvelopeKind = "room"
+
+	// Deliver to specific users connected to the receiving node
+	EnvelopeUsers EnvelopeKind = "users"
+
+	// A client message forwarded to the node that owns its room
+	EnvelopeForward EnvelopeKind = "forward"
+
+	// The owner of a room telling a user's node which room the user is in
+	EnvelopeRoomSet EnvelopeKind = "room_set"
+
+	// A user's node telling a room owner that the user disconnected
+	EnvelopeDisconnect EnvelopeKind = "disconnect"
+
+	// A user's node telling a room owner that the user resumed their session
+	EnvelopeResume EnvelopeKind = "resume"
+)
+
+// Envelope is the unit of traffic between nodes
+type Envelope struct {
+	Kind   EnvelopeKind `json:"kind"`
+	Origin string       `json:"origin"`           // Node that published the envelope
+	Target string       `json:"target,omitempty"` // Node to deliver to; empty means every node
+
+	RoomID        string   `json:"room_id,omitempty"`
+	UserIDs       []string `json:"user_ids,omitempty"`
+	ExcludeUserID string   `json:"exclude_user_id,omitempty"`
+
+	Message  []byte               `json:"message,omitempty"`  // JSON text
+	Binary   []byte               `json:"binary,omitempty"`   // Binary drawing frame
+	Commands []models.DrawCommand `json:"commands,omitempty"` // Drawing commands carried by Message/Binary
+
+	// Sender details for forwarded messages and resumes
+	User            *RemoteUser `json:"user,omitempty"`
+	Protocol        string      `json:"protocol,omitempty"`
+	ProtocolVersion int         `json:"protocol_version,omitempty"`
+	Capabilities    []string    `json:"capabilities,omitempty"`
+}
+
+// RemoteUser identifies the sender of a forwarded message
+type RemoteUser struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Avatar    string `json:"avatar"`
+	GuestUser bool   `json:"guest_user"`
+}
+
+// Broadcaster carries hub traffic between server nodes. Every envelope
+// addressed to a node, including the publishing node itself, is handed to
+// that node's subscribers.
+type Broadcaster interface {
+	// NodeID returns this node's ID
+	NodeID() string
+
+	// Nodes returns the IDs of every node in the cluster, including this one
+	Nodes() []string
+
+	// Publish sends an envelope to its target node, or to every node if it has none
+	Publish(envelope *Envelope) error
+
+	// Subscribe registers a handler for envelopes addressed to this node
+	Subscribe(handler func(*Envelope))
+
+	// Close stops the broadcaster
+	Close() error
+}
+
+// OwnerOf picks the node that owns a key using rendezvous hashing, so
+// every node agrees on the owner without coordination
+func OwnerOf(nodes []string, key string) string {
+	var owner string
+	var best uint64
+	for _, node := range nodes {
+		h := fnv.New64a()
+		h.Write([]byte(node))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := h.Sum64(); owner == "" || score > best {
+			owner, best = node, score
+		}
+	}
+	return owner
+}
+
+// LocalNodeID is the node ID used when running a single instance
+const LocalNodeID = "local"
+
+// LocalBroadcaster is the in-process broadcaster for a single node
+type LocalBroadcaster struct {
+	handlers []func(*Envelope)
+	mutex    sync.RWMutex
+}
+
+// NewLocalBroadcaster creates a new in-process broadcaster
+func NewLocalBroadcaster() *LocalBroadcaster {
+	return &LocalBroadcaster{}
+}
+
+// NodeID returns this node's ID
+func (b *LocalBroadcaster) NodeID() string {
+	return LocalNodeID
+}
+
+// Nodes returns the single local node
+func (b *LocalBroadcaster) Nodes() []string {
+	return []string{LocalNodeID}
+}
+
+// Publish hands the envelope straight to local subscribers
+func (b *LocalBroadcaster) Publish(envelope *Envelope) error {
+	envelope.Origin = LocalNodeID
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, handler := range b.handlers {
+		handler(envelope)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for published envelopes
+func (b *LocalBroadcaster) Subscribe(handler func(*Envelope)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Close is a no-op for the local broadcaster
+func (b *LocalBroadcaster) Close() error {
+	return nil
+}
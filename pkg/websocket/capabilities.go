@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"fmt"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// Protocol versions this server can speak. Clients that omit a version in
+// their connect message are treated as speaking MinProtocolVersion.
+const (
+	MinProtocolVersion     = 1
+	CurrentProtocolVersion = 1
+)
+
+// Capability is an optional protocol feature a client can ask for
+type Capability string
+
+const (
+	// Binary drawing frames; requires the binary subprotocol
+	CapabilityBinaryDrawing Capability = "binary_drawing"
+
+	// Compressed websocket messages; requires permessage-deflate
+	CapabilityCompression Capability = "compression"
+
+	// Joining rooms as a spectator
+	CapabilitySpectator Capability = "spectator"
+
+	// Resuming a dropped session with a resume token
+	CapabilitySessionResume Capability = "session_resume"
+)
+
+// ErrUnsupportedProtocolVersion is returned when a client asks for a
+// protocol version outside the supported range
+var ErrUnsupportedProtocolVersion = fmt.Errorf("protocol version must be between %d and %d", MinProtocolVersion, CurrentProtocolVersion)
+
+// supportsCapability reports whether the server can enable a capability
+// for this client's connection
+func (c *Client) supportsCapability(capability Capability) bool {
+	switch capability {
+	case CapabilityBinaryDrawing:
+		return c.UsesBinaryDrawing()
+	case CapabilityCompression:
+		return c.compression
+	case CapabilitySessionResume, CapabilitySpectator:
+		return true
+	}
+	return false
+}
+
+// Negotiate settles the protocol version and capabilities requested by the
+// client and returns the capabilities that were enabled
+func (c *Client) Negotiate(version int, requested []string) ([]Capability, error) {
+	if version == 0 {
+		version = MinProtocolVersion
+	}
+	if version < MinProtocolVersion || version > CurrentProtocolVersion {
+		return nil, ErrUnsupportedProtocolVersion
+	}
+
+	enabled := make([]Capability, 0, len(requested))
+	capabilities := make(map[Capability]bool, len(requested))
+	for _, name := range requested {
+		capability := Capability(name)
+		if capabilities[capability] || !c.supportsCapability(capability) {
+			continue
+		}
+		capabilities[capability] = true
+		enabled = append(enabled, capability)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.protocolVersion = version
+	c.capabilities = capabilities
+
+	return enabled, nil
+}
+
+// ProtocolVersion returns the negotiated protocol version (thread-safe)
+func (c *Client) ProtocolVersion() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.protocolVersion == 0 {
+		return MinProtocolVersion
+	}
+	return c.protocolVersion
+}
+
+// HasCapability reports whether a capability was negotiated (thread-safe)
+func (c *Client) HasCapability(capability Capability) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.capabilities[capability]
+}
+
+// capabilityNames returns the negotiated capabilities as strings (thread-safe)
+func (c *Client) capabilityNames() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	names := make([]string, 0, len(c.capabilities))
+	for capability := range c.capabilities {
+		names = append(names, string(capability))
+	}
+	return names
+}
+
+// NewConnectedMessage creates the acknowledgement for a connect message
+func NewConnectedMessage(version int, capabilities []Capability, user *models.PublicUser) (*Message, error) {
+	names := make([]string, len(capabilities))
+	for i, capability := range capabilities {
+		names[i] = string(capability)
+	}
+
+	return NewMessage(models.MessageTypeConnected, models.ConnectedData{
+		ProtocolVersion: version,
+		Capabilities:    names,
+		User:            user,
+	})
+}
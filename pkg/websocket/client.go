@@ -3,44 +3,19 @@ package websocket
 import (
 	"bytes"
 	"fmt"
-	"log"
-	"net/http"
 	"sync"
 	"time"
 
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 	"github.com/gorilla/websocket"
 )
 
-const (
-	// Time allowed to write a message to the peer
-	writeWait = 10 * time.Second
-
-	// Time allowed to read the next pong message from the peer
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait
-	pingPeriod = (pongWait * 9) / 10
-
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
-)
-
 var (
 	newline = []byte{'\n'}
 	space   = []byte{' '}
 )
 
-// WebSocket upgrader with reasonable defaults
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// In production, implement proper origin checking
-		return true
-	},
-}
-
 // Client represents a WebSocket client connection
 type Client struct {
 	// The websocket connection
@@ -49,17 +24,43 @@ type Client struct {
 	// Hub that manages this client
 	hub *Hub
 
-	// Buffered channel of outbound messages
-	send chan []byte
+	// Prioritized queue of outbound messages
+	send *outboundQueue
+
+	// Closed when the client disconnects; stops the write pump
+	done chan struct{}
 
 	// User information
 	user *models.User
 
+	// Resumable session; set before registration when resuming
+	session *Session
+
 	// Current room ID
 	roomID string
 
+	// Negotiated WebSocket subprotocol
+	protocol string
+
+	// Whether permessage-deflate was negotiated
+	compression bool
+
+	// Node the client is connected to, for proxies of clients on other nodes
+	remoteNode string
+
+	// Protocol version and capabilities negotiated in the connect message
+	protocolVersion int
+	capabilities    map[Capability]bool
+
+	// Close frame payload sent when the client is disconnected, if not the default
+	closeMessage []byte
+
+	// Closed when the write pump has sent the close frame and exited
+	writerDone chan struct{}
+
 	// Connection metadata
 	connectedAt time.Time
+	remoteAddr  string
 	
 	// Mutex for thread safety
 	mutex sync.RWMutex
@@ -73,13 +74,81 @@ func NewClient(hub *Hub, conn *websocket.Conn, user *models.User) *Client {
 	return &Client{
 		conn:        conn,
 		hub:         hub,
-		send:        make(chan []byte, 256),
+		send:        newOutboundQueue(hub.config.WebSocket.SendQueueSize, hub.config.WebSocket.CriticalDeliveryDeadline),
+		done:        make(chan struct{}),
+		writerDone:  make(chan struct{}),
+		user:        user,
+		protocol:    conn.Subprotocol(),
+		connectedAt: time.Now(),
+		isConnected: true,
+	}
+}
+
+
+// NewResumedClient creates a client that takes over an existing session
+func NewResumedClient(hub *Hub, conn *websocket.Conn, session *Session) *Client {
+	client := NewClient(hub, conn, session.User)
+	client.session = session
+	return client
+}
+
+// newRemoteClient creates a proxy for a client connected to another node.
+// It has no connection; everything sent to it is routed back to that node.
+func newRemoteClient(hub *Hub, node, protocol string, user *models.User) *Client {
+	return &Client{
+		hub:         hub,
+		send:        newOutboundQueue(hub.config.WebSocket.SendQueueSize, hub.config.WebSocket.CriticalDeliveryDeadline),
+		done:        make(chan struct{}),
 		user:        user,
+		protocol:    protocol,
+		remoteNode:  node,
 		connectedAt: time.Now(),
 		isConnected: true,
 	}
 }
 
+// updateRemote refreshes a proxy from the details in a forwarded message
+func (c *Client) updateRemote(envelope *Envelope) {
+	capabilities := make(map[Capability]bool, len(envelope.Capabilities))
+	for _, name := range envelope.Capabilities {
+		capabilities[Capability(name)] = true
+	}
+
+	c.mutex.Lock()
+	c.protocolVersion = envelope.ProtocolVersion
+	c.capabilities = capabilities
+	c.mutex.Unlock()
+
+	user := c.GetUser()
+	if remote := envelope.User; remote != nil {
+		user.Username = remote.Username
+		user.Avatar = remote.Avatar
+		user.GuestUser = remote.GuestUser
+	}
+	user.UpdateActivity()
+}
+
+// SetCompression records whether permessage-deflate was negotiated for the
+// connection. Must be called before the pumps start.
+func (c *Client) SetCompression(enabled bool) {
+	c.compression = enabled
+	if enabled {
+		c.conn.SetCompressionLevel(c.hub.config.WebSocket.CompressionLevel)
+	}
+}
+
+// SetRemoteAddr records the address the client connected from. Must be
+// called before the client is registered.
+func (c *Client) SetRemoteAddr(addr string) {
+	c.remoteAddr = addr
+}
+
+// RemoteAddr returns the address the client connected from, or "" for
+// clients connected to other nodes
+func (c *Client) RemoteAddr() string {
+	return c.remoteAddr
+}
+
 // GetUser returns the client's user (thread-safe)
 func (c *Client) GetUser() *models.User {
 	c.mutex.RLock()
@@ -115,6 +184,21 @@ func (c *Client) IsConnected() bool {
 	return c.isConnected
 }
 
+// UsesBinaryDrawing reports whether the client connected with the binary
+// subprotocol. Binary frames are only exchanged once the binary_drawing
+// capability has also been negotiated.
+func (c *Client) UsesBinaryDrawing() bool {
+	return c.protocol == SubprotocolBinary
+}
+
+// readLimit returns the maximum inbound message size for the client's protocol
+func (c *Client) readLimit() int64 {
+	if c.UsesBinaryDrawing() {
+		return c.hub.config.WebSocket.MaxBinaryMessageSize
+	}
+	return c.hub.config.WebSocket.MaxMessageSize
+}
+
 // ReadPump pumps messages from the websocket connection to the hub
 func (c *Client) ReadPump() {
 	defer func() {
@@ -122,7 +206,9 @@ func (c *Client) ReadPump() {
 	}()
 
 	// Set connection parameters
-	c.conn.SetReadLimit(maxMessageSize)
+	pongWait := c.hub.config.WebSocket.PongWait
+	limiter := newInboundLimiter(c.hub.config.RateLimit.Messages)
+	c.conn.SetReadLimit(c.readLimit())
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -130,34 +216,51 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, messageBytes, err := c.conn.ReadMessage()
+		frameType, messageBytes, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				logger.Warn("unexpected websocket close", logging.UserID(c.getUserID()), logging.Err(err))
 			}
 			break
 		}
 
-		// Clean up the message
-		messageBytes = bytes.TrimSpace(bytes.Replace(messageBytes, newline, space, -1))
-
-		// Parse the message
-		message, err := ParseMessage(messageBytes)
+		message, err := c.parseFrame(frameType, messageBytes)
 		if err != nil {
-			log.Printf("Error parsing message: %v", err)
+			logger.Debug("parsing message", logging.UserID(c.getUserID()), logging.Err(err))
 			c.sendError("Invalid message format", "INVALID_MESSAGE")
 			continue
 		}
+		messagesIn.WithLabelValues(typeLabel(message.Type)).Inc()
 
-		// Set message metadata
-		if c.user != nil {
-			message.UserID = c.user.ID
+		// Enforce rate limits before the message costs anything else
+		switch limiter.check(message.Type, time.Now()) {
+		case rateAllow:
+		case rateWarn:
+			c.sendRateLimited(message, "You are sending messages too fast; some were dropped")
+			continue
+		case rateMute:
+			logger.Info("muting client for sending messages too fast", logging.UserID(c.getUserID()), logging.MessageType(message.Type))
+			c.sendRateLimited(message, fmt.Sprintf("You are muted for %s for sending messages too fast", c.hub.config.RateLimit.Messages.MuteDuration))
+			continue
+		case rateDisconnect:
+			logger.Warn("disconnecting client for flooding", logging.UserID(c.getUserID()), logging.MessageType(message.Type))
+			forcedDisconnects.WithLabelValues(disconnectRateLimited).Inc()
+			c.setCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded")
+			return
+		default:
+			continue
+		}
+
+		// Set message metadata; signing in can swap the user at any time
+		user := c.GetUser()
+		if user != nil {
+			message.UserID = user.ID
 		}
 		message.RoomID = c.GetRoomID()
 
 		// Update user activity
-		if c.user != nil {
-			c.user.UpdateActivity()
+		if user != nil {
+			user.UpdateActivity()
 		}
 
 		// Send to hub for processing
@@ -167,52 +270,123 @@ func (c *Client) ReadPump() {
 			Client:  c,
 		}:
 		default:
-			log.Println("Hub message handler is full, dropping message")
+			logger.Warn("hub message handler is full, dropping message", logging.UserID(message.UserID), logging.MessageType(message.Type))
+			messagesDropped.WithLabelValues(dropMessageHandler).Inc()
 		}
 	}
 }
 
+// parseFrame decodes a text frame as a JSON message, or a binary frame as a
+// batch of drawing commands for clients on the binary protocol
+func (c *Client) parseFrame(frameType int, data []byte) (*Message, error) {
+	if frameType == websocket.BinaryMessage {
+		if !c.UsesBinaryDrawing() {
+			return nil, fmt.Errorf("binary frame on %q protocol", c.protocol)
+		}
+		if !c.HasCapability(CapabilityBinaryDrawing) {
+			return nil, fmt.Errorf("binary frame without the %q capability", CapabilityBinaryDrawing)
+		}
+		return NewDrawBatchFromFrame(data)
+	}
+
+	// Clean up the message
+	data = bytes.TrimSpace(bytes.Replace(data, newline, space, -1))
+	return ParseMessage(data)
+}
+
 // WritePump pumps messages from the hub to the websocket connection
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	writeWait := c.hub.config.WebSocket.WriteWait
+	ticker := time.NewTicker(c.hub.config.WebSocket.PingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		close(c.writerDone)
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.send.notify:
+			frames := c.send.drain()
+			if len(frames) == 0 {
+				continue
+			}
+
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			if err := c.writeFrames(frames); err != nil {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
-			w.Write(message)
 
-			// Add queued messages to the current websocket message
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.send)
+		case <-c.done:
+			// The client was disconnected. Send what is already queued, then
+			// the close frame.
+			if frames := c.send.drain(); len(frames) > 0 {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.writeFrames(frames); err != nil {
+					return
+				}
 			}
+			c.mutex.RLock()
+			closeMessage := c.closeMessage
+			c.mutex.RUnlock()
+			c.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
 
-			if err := w.Close(); err != nil {
-				return
+// writeFrames writes queued frames in order. Consecutive text messages are
+// joined with newlines into one websocket message; binary frames and frames
+// marked alone are sent on their own.
+func (c *Client) writeFrames(frames []outboundFrame) error {
+	for i := 0; i < len(frames); {
+		if frames[i].binary || frames[i].alone {
+			messageType := websocket.TextMessage
+			if frames[i].binary {
+				messageType = websocket.BinaryMessage
 			}
-
-		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
+			c.compressAbove(len(frames[i].data))
+			if err := c.conn.WriteMessage(messageType, frames[i].data); err != nil {
+				return err
 			}
+			i++
+			continue
+		}
+
+		size := len(frames[i].data)
+		for j := i + 1; j < len(frames) && frames[j].batchable(); j++ {
+			size += len(newline) + len(frames[j].data)
+		}
+		c.compressAbove(size)
+
+		w, err := c.conn.NextWriter(websocket.TextMessage)
+		if err != nil {
+			return err
 		}
+		w.Write(frames[i].data)
+		for i++; i < len(frames) && frames[i].batchable(); i++ {
+			w.Write(newline)
+			w.Write(frames[i].data)
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressAbove compresses the next message only if the client asked for
+// compression and the message reaches the configured threshold; small
+// messages aren't worth the CPU
+func (c *Client) compressAbove(size int) {
+	if c.compression {
+		c.conn.EnableWriteCompression(c.HasCapability(CapabilityCompression) && size >= c.hub.config.WebSocket.CompressionThreshold)
 	}
 }
 
@@ -227,32 +401,77 @@ func (c *Client) SendMessage(message *Message) error {
 		return err
 	}
 
-	select {
-	case c.send <- messageBytes:
+	return c.enqueue(newTextFrame(messageBytes))
+}
+
+// SendJSON sends an already encoded JSON message to this client
+func (c *Client) SendJSON(data []byte) error {
+	return c.enqueue(newTextFrame(data))
+}
+
+// enqueue queues a frame for the write pump, disconnecting the client if
+// its critical messages are stuck past the delivery deadline
+func (c *Client) enqueue(frame outboundFrame) error {
+	if !c.IsConnected() {
+		return ErrClientDisconnected
+	}
+
+	// Proxies hand the message back to the client's own node
+	if c.remoteNode != "" {
+		c.hub.publish(&Envelope{
+			Kind:    EnvelopeUsers,
+			Target:  c.remoteNode,
+			UserIDs: []string{c.getUserID()},
+			Message: frame.data,
+		})
 		return nil
-	default:
-		// Channel is full, client is slow or disconnected
+	}
+
+	if !c.send.push(frame) {
+		logger.Warn("client could not receive critical messages in time, disconnecting", logging.UserID(c.getUserID()))
+		forcedDisconnects.WithLabelValues(disconnectSlowConsumer).Inc()
 		c.disconnect()
 		return ErrClientDisconnected
 	}
+	messagesOut.WithLabelValues(frame.typeLabel()).Inc()
+	return nil
 }
 
 // SendError sends an error message to the client
 func (c *Client) sendError(message, code string) {
 	errorMsg, err := NewErrorMessage(message, code)
 	if err != nil {
-		log.Printf("Error creating error message: %v", err)
+		logger.Error("creating error message", logging.Err(err))
 		return
 	}
 
 	c.SendMessage(errorMsg)
 }
 
+// sendRateLimited tells the client a message was rejected by rate limiting
+func (c *Client) sendRateLimited(request *Message, text string) {
+	errorMsg, err := NewErrorMessage(text, "RATE_LIMITED")
+	if err != nil {
+		logger.Error("creating error message", logging.Err(err))
+		return
+	}
+
+	c.SendMessage(errorMsg.InReplyTo(request))
+}
+
+// setCloseMessage sets the close code and reason sent when the client is
+// disconnected
+func (c *Client) setCloseMessage(code int, text string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closeMessage = websocket.FormatCloseMessage(code, text)
+}
+
 // SendSystemMessage sends a system chat message to the client
 func (c *Client) SendSystemMessage(message string) {
 	systemMsg, err := NewChatMessage("System", message, true)
 	if err != nil {
-		log.Printf("Error creating system message: %v", err)
+		logger.Error("creating system message", logging.Err(err))
 		return
 	}
 
@@ -261,28 +480,49 @@ func (c *Client) SendSystemMessage(message string) {
 
 // disconnect handles client disconnection
 func (c *Client) disconnect() {
+	if !c.close() {
+		return
+	}
+
+	// A proxy's real connection lives on another node
+	if c.remoteNode != "" {
+		c.hub.publish(&Envelope{
+			Kind:    EnvelopeDisconnect,
+			Target:  c.remoteNode,
+			UserIDs: []string{c.getUserID()},
+		})
+	}
+
+	// Notify hub about disconnection without blocking, since this may be
+	// called from inside the hub loop
+	c.hub.UnregisterClient(c)
+
+	logger.Info("client disconnected", logging.UserID(c.getUserID()), logging.RoomID(c.GetRoomID()))
+}
+
+// close marks the client as disconnected and releases the connection without
+// notifying the hub. Returns false if the client was already closed.
+func (c *Client) close() bool {
 	c.mutex.Lock()
 	wasConnected := c.isConnected
 	c.isConnected = false
 	c.mutex.Unlock()
 
-	if wasConnected {
-		// Update user status
-		if c.user != nil {
-			c.user.SetConnected(false)
-		}
-
-		// Notify hub about disconnection
-		c.hub.unregister <- c
+	if !wasConnected {
+		return false
+	}
 
-		// Close the connection
-		c.conn.Close()
+	// Update user status
+	if user := c.GetUser(); user != nil {
+		user.SetConnected(false)
+	}
 
-		// Close the send channel
-		close(c.send)
+	// Stop the write pump, which sends a close frame and closes the
+	// connection. The send channel is left open so late senders never
+	// panic on a closed channel.
+	close(c.done)
 
-		log.Printf("Client disconnected: %s", c.getUserDisplayName())
-	}
+	return true
 }
 
 // Disconnect safely disconnects the client
@@ -290,49 +530,77 @@ func (c *Client) Disconnect() {
 	c.disconnect()
 }
 
+// closeGoingAway closes the client with a going-away close frame as the
+// server stops, without notifying the hub
+func (c *Client) closeGoingAway() {
+	c.setCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	c.close()
+}
+
+// Kick disconnects the client with a policy violation close frame carrying
+// reason. Proxies ask the client's own node to disconnect it.
+func (c *Client) Kick(reason string) {
+	// Close frame payloads are limited to 125 bytes, 2 of them the code
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	logger.Info("kicking client", logging.UserID(c.getUserID()), "reason", reason)
+	forcedDisconnects.WithLabelValues(disconnectKicked).Inc()
+	c.setCloseMessage(websocket.ClosePolicyViolation, reason)
+	c.disconnect()
+}
+
 // getUserDisplayName returns a display name for logging
 func (c *Client) getUserDisplayName() string {
-	if c.user != nil {
-		return c.user.Username + " (" + c.user.ID + ")"
+	if user := c.GetUser(); user != nil {
+		return user.Username + " (" + user.ID + ")"
 	}
 	return "unknown"
 }
 
 // GetConnectionInfo returns information about the connection
 func (c *Client) GetConnectionInfo() ConnectionInfo {
+	userID, username := c.getUserID(), c.getUserName()
+
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
 	return ConnectionInfo{
-		UserID:      c.getUserID(),
-		Username:    c.getUserName(),
+		UserID:      userID,
+		Username:    username,
 		RoomID:      c.roomID,
-		ConnectedAt: c.connectedAt,
-		IsConnected: c.isConnected,
+		RemoteAddr:      c.remoteAddr,
+		RemoteNode:      c.remoteNode,
+		ConnectedAt:     c.connectedAt,
+		IsConnected:     c.isConnected,
+		DroppedMessages: c.send.droppedCount(),
 	}
 }
 
 func (c *Client) getUserID() string {
-	if c.user != nil {
-		return c.user.ID
+	if user := c.GetUser(); user != nil {
+		return user.ID
 	}
 	return ""
 }
 
 func (c *Client) getUserName() string {
-	if c.user != nil {
-		return c.user.Username
+	if user := c.GetUser(); user != nil {
+		return user.Username
 	}
 	return ""
 }
 
 // ConnectionInfo represents information about a client connection
 type ConnectionInfo struct {
-	UserID      string    `json:"user_id"`
-	Username    string    `json:"username"`
-	RoomID      string    `json:"room_id"`
-	ConnectedAt time.Time `json:"connected_at"`
-	IsConnected bool      `json:"is_connected"`
+	UserID          string    `json:"user_id"`
+	Username        string    `json:"username"`
+	RoomID          string    `json:"room_id"`
+	RemoteAddr      string    `json:"remote_addr,omitempty"`
+	RemoteNode      string    `json:"remote_node,omitempty"` // Set for clients connected to other nodes
+	ConnectedAt     time.Time `json:"connected_at"`
+	IsConnected     bool      `json:"is_connected"`
+	DroppedMessages int64     `json:"dropped_messages"`
 }
 
 // MessageWithClient pairs a message with its originating client
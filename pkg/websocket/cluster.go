@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+var clusterLogger = logging.For("cluster")
+
+// Messages that are always handled by the node the client is connected to,
+// even when the client is in a room owned by another node
+var nodeLocalMessageTypes = map[models.MessageType]bool{
+	models.MessageTypeConnect:              true,
+	models.MessageTypeRegister:             true,
+	models.MessageTypeGetProfile:           true,
+	models.MessageTypeCreateRoom:           true,
+	models.MessageTypeJoinRoom:             true,
+	models.MessageTypeListPublicRooms:      true,
+	models.MessageTypeGetGlobalLeaderboard: true,
+	models.MessageTypeQuickPlay:            true,
+	models.MessageTypeCancelQuickPlay:      true,
+}
+
+// SetBroadcaster replaces the broadcaster that carries hub traffic between
+// nodes. Must be called before Run.
+func (h *Hub) SetBroadcaster(broadcaster Broadcaster) {
+	h.broadcaster = broadcaster
+	broadcaster.Subscribe(h.receive)
+}
+
+// NodeID returns the ID of the node this hub runs on
+func (h *Hub) NodeID() string {
+	return h.broadcaster.NodeID()
+}
+
+// IsLocalKey reports whether this node owns a room ID or room code
+func (h *Hub) IsLocalKey(key string) bool {
+	return OwnerOf(h.broadcaster.Nodes(), key) == h.broadcaster.NodeID()
+}
+
+// ForwardToOwner sends a client message to the node that owns key. Returns
+// false without forwarding if this node is the owner.
+func (h *Hub) ForwardToOwner(key string, client *Client, message *Message) bool {
+	owner := OwnerOf(h.broadcaster.Nodes(), key)
+	if owner == h.broadcaster.NodeID() || client.remoteNode != "" {
+		// Proxies are never forwarded a second time
+		return false
+	}
+	h.forward(owner, client, message)
+	return true
+}
+
+// publish hands an envelope to the broadcaster
+func (h *Hub) publish(envelope *Envelope) {
+	if err := h.broadcaster.Publish(envelope); err != nil {
+		clusterLogger.Error("publishing envelope", "kind", envelope.Kind, logging.Err(err))
+	}
+}
+
+// receive handles an envelope addressed to this node. Deliveries go through
+// the hub loop; forwarded messages go straight to the room actors.
+func (h *Hub) receive(envelope *Envelope) {
+	switch envelope.Kind {
+	case EnvelopeAll:
+		select {
+		case h.broadcast <- envelope.Message:
+		default:
+			clusterLogger.Warn("broadcast channel is full, dropping message")
+			messagesDropped.WithLabelValues(dropBroadcast).Inc()
+		}
+
+	case EnvelopeRoom:
+		roomMsg := &RoomMessage{
+			RoomID:        envelope.RoomID,
+			Message:       envelope.Message,
+			Binary:        envelope.Binary,
+			excludeUserID: envelope.ExcludeUserID,
+			commands:      envelope.Commands,
+		}
+		select {
+		case h.roomBroadcast <- roomMsg:
+		default:
+			clusterLogger.Warn("room broadcast channel is full, dropping message", logging.RoomID(envelope.RoomID))
+			messagesDropped.WithLabelValues(dropRoomBroadcast).Inc()
+		}
+
+	case EnvelopeUsers:
+		for _, userID := range envelope.UserIDs {
+			select {
+			case h.clientMessage <- &ClientMessage{UserID: userID, Message: envelope.Message}:
+			default:
+				clusterLogger.Warn("client message channel is full, dropping message", logging.UserID(userID))
+				messagesDropped.WithLabelValues(dropClientMessage).Inc()
+			}
+		}
+
+	case EnvelopeForward:
+		h.receiveForward(envelope)
+
+	case EnvelopeRoomSet:
+		h.receiveRoomSet(envelope)
+
+	case EnvelopeDisconnect:
+		h.receiveDisconnect(envelope)
+
+	case EnvelopeResume:
+		h.receiveResume(envelope)
+
+	default:
+		clusterLogger.Warn("unknown envelope kind", "kind", envelope.Kind, "node", envelope.Origin)
+	}
+}
+
+// forward sends a client's message to the node that owns its room
+func (h *Hub) forward(owner string, client *Client, message *Message) {
+	data, err := json.Marshal(message.Message)
+	if err != nil {
+		clusterLogger.Error("encoding forwarded message", logging.Err(err))
+		return
+	}
+
+	if client.GetUser() == nil {
+		return
+	}
+
+	envelope := h.remoteEnvelope(EnvelopeForward, owner, client)
+	envelope.Message = data
+	envelope.Commands = message.commands
+	h.publish(envelope)
+}
+
+// remoteEnvelope builds an envelope that carries a local client's details
+// to another node
+func (h *Hub) remoteEnvelope(kind EnvelopeKind, target string, client *Client) *Envelope {
+	user := client.GetUser()
+	return &Envelope{
+		Kind:   kind,
+		Target: target,
+		User: &RemoteUser{
+			ID:        user.ID,
+			Username:  user.Username,
+			Avatar:    user.Avatar,
+			GuestUser: user.GuestUser,
+		},
+		Protocol:        client.protocol,
+		ProtocolVersion: client.ProtocolVersion(),
+		Capabilities:    client.capabilityNames(),
+	}
+}
+
+// receiveForward runs a message forwarded from another node on behalf of a
+// remote client. The client is represented here by a proxy whose sends are
+// routed back to its node.
+func (h *Hub) receiveForward(envelope *Envelope) {
+	if envelope.User == nil || envelope.User.ID == "" {
+		return
+	}
+
+	msg := &models.Message{}
+	if err := json.Unmarshal(envelope.Message, msg); err != nil {
+		clusterLogger.Warn("decoding forwarded message", "node", envelope.Origin, logging.Err(err))
+		return
+	}
+	msg.UserID = envelope.User.ID
+
+	h.mutex.Lock()
+	proxy := h.attachRemote(envelope, msg.RoomID)
+	h.mutex.Unlock()
+
+	h.processMessage(&MessageWithClient{
+		Message: &Message{Message: msg, commands: envelope.Commands},
+		Client:  proxy,
+	})
+}
+
+// receiveResume puts a remote client back in its room after it resumed
+// its session on its own node
+func (h *Hub) receiveResume(envelope *Envelope) {
+	if envelope.User == nil || envelope.User.ID == "" || envelope.RoomID == "" {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.attachRemote(envelope, envelope.RoomID)
+}
+
+// attachRemote returns the proxy for a remote client and makes sure it is
+// in roomID. The client's node knows which room it is in, so a proxy that
+// was released or recreated after a reconnect is caught up here. Caller
+// must hold h.mutex.
+func (h *Hub) attachRemote(envelope *Envelope, roomID string) *Client {
+	proxy := h.remoteClient(envelope)
+	if roomID != "" && proxy.GetRoomID() != roomID && h.IsLocalKey(roomID) {
+		h.addClientToRoom(proxy, roomID)
+		h.playerReconnected(proxy, roomID)
+	}
+	return proxy
+}
+
+// remoteClient returns the proxy for a forwarded message's sender, creating
+// or reviving it as needed. Caller must hold h.mutex.
+func (h *Hub) remoteClient(envelope *Envelope) *Client {
+	remote := envelope.User
+	proxy, exists := h.remoteClients[remote.ID]
+	if exists && proxy.IsConnected() && proxy.remoteNode == envelope.Origin && proxy.protocol == envelope.Protocol {
+		proxy.updateRemote(envelope)
+		return proxy
+	}
+
+	// Keep the same user object so room state stays attached to it
+	var user *models.User
+	if exists {
+		// Moving the user to the new proxy is not a disconnect, so skip
+		// the lifecycle hooks
+		if roomID := proxy.GetRoomID(); roomID != "" {
+			h.removeClientFromRoom(proxy, roomID)
+		}
+		user = proxy.GetUser()
+	} else {
+		user = models.NewUser(remote.Username, remote.Avatar)
+		user.ID = remote.ID
+	}
+
+	proxy = newRemoteClient(h, envelope.Origin, envelope.Protocol, user)
+	proxy.updateRemote(envelope)
+	user.SetConnected(true)
+	h.remoteClients[remote.ID] = proxy
+
+	clusterLogger.Info("remote client attached", logging.UserID(proxy.getUserID()), "node", envelope.Origin)
+	return proxy
+}
+
+// receiveRoomSet updates which room a local client is in after the room's
+// owner moved it
+func (h *Hub) receiveRoomSet(envelope *Envelope) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, userID := range envelope.UserIDs {
+		client, exists := h.clientsByUserID[userID]
+		if !exists {
+			// Keep the seat for a resume
+			h.sessions.SetRoom(userID, envelope.RoomID)
+			continue
+		}
+		if envelope.RoomID == "" {
+			if roomID := client.GetRoomID(); roomID != "" {
+				h.removeClientFromRoom(client, roomID)
+			}
+			continue
+		}
+		h.addClientToRoom(client, envelope.RoomID)
+	}
+}
+
+// receiveDisconnect handles a client dropping on another node. The room
+// owner releases the client's proxy; the client's own node disconnects it.
+func (h *Hub) receiveDisconnect(envelope *Envelope) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, userID := range envelope.UserIDs {
+		if proxy, exists := h.remoteClients[userID]; exists && proxy.remoteNode == envelope.Origin {
+			h.removeClient(proxy)
+			proxy.close()
+			continue
+		}
+		if client, exists := h.clientsByUserID[userID]; exists {
+			h.removeClient(client)
+			client.close()
+		}
+	}
+}
+
+// notifyRoomOwner tells the owner of a remote room that a local client in
+// it has gone. Caller must hold h.mutex.
+func (h *Hub) notifyRoomOwner(client *Client, roomID string) {
+	if roomID == "" || client.remoteNode != "" || h.IsLocalKey(roomID) {
+		return
+	}
+	h.publish(&Envelope{
+		Kind:    EnvelopeDisconnect,
+		Target:  OwnerOf(h.broadcaster.Nodes(), roomID),
+		RoomID:  roomID,
+		UserIDs: []string{client.getUserID()},
+	})
+}
+
+// notifyClientNode tells a remote client's node which room the client is in
+func (h *Hub) notifyClientNode(client *Client, roomID string) {
+	if client.remoteNode == "" {
+		return
+	}
+	h.publish(&Envelope{
+		Kind:    EnvelopeRoomSet,
+		Target:  client.remoteNode,
+		RoomID:  roomID,
+		UserIDs: []string{client.getUserID()},
+	})
+}
+
+// cleanupRemoteClients forgets proxies whose clients have been gone longer
+// than they could still resume. Caller must hold h.mutex.
+func (h *Hub) cleanupRemoteClients() {
+	for userID, proxy := range h.remoteClients {
+		user := proxy.GetUser()
+		if !proxy.IsConnected() && user.IsInactive(h.sessions.GracePeriod()+time.Minute) {
+			delete(h.remoteClients, userID)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// Subprotocols negotiated through Sec-WebSocket-Protocol. Clients that ask
+// for neither get the JSON protocol.
+const (
+	SubprotocolJSON   = "doodledash.v1.json"
+	SubprotocolBinary = "doodledash.v1.binary"
+)
+
+// Subprotocols returns the supported subprotocols in order of preference
+func Subprotocols() []string {
+	return []string{SubprotocolBinary, SubprotocolJSON}
+}
+
+// Binary drawing frame layout:
+//
+//	version  byte (drawFrameVersion)
+//	kind     byte (drawFrameStrokes)
+//	user ID  uvarint length + bytes (empty from clients, set by the server)
+//	count    uvarint number of commands
+//	commands op byte, then per op:
+//	           start: uvarint color length + color, uvarint size*100, dx, dy
+//	           move, end: dx, dy
+//	           clear: nothing
+//
+// dx and dy are zigzag varints in hundredths of a unit, relative to the
+// previous point in the frame (the first point is relative to 0,0).
+const (
+	drawFrameVersion = 1
+	drawFrameStrokes = 1
+
+	drawOpStart = 0
+	drawOpMove  = 1
+	drawOpEnd   = 2
+	drawOpClear = 3
+
+	// Fixed-point scale for coordinates and brush sizes
+	drawScale = 100
+
+	// Upper bounds that keep a hostile frame from allocating too much
+	maxDrawCommandsPerFrame = 4096
+	maxDrawStringLength     = 64
+)
+
+// Errors returned when decoding binary drawing frames
+var (
+	ErrInvalidDrawFrame = errors.New("invalid drawing frame")
+	ErrUnknownDrawOp    = errors.New("unknown drawing operation")
+)
+
+// ValidDrawCommand reports whether a drawing command has a known type and
+// fits in a binary stroke frame
+func ValidDrawCommand(cmd models.DrawCommand) bool {
+	_, ok := drawOpFromType(cmd.Type)
+	return ok && len(cmd.Color) <= maxDrawStringLength
+}
+
+// EncodeDrawFrame packs drawing commands into a binary stroke frame.
+// Commands of unknown types are left out.
+func EncodeDrawFrame(userID string, commands []models.DrawCommand) []byte {
+	count := 0
+	for _, cmd := range commands {
+		if _, ok := drawOpFromType(cmd.Type); ok {
+			count++
+		}
+	}
+
+	buf := make([]byte, 0, 16+len(userID)+count*5)
+	buf = append(buf, drawFrameVersion, drawFrameStrokes)
+	buf = appendString(buf, userID)
+	buf = binary.AppendUvarint(buf, uint64(count))
+
+	var lastX, lastY int64
+	for _, cmd := range commands {
+		op, ok := drawOpFromType(cmd.Type)
+		if !ok {
+			continue
+		}
+		buf = append(buf, op)
+		if op == drawOpClear {
+			continue
+		}
+		if op == drawOpStart {
+			buf = appendString(buf, cmd.Color)
+			buf = binary.AppendUvarint(buf, uint64(toFixed(math.Max(cmd.Size, 0))))
+		}
+		x, y := toFixed(cmd.X), toFixed(cmd.Y)
+		buf = binary.AppendVarint(buf, x-lastX)
+		buf = binary.AppendVarint(buf, y-lastY)
+		lastX, lastY = x, y
+	}
+
+	return buf
+}
+
+// DecodeDrawFrame unpacks a binary stroke frame into drawing commands
+func DecodeDrawFrame(data []byte) (string, []models.DrawCommand, error) {
+	r := &frameReader{data: data}
+
+	if r.byte() != drawFrameVersion || r.byte() != drawFrameStrokes {
+		return "", nil, ErrInvalidDrawFrame
+	}
+	userID := r.string()
+	count := r.uvarint()
+	if r.err != nil || count > maxDrawCommandsPerFrame {
+		return "", nil, ErrInvalidDrawFrame
+	}
+
+	commands := make([]models.DrawCommand, 0, count)
+	var lastX, lastY int64
+	for i := uint64(0); i < count; i++ {
+		op := r.byte()
+		cmd := models.DrawCommand{}
+		switch op {
+		case drawOpStart:
+			cmd.Type = "start"
+			cmd.Color = r.string()
+			cmd.Size = fromFixed(int64(r.uvarint()))
+		case drawOpMove:
+			cmd.Type = "move"
+		case drawOpEnd:
+			cmd.Type = "end"
+		case drawOpClear:
+			cmd.Type = "clear"
+		default:
+			if r.err == nil {
+				return "", nil, ErrUnknownDrawOp
+			}
+		}
+		if op != drawOpClear {
+			lastX += r.varint()
+			lastY += r.varint()
+			cmd.X, cmd.Y = fromFixed(lastX), fromFixed(lastY)
+		}
+		if r.err != nil {
+			return "", nil, ErrInvalidDrawFrame
+		}
+		commands = append(commands, cmd)
+	}
+
+	return userID, commands, nil
+}
+
+func drawOpFromType(cmdType string) (byte, bool) {
+	switch cmdType {
+	case "start":
+		return drawOpStart, true
+	case "move":
+		return drawOpMove, true
+	case "end":
+		return drawOpEnd, true
+	case "clear":
+		return drawOpClear, true
+	}
+	return 0, false
+}
+
+func toFixed(v float64) int64 {
+	return int64(math.Round(v * drawScale))
+}
+
+func fromFixed(v int64) float64 {
+	return float64(v) / drawScale
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// frameReader reads frame fields, remembering the first error
+type frameReader struct {
+	data []byte
+	err  error
+}
+
+func (r *frameReader) byte() byte {
+	if r.err != nil || len(r.data) == 0 {
+		r.err = ErrInvalidDrawFrame
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *frameReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = ErrInvalidDrawFrame
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *frameReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = ErrInvalidDrawFrame
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *frameReader) string() string {
+	length := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if length > maxDrawStringLength || length > uint64(len(r.data)) {
+		r.err = ErrInvalidDrawFrame
+		return ""
+	}
+	s := string(r.data[:length])
+	r.data = r.data[length:]
+	return s
+}
@@ -0,0 +1,136 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+func TestDrawFrameRoundTrip(t *testing.T) {
+	stroke := []models.DrawCommand{
+		{Type: "start", X: 10.5, Y: 20.25, Color: "#ff0000", Size: 4},
+		{Type: "move", X: 11, Y: 19.75},
+		{Type: "move", X: -3.5, Y: 0},
+		{Type: "end", X: -3.5, Y: 0},
+	}
+
+	tests := []struct {
+		name     string
+		userID   string
+		commands []models.DrawCommand
+		want     []models.DrawCommand
+	}{
+		{"empty", "", nil, []models.DrawCommand{}},
+		{"clear", "user_1", []models.DrawCommand{{Type: "clear"}}, []models.DrawCommand{{Type: "clear"}}},
+		{"stroke", "user_1", stroke, stroke},
+		{
+			"stroke after clear",
+			"user_1",
+			append([]models.DrawCommand{{Type: "clear"}}, stroke...),
+			append([]models.DrawCommand{{Type: "clear"}}, stroke...),
+		},
+		{
+			"unknown types skipped",
+			"user_1",
+			[]models.DrawCommand{{Type: "fill"}, stroke[0], {Type: ""}, stroke[3]},
+			[]models.DrawCommand{stroke[0], stroke[3]},
+		},
+		{
+			"longest color",
+			"user_1",
+			[]models.DrawCommand{{Type: "start", Color: strings.Repeat("c", maxDrawStringLength), Size: 1}},
+			[]models.DrawCommand{{Type: "start", Color: strings.Repeat("c", maxDrawStringLength), Size: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		userID, commands, err := DecodeDrawFrame(EncodeDrawFrame(tt.userID, tt.commands))
+		if err != nil {
+			t.Errorf("%s: DecodeDrawFrame error = %v", tt.name, err)
+			continue
+		}
+		if userID != tt.userID {
+			t.Errorf("%s: user ID = %q, want %q", tt.name, userID, tt.userID)
+		}
+		if !reflect.DeepEqual(commands, tt.want) {
+			t.Errorf("%s: commands = %+v, want %+v", tt.name, commands, tt.want)
+		}
+	}
+}
+
+// frame builds a stroke frame header followed by the given bytes
+func frame(rest ...byte) []byte {
+	return append([]byte{drawFrameVersion, drawFrameStrokes}, rest...)
+}
+
+func TestDecodeDrawFrameMalformed(t *testing.T) {
+	oversizedCount := binary.AppendUvarint(frame(0), maxDrawCommandsPerFrame+1)
+	hugeCount := binary.AppendUvarint(frame(0), 1<<62)
+	oversizedColor := binary.AppendUvarint(frame(0, 1, drawOpStart), maxDrawStringLength+1)
+	oversizedColor = append(oversizedColor, strings.Repeat("c", maxDrawStringLength+1)...)
+	oversizedUserID := binary.AppendUvarint(frame(), maxDrawStringLength+1)
+	oversizedUserID = append(oversizedUserID, strings.Repeat("u", maxDrawStringLength+1)...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"empty", nil, ErrInvalidDrawFrame},
+		{"version only", []byte{drawFrameVersion}, ErrInvalidDrawFrame},
+		{"wrong version", []byte{2, drawFrameStrokes, 0, 0}, ErrInvalidDrawFrame},
+		{"wrong kind", []byte{drawFrameVersion, 2, 0, 0}, ErrInvalidDrawFrame},
+		{"missing user ID", frame(), ErrInvalidDrawFrame},
+		{"truncated user ID length", frame(0x80), ErrInvalidDrawFrame},
+		{"user ID past end", frame(5, 'a', 'b'), ErrInvalidDrawFrame},
+		{"oversized user ID", oversizedUserID, ErrInvalidDrawFrame},
+		{"missing count", frame(0), ErrInvalidDrawFrame},
+		{"truncated count", frame(0, 0xff, 0xff), ErrInvalidDrawFrame},
+		{"overflowing count", frame(0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01), ErrInvalidDrawFrame},
+		{"oversized count", oversizedCount, ErrInvalidDrawFrame},
+		{"huge count", hugeCount, ErrInvalidDrawFrame},
+		{"fewer commands than count", frame(0, 2, drawOpClear), ErrInvalidDrawFrame},
+		{"unknown op", frame(0, 1, 9), ErrUnknownDrawOp},
+		{"truncated color length", frame(0, 1, drawOpStart, 0x80), ErrInvalidDrawFrame},
+		{"oversized color", oversizedColor, ErrInvalidDrawFrame},
+		{"missing size", frame(0, 1, drawOpStart, 1, 'c'), ErrInvalidDrawFrame},
+		{"missing coordinates", frame(0, 1, drawOpMove), ErrInvalidDrawFrame},
+		{"truncated x", frame(0, 1, drawOpMove, 0x80), ErrInvalidDrawFrame},
+		{"truncated y", frame(0, 1, drawOpMove, 2, 0x80), ErrInvalidDrawFrame},
+	}
+
+	for _, tt := range tests {
+		_, commands, err := DecodeDrawFrame(tt.data)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: DecodeDrawFrame error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if commands != nil {
+			t.Errorf("%s: commands = %+v, want nil", tt.name, commands)
+		}
+	}
+}
+
+func TestValidDrawCommand(t *testing.T) {
+	tests := []struct {
+		cmd  models.DrawCommand
+		want bool
+	}{
+		{models.DrawCommand{Type: "start", Color: "#000000"}, true},
+		{models.DrawCommand{Type: "start", Color: strings.Repeat("c", maxDrawStringLength)}, true},
+		{models.DrawCommand{Type: "start", Color: strings.Repeat("c", maxDrawStringLength+1)}, false},
+		{models.DrawCommand{Type: "move"}, true},
+		{models.DrawCommand{Type: "end"}, true},
+		{models.DrawCommand{Type: "clear"}, true},
+		{models.DrawCommand{Type: "fill"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidDrawCommand(tt.cmd); got != tt.want {
+			t.Errorf("ValidDrawCommand(%q, %d byte color) = %v, want %v", tt.cmd.Type, len(tt.cmd.Color), got, tt.want)
+		}
+	}
+}
@@ -1,11 +1,20 @@
 package websocket
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 )
 
+var logger = logging.For("hub")
+
 // Hub maintains the set of active clients and broadcasts messages to the clients
 type Hub struct {
 	// Registered clients
@@ -42,17 +51,55 @@ type Hub struct {
 	mutex sync.RWMutex
 
 	// Shutdown channel
-	shutdown chan struct{}
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+
+	// Closed when Run has returned
+	stopped chan struct{}
+
+	// Set once the server starts shutting down; no new connections are accepted
+	draining atomic.Bool
 
 	// Message processor function (injected dependency)
 	ProcessMessage func(*MessageWithClient)
+
+	// Per-room actors that serialize message processing, keyed by room ID
+	actors      map[string]*roomActor
+	actorsMutex sync.Mutex
+
+	// Resumable sessions for reconnecting clients
+	sessions *SessionManager
+
+	// Replies to recent requests, for idempotent retries
+	replies *replyCache
+
+	// Carries broadcasts and forwarded messages between nodes
+	broadcaster Broadcaster
+
+	// Proxies for clients connected to other nodes, keyed by user ID
+	remoteClients map[string]*Client
+
+	// Read-only observers of room broadcasts, keyed by room ID
+	observers map[string]map[*RoomObserver]bool
+
+	// Called as players in this node's rooms disconnect and reconnect
+	lifecycle LifecycleHooks
+
+	config *config.Config
 }
 
 // RoomMessage represents a message to be sent to a specific room
 type RoomMessage struct {
 	RoomID  string
 	Message []byte
+	Binary  []byte  // Optional binary frame for clients on the binary protocol
 	Exclude *Client // Optional client to exclude from broadcast
+
+	// User excluded from the broadcast on every node
+	excludeUserID string
+
+	// Drawing commands carried by a draw broadcast, used to prioritize it
+	commands []models.DrawCommand
 }
 
 // ClientMessage represents a message to be sent to a specific client
@@ -75,7 +122,8 @@ type HubStats struct {
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
-	return &Hub{
+	cfg := config.GetConfig()
+	h := &Hub{
 		clients:         make(map[*Client]bool),
 		clientsByUserID: make(map[string]*Client),
 		clientsByRoom:   make(map[string]map[*Client]bool),
@@ -86,11 +134,20 @@ func NewHub() *Hub {
 		roomBroadcast:   make(chan *RoomMessage, 500),
 		clientMessage:   make(chan *ClientMessage, 500),
 		shutdown:        make(chan struct{}),
+		stopped:         make(chan struct{}),
+		actors:          make(map[string]*roomActor),
+		sessions:        NewSessionManager(cfg.Session.ResumeGracePeriod, cfg.Session.MaxReplayMessages),
+		replies:         newReplyCache(),
+		remoteClients:   make(map[string]*Client),
+		observers:       make(map[string]map[*RoomObserver]bool),
+		config:          cfg,
 		stats: &HubStats{
 			ClientsByRoom: make(map[string]int),
 			StartTime:     time.Now(),
 		},
 	}
+	h.SetBroadcaster(NewLocalBroadcaster())
+	return h
 }
 
 // SetMessageProcessor sets the function to process incoming messages
@@ -100,7 +157,8 @@ func (h *Hub) SetMessageProcessor(processor func(*MessageWithClient)) {
 
 // Run starts the hub and handles all incoming requests
 func (h *Hub) Run() {
-	log.Println("WebSocket Hub starting...")
+	logger.Info("hub starting")
+	defer close(h.stopped)
 	
 	// Start cleanup routine
 	go h.cleanupRoutine()
@@ -126,7 +184,8 @@ func (h *Hub) Run() {
 			h.sendToClient(clientMsg)
 
 		case <-h.shutdown:
-			log.Println("WebSocket Hub shutting down...")
+			logger.Info("hub shutting down")
+			h.flushBroadcasts()
 			h.shutdownAllClients()
 			return
 		}
@@ -138,56 +197,129 @@ func (h *Hub) RegisterClient(client *Client) {
 	select {
 	case h.register <- client:
 	default:
-		log.Println("Register channel is full, dropping client registration")
+		logger.Warn("register channel is full, dropping client registration", logging.UserID(client.getUserID()))
+		forcedDisconnects.WithLabelValues(disconnectRegisterFull).Inc()
 		client.Disconnect()
 	}
 }
 
-// UnregisterClient unregisters a client from the hub
+// UnregisterClient unregisters a client from the hub. It never blocks the
+// caller, so it is safe to call from inside the hub loop.
 func (h *Hub) UnregisterClient(client *Client) {
 	select {
 	case h.unregister <- client:
 	default:
-		log.Println("Unregister channel is full")
+		// Hand off to a goroutine rather than blocking until Run drains the channel
+		go func() {
+			select {
+			case h.unregister <- client:
+			case <-h.shutdown:
+			}
+		}()
 	}
 }
 
-// BroadcastToAll sends a message to all connected clients
+// ResumeToken returns the current resume token of a user's session on this
+// node, or an empty string if they have none
+func (h *Hub) ResumeToken(userID string) string {
+	return h.sessions.Token(userID)
+}
+
+// RestoreSession gives a player restored from a room snapshot their session
+// back, so they can resume with the token they held before the restart
+func (h *Hub) RestoreSession(user *models.User, token, roomID string) {
+	h.sessions.Restore(user, token, roomID)
+}
+
+// ResumeSession looks up a resumable session by its resume token.
+// Returns nil if the token is unknown or expired.
+func (h *Hub) ResumeSession(token string) *Session {
+	return h.sessions.Resume(token)
+}
+
+// BroadcastToAll sends a message to all connected clients on every node
 func (h *Hub) BroadcastToAll(message []byte) {
+	h.publish(&Envelope{
+		Kind:    EnvelopeAll,
+		Message: message,
+	})
+}
+
+// BroadcastToNode sends a message to the clients connected to this node only
+func (h *Hub) BroadcastToNode(message []byte) {
 	select {
 	case h.broadcast <- message:
 	default:
-		log.Println("Broadcast channel is full, dropping message")
+		logger.Warn("broadcast channel is full, dropping message")
+		messagesDropped.WithLabelValues(dropBroadcast).Inc()
 	}
 }
 
-// BroadcastToRoom sends a message to all clients in a specific room
+// BroadcastToRoom sends a message to all clients in a specific room,
+// whichever node they are connected to
 func (h *Hub) BroadcastToRoom(roomID string, message []byte, exclude *Client) {
-	roomMsg := &RoomMessage{
-		RoomID:  roomID,
-		Message: message,
-		Exclude: exclude,
-	}
+	h.publish(&Envelope{
+		Kind:          EnvelopeRoom,
+		RoomID:        roomID,
+		Message:       message,
+		ExcludeUserID: excludedUserID(exclude),
+	})
+}
 
-	select {
-	case h.roomBroadcast <- roomMsg:
-	default:
-		log.Println("Room broadcast channel is full, dropping message")
+// BroadcastDrawToRoom sends drawing commands to a room. Clients on the
+// binary protocol get one packed frame; everyone else gets a draw_data JSON
+// message per command.
+func (h *Hub) BroadcastDrawToRoom(roomID, userID string, commands []models.DrawCommand, exclude *Client) {
+	var jsonData []byte
+	for _, cmd := range commands {
+		drawMsg, err := NewDrawDataMessage(DrawDataMessage{
+			Type:   cmd.Type,
+			X:      cmd.X,
+			Y:      cmd.Y,
+			Color:  cmd.Color,
+			Size:   cmd.Size,
+			UserID: userID,
+		})
+		if err != nil {
+			logger.Error("creating draw data message", logging.Err(err))
+			return
+		}
+		data, err := drawMsg.ToJSON()
+		if err != nil {
+			logger.Error("encoding draw data message", logging.Err(err))
+			return
+		}
+		if jsonData != nil {
+			jsonData = append(jsonData, newline...)
+		}
+		jsonData = append(jsonData, data...)
 	}
+
+	h.publish(&Envelope{
+		Kind:          EnvelopeRoom,
+		RoomID:        roomID,
+		Message:       jsonData,
+		Binary:        EncodeDrawFrame(userID, commands),
+		Commands:      commands,
+		ExcludeUserID: excludedUserID(exclude),
+	})
 }
 
-// SendToClient sends a message to a specific client by user ID
+// SendToClient sends a message to a specific client by user ID, on
+// whichever node it is connected to
 func (h *Hub) SendToClient(userID string, message []byte) {
-	clientMsg := &ClientMessage{
-		UserID:  userID,
+	h.publish(&Envelope{
+		Kind:    EnvelopeUsers,
+		UserIDs: []string{userID},
 		Message: message,
-	}
+	})
+}
 
-	select {
-	case h.clientMessage <- clientMsg:
-	default:
-		log.Println("Client message channel is full, dropping message")
+func excludedUserID(exclude *Client) string {
+	if exclude == nil {
+		return ""
 	}
+	return exclude.getUserID()
 }
 
 // GetRoomClients returns all clients in a specific room
@@ -216,6 +348,9 @@ func (h *Hub) GetClientByUserID(userID string) (*Client, bool) {
 	defer h.mutex.RUnlock()
 
 	client, exists := h.clientsByUserID[userID]
+	if !exists {
+		client, exists = h.remoteClients[userID]
+	}
 	return client, exists && client.IsConnected()
 }
 
@@ -226,6 +361,22 @@ func (h *Hub) GetConnectedClients() int {
 	return len(h.clients)
 }
 
+// GetConnections returns information about this node's connected clients,
+// oldest first
+func (h *Hub) GetConnections() []ConnectionInfo {
+	h.mutex.RLock()
+	connections := make([]ConnectionInfo, 0, len(h.clients))
+	for client := range h.clients {
+		connections = append(connections, client.GetConnectionInfo())
+	}
+	h.mutex.RUnlock()
+
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ConnectedAt.Before(connections[j].ConnectedAt)
+	})
+	return connections
+}
+
 // GetStats returns current hub statistics
 func (h *Hub) GetStats() *HubStats {
 	h.stats.mutex.Lock()
@@ -256,9 +407,48 @@ func (h *Hub) GetStats() *HubStats {
 	}
 }
 
-// Shutdown gracefully shuts down the hub
-func (h *Hub) Shutdown() {
-	close(h.shutdown)
+// Drain stops the hub taking new connections ahead of a shutdown
+func (h *Hub) Drain() {
+	h.draining.Store(true)
+}
+
+// Draining reports whether the hub has stopped taking new connections
+func (h *Hub) Draining() bool {
+	return h.draining.Load()
+}
+
+// Shutdown stops the hub and its room actors and closes every client
+// connection with a going-away close frame. It waits until the close frames
+// are written or ctx is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.Drain()
+	h.shutdownOnce.Do(func() {
+		close(h.shutdown)
+	})
+
+	select {
+	case <-h.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h.mutex.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		if client.remoteNode == "" {
+			clients = append(clients, client)
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range clients {
+		select {
+		case <-client.writerDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
 }
 
 // Internal methods
@@ -270,47 +460,180 @@ func (h *Hub) registerClient(client *Client) {
 	// Add to main clients map
 	h.clients[client] = true
 
-	// Add to user ID lookup
+	h.indexByUserID(client)
+	h.attachSession(client)
+
+	logger.Info("client registered", logging.UserID(client.getUserID()), "clients", len(h.clients))
+}
+
+// indexByUserID adds a client to the user ID lookup. If its user already
+// has a connection, the old one is disconnected. Caller must hold h.mutex.
+func (h *Hub) indexByUserID(client *Client) {
+	user := client.GetUser()
+	if user == nil {
+		return
+	}
+
+	// We already hold the hub lock, so remove the old connection directly
+	// instead of going through the unregister channel. The new client is
+	// listed first so the old one's removal isn't treated as the player
+	// leaving.
+	oldClient, exists := h.clientsByUserID[user.ID]
+	h.clientsByUserID[user.ID] = client
+	if exists && oldClient != client {
+		logger.Info("user reconnecting, disconnecting old connection", logging.UserID(user.ID))
+		forcedDisconnects.WithLabelValues(disconnectReplaced).Inc()
+		h.removeClient(oldClient)
+		oldClient.close()
+	}
+}
+
+// SwitchUser moves a connected client that is not in a room over to
+// another user, e.g. when it signs in to an account. The client's old
+// session ends; the user's own session is resumed if they have one, so a
+// player seated in a room gets their seat back. The client is sent a new
+// session message either way.
+func (h *Hub) SwitchUser(client *Client, user *models.User) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, registered := h.clients[client]; !registered {
+		return
+	}
+	oldUser := client.GetUser()
+	if h.clientsByUserID[oldUser.ID] == client {
+		delete(h.clientsByUserID, oldUser.ID)
+	}
+	h.sessions.Remove(oldUser.ID)
+
+	client.session = nil
+	if session := h.sessions.Resume(h.sessions.Token(user.ID)); session != nil {
+		client.session = session
+		user = session.User
+	}
+	client.SetUser(user)
+
+	h.indexByUserID(client)
+	h.attachSession(client)
+
+	logger.Info("client switched user", logging.UserID(user.ID), "previous_user_id", oldUser.ID)
+}
+
+// attachSession binds a newly registered client to its session. A resumed
+// client gets its room seat back and the messages it missed; everyone else
+// gets a fresh session. Caller must hold h.mutex.
+func (h *Hub) attachSession(client *Client) {
 	user := client.GetUser()
-	if user != nil {
-		// If user already has a connection, disconnect the old one
-		if oldClient, exists := h.clientsByUserID[user.ID]; exists {
-			log.Printf("User %s reconnecting, disconnecting old connection", user.Username)
-			oldClient.Disconnect()
+	if user == nil {
+		return
+	}
+
+	var missed [][]byte
+	var truncated bool
+	session := client.session
+	resumed := session != nil
+	if resumed {
+		missed, truncated = h.sessions.Attach(session)
+		if session.RoomID != "" {
+			h.addClientToRoom(client, session.RoomID)
 		}
-		h.clientsByUserID[user.ID] = client
+	} else {
+		session = h.sessions.Create(user)
+		client.session = session
+	}
+	user.SetConnected(true)
+	if resumed && session.RoomID != "" {
+		h.playerReconnected(client, session.RoomID)
+	}
+
+	sessionMsg, err := NewMessage(models.MessageTypeSession, models.SessionData{
+		UserID:          user.ID,
+		ResumeToken:     session.Token,
+		GracePeriod:     int(h.sessions.GracePeriod().Seconds()),
+		Resumed:         resumed,
+		RoomID:          client.GetRoomID(),
+		ReplayTruncated: truncated,
+	})
+	if err != nil {
+		logger.Error("creating session message", logging.Err(err))
+		return
+	}
+	sessionData, err := sessionMsg.ToJSON()
+	if err != nil {
+		logger.Error("encoding session message", logging.Err(err))
+		return
 	}
+	h.deliver(client, sessionData)
 
-	log.Printf("Client registered: %s (Total: %d)", client.getUserDisplayName(), len(h.clients))
+	// Replay what the client missed while it was away
+	for _, message := range missed {
+		h.deliver(client, message)
+	}
+
+	if resumed {
+		logger.Info("client resumed session", logging.UserID(client.getUserID()), logging.RoomID(client.GetRoomID()), "missed_messages", len(missed))
+	}
 }
 
 func (h *Hub) unregisterClient(client *Client) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
+	h.removeClient(client)
+}
+
+// removeClient drops a client from every index. Caller must hold h.mutex.
+func (h *Hub) removeClient(client *Client) {
+	if client.remoteNode != "" {
+		h.removeRemoteClient(client)
+		return
+	}
 
 	// Remove from main clients map
 	if _, ok := h.clients[client]; ok {
 		delete(h.clients, client)
 
-		// Remove from user ID lookup
+		// Remove from user ID lookup and hold the session open for a resume
+		roomID := client.GetRoomID()
 		user := client.GetUser()
+		replaced := true
 		if user != nil {
 			if h.clientsByUserID[user.ID] == client {
 				delete(h.clientsByUserID, user.ID)
+				replaced = false
 			}
+			h.sessions.MarkDisconnected(user.ID, roomID)
 		}
 
-		// Remove from room
-		roomID := client.GetRoomID()
+		// Remove from room. A client replaced by a newer connection for the
+		// same user hands its seat over rather than leaving.
 		if roomID != "" {
 			h.removeClientFromRoom(client, roomID)
+			if !replaced {
+				h.notifyRoomOwner(client, roomID)
+				h.playerDisconnected(roomID, user)
+			}
 		}
 
-		log.Printf("Client unregistered: %s (Total: %d)", client.getUserDisplayName(), len(h.clients))
+		logger.Info("client unregistered", logging.UserID(client.getUserID()), "clients", len(h.clients))
 	}
 }
 
+// removeRemoteClient takes a proxy out of its room. The proxy itself is
+// kept so the user's state survives a reconnect. Caller must hold h.mutex.
+func (h *Hub) removeRemoteClient(client *Client) {
+	if roomID := client.GetRoomID(); roomID != "" {
+		h.removeClientFromRoom(client, roomID)
+		h.playerDisconnected(roomID, client.GetUser())
+	}
+	logger.Info("remote client released", logging.UserID(client.getUserID()), "node", client.remoteNode)
+}
+
 func (h *Hub) addClientToRoom(client *Client, roomID string) {
+	// Leave the previous room first
+	if oldRoomID := client.GetRoomID(); oldRoomID != "" && oldRoomID != roomID {
+		h.removeClientFromRoom(client, oldRoomID)
+	}
+
 	// Add client to room
 	if h.clientsByRoom[roomID] == nil {
 		h.clientsByRoom[roomID] = make(map[*Client]bool)
@@ -320,7 +643,7 @@ func (h *Hub) addClientToRoom(client *Client, roomID string) {
 	// Set client's room
 	client.SetRoomID(roomID)
 
-	log.Printf("Client %s joined room %s", client.getUserDisplayName(), roomID)
+	logger.Info("client joined room", logging.UserID(client.getUserID()), logging.RoomID(roomID))
 }
 
 func (h *Hub) removeClientFromRoom(client *Client, roomID string) {
@@ -330,14 +653,14 @@ func (h *Hub) removeClientFromRoom(client *Client, roomID string) {
 		// If room is empty, remove it
 		if len(roomClients) == 0 {
 			delete(h.clientsByRoom, roomID)
-			log.Printf("Room %s removed (empty)", roomID)
+			logger.Debug("no clients left in room", logging.RoomID(roomID))
 		}
 	}
 
 	// Clear client's room
 	client.SetRoomID("")
 
-	log.Printf("Client %s left room %s", client.getUserDisplayName(), roomID)
+	logger.Info("client left room", logging.UserID(client.getUserID()), logging.RoomID(roomID))
 }
 
 func (h *Hub) handleMessage(messageWithClient *MessageWithClient) {
@@ -346,11 +669,36 @@ func (h *Hub) handleMessage(messageWithClient *MessageWithClient) {
 	h.stats.MessagesHandled++
 	h.stats.mutex.Unlock()
 
-	// Process the message using the injected processor
-	if h.ProcessMessage != nil {
+	// Messages for a room owned by another node are processed there
+	message := messageWithClient.Message
+	if message.RoomID != "" && !nodeLocalMessageTypes[message.Type] &&
+		h.ForwardToOwner(message.RoomID, messageWithClient.Client, message) {
+		return
+	}
+
+	h.processMessage(messageWithClient)
+}
+
+// processMessage runs a message on this node
+func (h *Hub) processMessage(messageWithClient *MessageWithClient) {
+	if h.ProcessMessage == nil {
+		logger.Error("no message processor set, dropping message")
+		return
+	}
+
+	// Route to the room's actor; the processor runs on that actor's goroutine
+	message := messageWithClient.Message
+	roomID := message.RoomID
+	latency := handlerDuration.WithLabelValues(typeLabel(message.Type))
+	logger.LogAttrs(context.Background(), slog.LevelDebug, "processing message",
+		logging.UserID(message.UserID), logging.RoomID(roomID),
+		logging.MessageType(message.Type), logging.RequestID(message.RequestID))
+	if !h.dispatch(roomID, func() {
+		start := time.Now()
 		h.ProcessMessage(messageWithClient)
-	} else {
-		log.Println("No message processor set, dropping message")
+		latency.ObserveSince(start)
+	}) {
+		logger.Warn("room mailbox is full, dropping message", logging.RoomID(roomID), logging.MessageType(message.Type))
 	}
 }
 
@@ -358,15 +706,9 @@ func (h *Hub) broadcastToAll(message []byte) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
+	frame := newTextFrame(message)
 	for client := range h.clients {
-		if client.IsConnected() {
-			select {
-			case client.send <- message:
-			default:
-				// Client's send channel is full, disconnect them
-				go client.Disconnect()
-			}
-		}
+		h.deliverFrame(client, frame)
 	}
 }
 
@@ -374,25 +716,43 @@ func (h *Hub) broadcastToRoom(roomMsg *RoomMessage) {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
+	// Hold the message for disconnected players who may still resume
+	h.sessions.BufferForRoom(roomMsg.RoomID, roomMsg.Message)
+	h.notifyObservers(roomMsg.RoomID, roomMsg.Message)
+
 	roomClients, exists := h.clientsByRoom[roomMsg.RoomID]
 	if !exists {
 		return
 	}
 
+	// Classify once rather than per client
+	var textFrame, binaryFrame outboundFrame
+	if roomMsg.commands != nil {
+		textFrame = newDrawFrame(roomMsg.Message, false, roomMsg.commands)
+		binaryFrame = newDrawFrame(roomMsg.Binary, true, roomMsg.commands)
+	} else {
+		textFrame = newTextFrame(roomMsg.Message)
+	}
+
 	for client := range roomClients {
+		// Clients on other nodes are reached through their own node
+		if client.remoteNode != "" {
+			continue
+		}
+
 		// Skip excluded client
 		if roomMsg.Exclude != nil && client == roomMsg.Exclude {
 			continue
 		}
+		if roomMsg.excludeUserID != "" && client.getUserID() == roomMsg.excludeUserID {
+			continue
+		}
 
-		if client.IsConnected() {
-			select {
-			case client.send <- roomMsg.Message:
-			default:
-				// Client's send channel is full, disconnect them
-				go client.Disconnect()
-			}
+		if roomMsg.Binary != nil && client.HasCapability(CapabilityBinaryDrawing) {
+			h.deliverFrame(client, binaryFrame)
+			continue
 		}
+		h.deliverFrame(client, textFrame)
 	}
 }
 
@@ -402,29 +762,56 @@ func (h *Hub) sendToClient(clientMsg *ClientMessage) {
 	h.mutex.RUnlock()
 
 	if exists && client.IsConnected() {
-		select {
-		case client.send <- clientMsg.Message:
-		default:
-			// Client's send channel is full, disconnect them
-			go client.Disconnect()
-		}
+		h.deliver(client, clientMsg.Message)
+		return
 	}
+
+	// Hold the message in case the user resumes their session
+	h.sessions.BufferForUser(clientMsg.UserID, clientMsg.Message)
+}
+
+// deliver queues a text message on a client's outbound queue
+func (h *Hub) deliver(client *Client, message []byte) {
+	h.deliverFrame(client, newTextFrame(message))
+}
+
+// deliverFrame queues a frame for a client. Disconnecting a stuck client
+// does not block, so this is safe inside the hub loop.
+func (h *Hub) deliverFrame(client *Client, frame outboundFrame) {
+	client.enqueue(frame)
 }
 
 func (h *Hub) cleanupRoutine() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
+	// Sweep sessions often enough that expiry lands close to the grace period
+	sessionInterval := h.sessions.GracePeriod() / 4
+	if sessionInterval < time.Second {
+		sessionInterval = time.Second
+	}
+	sessionTicker := time.NewTicker(sessionInterval)
+	defer sessionTicker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
 			h.cleanupDisconnectedClients()
+		case <-sessionTicker.C:
+			h.expireSessions()
 		case <-h.shutdown:
 			return
 		}
 	}
 }
 
+func (h *Hub) expireSessions() {
+	expired := h.sessions.ExpireStale()
+	for _, session := range expired {
+		logger.Info("session expired", logging.UserID(session.User.ID))
+	}
+}
+
 func (h *Hub) cleanupDisconnectedClients() {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -440,21 +827,29 @@ func (h *Hub) cleanupDisconnectedClients() {
 
 	// Remove disconnected clients
 	for _, client := range disconnectedClients {
-		delete(h.clients, client)
-
-		user := client.GetUser()
-		if user != nil && h.clientsByUserID[user.ID] == client {
-			delete(h.clientsByUserID, user.ID)
-		}
-
-		roomID := client.GetRoomID()
-		if roomID != "" {
-			h.removeClientFromRoom(client, roomID)
-		}
+		h.removeClient(client)
 	}
+	h.cleanupRemoteClients()
 
 	if len(disconnectedClients) > 0 {
-		log.Printf("Cleaned up %d disconnected clients", len(disconnectedClients))
+		logger.Info("cleaned up disconnected clients", "count", len(disconnectedClients))
+	}
+}
+
+// flushBroadcasts delivers broadcasts already queued when the hub shuts
+// down, such as the last round results
+func (h *Hub) flushBroadcasts() {
+	for {
+		select {
+		case message := <-h.broadcast:
+			h.broadcastToAll(message)
+		case roomMsg := <-h.roomBroadcast:
+			h.broadcastToRoom(roomMsg)
+		case clientMsg := <-h.clientMessage:
+			h.sendToClient(clientMsg)
+		default:
+			return
+		}
 	}
 }
 
@@ -463,7 +858,12 @@ func (h *Hub) shutdownAllClients() {
 	defer h.mutex.Unlock()
 
 	for client := range h.clients {
-		client.Disconnect()
+		client.closeGoingAway()
+	}
+	for _, observers := range h.observers {
+		for observer := range observers {
+			h.removeObserver(observer)
+		}
 	}
 }
 
@@ -472,6 +872,7 @@ func (h *Hub) AddClientToRoom(client *Client, roomID string) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	h.addClientToRoom(client, roomID)
+	h.notifyClientNode(client, roomID)
 }
 
 // RemoveClientFromRoom removes a client from a room (public method)  
@@ -479,4 +880,5 @@ func (h *Hub) RemoveClientFromRoom(client *Client, roomID string) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 	h.removeClientFromRoom(client, roomID)
+	h.notifyClientNode(client, "")
 }
\ No newline at end of file
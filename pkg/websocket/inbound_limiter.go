@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"golang.org/x/time/rate"
+)
+
+// messageCategory groups inbound message types that share rate settings
+type messageCategory int
+
+const (
+	categoryOther messageCategory = iota
+	categoryDrawing
+	categoryChat
+	categoryAuth
+)
+
+// Message types clients may send, by category. Each gets its own bucket;
+// anything else shares one bucket so unknown types can't grow the map.
+var inboundMessageCategories = map[models.MessageType]messageCategory{
+	models.MessageTypeConnect:              categoryOther,
+	models.MessageTypeRegister:             categoryAuth,
+	models.MessageTypeGetProfile:           categoryOther,
+	models.MessageTypeCreateRoom:           categoryOther,
+	models.MessageTypeJoinRoom:             categoryOther,
+	models.MessageTypeLeaveRoom:            categoryOther,
+	models.MessageTypeStartGame:            categoryOther,
+	models.MessageTypePromoteSpectator:     categoryOther,
+	models.MessageTypeListPublicRooms:      categoryOther,
+	models.MessageTypeGetGlobalLeaderboard: categoryOther,
+	models.MessageTypeQuickPlay:            categoryOther,
+	models.MessageTypeCancelQuickPlay:      categoryOther,
+	models.MessageTypeDrawStart:            categoryDrawing,
+	models.MessageTypeDrawMove:             categoryDrawing,
+	models.MessageTypeDrawEnd:              categoryDrawing,
+	models.MessageTypeDrawBatch:            categoryDrawing,
+	models.MessageTypeClearCanvas:          categoryDrawing,
+	models.MessageTypeSendGuess:            categoryChat,
+	models.MessageTypeChatMessage:          categoryChat,
+}
+
+// Bucket key for message types clients aren't expected to send
+const unknownMessageType models.MessageType = ""
+
+// rateVerdict is what the limiter decided about one inbound message
+type rateVerdict int
+
+const (
+	rateAllow      rateVerdict = iota
+	rateDrop                   // Over the limit; drop it
+	rateWarn                   // Drop it and warn the client
+	rateMute                   // Drop it and tell the client it is muted
+	rateMuted                  // Drop it; the client is muted
+	rateDisconnect             // Too many violations; disconnect the client
+)
+
+// inboundLimiter enforces one client's message rate limits. It is only used
+// from the client's ReadPump, so it needs no locking.
+type inboundLimiter struct {
+	cfg     config.MessageRateLimitConfig
+	buckets map[models.MessageType]*rate.Limiter
+
+	violations    int
+	lastViolation time.Time
+	mutedUntil    time.Time
+}
+
+func newInboundLimiter(cfg config.MessageRateLimitConfig) *inboundLimiter {
+	return &inboundLimiter{
+		cfg:     cfg,
+		buckets: make(map[models.MessageType]*rate.Limiter),
+	}
+}
+
+// check takes a token for a message and decides what to do with it. Chat
+// and drawing are dropped while the client is muted; other messages, such
+// as leave_room, still go through.
+func (l *inboundLimiter) check(msgType models.MessageType, now time.Time) rateVerdict {
+	category, known := inboundMessageCategories[msgType]
+	if !known {
+		msgType = unknownMessageType
+	}
+	muted := (category == categoryDrawing || category == categoryChat) && now.Before(l.mutedUntil)
+
+	if l.bucket(msgType, category).AllowN(now, 1) {
+		if muted {
+			return rateMuted
+		}
+		return rateAllow
+	}
+
+	// Violations spread further apart than the window start over
+	if now.Sub(l.lastViolation) > l.cfg.ViolationWindow {
+		l.violations = 0
+	}
+	l.violations++
+	l.lastViolation = now
+
+	switch {
+	case l.violations >= l.cfg.DisconnectAfter:
+		return rateDisconnect
+	case l.violations == l.cfg.MuteAfter:
+		l.mutedUntil = now.Add(l.cfg.MuteDuration)
+		return rateMute
+	case l.violations == l.cfg.WarnAfter:
+		return rateWarn
+	case muted:
+		return rateMuted
+	}
+	return rateDrop
+}
+
+// bucket returns the token bucket for a message type, creating it as needed
+func (l *inboundLimiter) bucket(msgType models.MessageType, category messageCategory) *rate.Limiter {
+	bucket, exists := l.buckets[msgType]
+	if !exists {
+		limit := l.cfg.Other
+		switch category {
+		case categoryDrawing:
+			limit = l.cfg.Drawing
+		case categoryChat:
+			limit = l.cfg.Chat
+		case categoryAuth:
+			limit = l.cfg.Auth
+		}
+		bucket = rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)
+		l.buckets[msgType] = bucket
+	}
+	return bucket
+}
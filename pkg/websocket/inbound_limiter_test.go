@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+func testMessageRateLimitConfig() config.MessageRateLimitConfig {
+	return config.MessageRateLimitConfig{
+		Drawing:         config.MessageRate{PerSecond: 10, Burst: 2},
+		Chat:            config.MessageRate{PerSecond: 1, Burst: 1},
+		Auth:            config.MessageRate{PerSecond: 0.1, Burst: 1},
+		Other:           config.MessageRate{PerSecond: 1, Burst: 1},
+		ViolationWindow: 10 * time.Second,
+		WarnAfter:       1,
+		MuteAfter:       3,
+		DisconnectAfter: 5,
+		MuteDuration:    30 * time.Second,
+	}
+}
+
+// limiterStep is one inbound message and the verdict expected for it
+type limiterStep struct {
+	at      time.Duration
+	msgType models.MessageType
+	want    rateVerdict
+}
+
+func runLimiterSteps(t *testing.T, name string, steps []limiterStep) {
+	t.Helper()
+	limiter := newInboundLimiter(testMessageRateLimitConfig())
+	start := time.Now()
+	for i, step := range steps {
+		if got := limiter.check(step.msgType, start.Add(step.at)); got != step.want {
+			t.Errorf("%s: step %d (%s at %v) = %d, want %d", name, i, step.msgType, step.at, got, step.want)
+		}
+	}
+}
+
+func TestInboundLimiter(t *testing.T) {
+	chat := models.MessageTypeSendGuess
+	tests := []struct {
+		name  string
+		steps []limiterStep
+	}{
+		{"escalation", []limiterStep{
+			{0, chat, rateAllow},
+			{0, chat, rateWarn},
+			{0, chat, rateDrop},
+			{0, chat, rateMute},
+			{0, chat, rateMuted},
+			{0, chat, rateDisconnect},
+		}},
+		{"muted client", []limiterStep{
+			{0, chat, rateAllow},
+			{0, chat, rateWarn},
+			{0, chat, rateDrop},
+			{0, chat, rateMute},
+			// Chat and drawing are dropped even with tokens to spare
+			{2 * time.Second, chat, rateMuted},
+			{2 * time.Second, models.MessageTypeDrawStart, rateMuted},
+			// Leaving a room still works
+			{2 * time.Second, models.MessageTypeLeaveRoom, rateAllow},
+			// The mute lasts MuteDuration
+			{31 * time.Second, chat, rateAllow},
+		}},
+		{"violations spread out", []limiterStep{
+			{0, chat, rateAllow},
+			{0, chat, rateWarn},
+			{11 * time.Second, chat, rateAllow},
+			{11 * time.Second, chat, rateWarn},
+			{22 * time.Second, chat, rateAllow},
+			{22 * time.Second, chat, rateWarn},
+		}},
+		{"bucket per message type", []limiterStep{
+			{0, models.MessageTypeDrawStart, rateAllow},
+			{0, models.MessageTypeDrawStart, rateAllow},
+			{0, models.MessageTypeDrawMove, rateAllow},
+			{0, models.MessageTypeDrawMove, rateAllow},
+			{0, models.MessageTypeDrawMove, rateWarn},
+			{0, models.MessageTypeChatMessage, rateAllow},
+		}},
+		{"unknown types share a bucket", []limiterStep{
+			{0, "made_up", rateAllow},
+			{0, "also_made_up", rateWarn},
+			{0, models.MessageTypeJoinRoom, rateAllow},
+		}},
+		{"register refills slowly", []limiterStep{
+			{0, models.MessageTypeRegister, rateAllow},
+			{0, models.MessageTypeCreateRoom, rateAllow},
+			{time.Second, models.MessageTypeCreateRoom, rateAllow},
+			{time.Second, models.MessageTypeRegister, rateWarn},
+			{10 * time.Second, models.MessageTypeRegister, rateAllow},
+		}},
+	}
+
+	for _, tt := range tests {
+		runLimiterSteps(t, tt.name, tt.steps)
+	}
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// LifecycleHooks let the game layer follow players in this node's rooms as
+// their connections come and go. Hooks run on the room's actor, serialized
+// with the room's messages.
+type LifecycleHooks struct {
+	// A player's connection dropped. They keep their seat and may resume.
+	OnDisconnect func(roomID, userID string)
+
+	// A player who dropped is back in the room
+	OnReconnect func(roomID, userID string)
+}
+
+// SetLifecycleHooks sets the functions called when players in rooms owned
+// by this node disconnect or reconnect
+func (h *Hub) SetLifecycleHooks(hooks LifecycleHooks) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.lifecycle = hooks
+}
+
+// ReleaseSeat forgets a disconnected player's place in a room once the room
+// has removed them, so resuming their session no longer rejoins it
+func (h *Hub) ReleaseSeat(roomID, userID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// The player's own node holds their session
+	if proxy, exists := h.remoteClients[userID]; exists {
+		if proxy.GetRoomID() == roomID {
+			h.removeClientFromRoom(proxy, roomID)
+		}
+		h.notifyClientNode(proxy, "")
+		return
+	}
+
+	if client, exists := h.clientsByUserID[userID]; exists {
+		if client.GetRoomID() == roomID {
+			h.removeClientFromRoom(client, roomID)
+		}
+		return
+	}
+	h.sessions.SetRoom(userID, "")
+}
+
+// playerDisconnected marks a player as gone and, if this node owns their
+// room, runs the disconnect hook. Caller must hold h.mutex.
+func (h *Hub) playerDisconnected(roomID string, user *models.User) {
+	user.SetConnected(false)
+	if h.lifecycle.OnDisconnect == nil || !h.IsLocalKey(roomID) {
+		return
+	}
+
+	hook, userID := h.lifecycle.OnDisconnect, user.ID
+	if !h.dispatch(roomID, func() { hook(roomID, userID) }) {
+		logger.Warn("room mailbox is full, dropping disconnect", logging.RoomID(roomID), logging.UserID(userID))
+	}
+}
+
+// playerReconnected runs the reconnect hook for a player back in one of
+// this node's rooms, or tells the room's owner. Caller must hold h.mutex.
+func (h *Hub) playerReconnected(client *Client, roomID string) {
+	if !h.IsLocalKey(roomID) {
+		if client.remoteNode == "" {
+			envelope := h.remoteEnvelope(EnvelopeResume, OwnerOf(h.broadcaster.Nodes(), roomID), client)
+			envelope.RoomID = roomID
+			h.publish(envelope)
+		}
+		return
+	}
+	if h.lifecycle.OnReconnect == nil {
+		return
+	}
+
+	hook, userID := h.lifecycle.OnReconnect, client.getUserID()
+	if !h.dispatch(roomID, func() { hook(roomID, userID) }) {
+		logger.Warn("room mailbox is full, dropping reconnect", logging.RoomID(roomID), logging.UserID(userID))
+	}
+}
@@ -1,12 +1,19 @@
 package websocket
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
 )
 
 // Message wraps the models.Message with WebSocket-specific functionality
 type Message struct {
 	*models.Message
+
+	// Drawing commands decoded from a binary frame
+	commands []models.DrawCommand
 }
 
 // NewMessage creates a new WebSocket message
@@ -40,6 +47,38 @@ func NewChatMessage(username, message string, isSystem bool) (*Message, error) {
 	return NewMessage(models.MessageTypeChatMessage, chatData)
 }
 
+// NewAnnouncementMessage creates a server-wide announcement message
+func NewAnnouncementMessage(text string) (*Message, error) {
+	return NewMessage(models.MessageTypeAnnouncement, models.AnnouncementData{Message: text})
+}
+
+// NewRoomClosedMessage creates a room closed message
+func NewRoomClosedMessage(roomID, reason string) (*Message, error) {
+	return NewMessage(models.MessageTypeRoomClosed, models.RoomClosedData{RoomID: roomID, Reason: reason})
+}
+
+// NewServerShuttingDownMessage creates a shutdown countdown message
+func NewServerShuttingDownMessage(shutdownAt time.Time) (*Message, error) {
+	secondsLeft := int(time.Until(shutdownAt).Round(time.Second).Seconds())
+	if secondsLeft < 0 {
+		secondsLeft = 0
+	}
+	return NewMessage(models.MessageTypeServerShuttingDown, models.ServerShuttingDownData{
+		SecondsLeft: secondsLeft,
+		ShutdownAt:  shutdownAt,
+	})
+}
+
+// NewAuthenticatedMessage creates a message carrying an account's tokens
+func NewAuthenticatedMessage(data models.AuthenticatedData) (*Message, error) {
+	return NewMessage(models.MessageTypeAuthenticated, data)
+}
+
+// NewProfileMessage creates a profile message
+func NewProfileMessage(profile *models.PublicProfile) (*Message, error) {
+	return NewMessage(models.MessageTypeProfile, profile)
+}
+
 // NewPointsMessage creates a points awarded message
 func NewPointsMessage(userID, username string, points, totalScore int, reason string) (*Message, error) {
 	pointsData := models.PointsAwardedData{
@@ -83,6 +122,32 @@ func NewPlayerLeftMessage(user *models.PublicUser) (*Message, error) {
 	return NewMessage(models.MessageTypePlayerLeft, user)
 }
 
+// NewSpectatorJoinedMessage creates a spectator joined message
+func NewSpectatorJoinedMessage(user *models.PublicUser) (*Message, error) {
+	return NewMessage(models.MessageTypeSpectatorJoined, user)
+}
+
+// NewSpectatorLeftMessage creates a spectator left message
+func NewSpectatorLeftMessage(user *models.PublicUser) (*Message, error) {
+	return NewMessage(models.MessageTypeSpectatorLeft, user)
+}
+
+// NewSpectatorPromotedMessage creates a message announcing a spectator who
+// became a player
+func NewSpectatorPromotedMessage(user *models.PublicUser) (*Message, error) {
+	return NewMessage(models.MessageTypeSpectatorPromoted, user)
+}
+
+// NewPlayerDisconnectedMessage creates a player disconnected message
+func NewPlayerDisconnectedMessage(user *models.PublicUser) (*Message, error) {
+	return NewMessage(models.MessageTypePlayerDisconnected, user)
+}
+
+// NewPlayerReconnectedMessage creates a player reconnected message
+func NewPlayerReconnectedMessage(user *models.PublicUser) (*Message, error) {
+	return NewMessage(models.MessageTypePlayerReconnected, user)
+}
+
 // NewGameStartedMessage creates a game started message
 func NewGameStartedMessage(room *models.PublicRoomInfo) (*Message, error) {
 	return NewMessage(models.MessageTypeGameStarted, room)
@@ -194,6 +259,11 @@ func NewPublicRoomsListMessage(rooms []*models.PublicRoomInfo) (*Message, error)
 	return NewMessage(models.MessageTypePublicRoomsList, data)
 }
 
+// NewQuickPlayStatusMessage creates a quick play status message
+func NewQuickPlayStatusMessage(status *models.QuickPlayStatusData) (*Message, error) {
+	return NewMessage(models.MessageTypeQuickPlayStatus, status)
+}
+
 // DrawDataMessage represents drawing data for broadcasting
 type DrawDataMessage struct {
 	Type   string  `json:"type"` // "start", "move", "end", "clear"
@@ -227,6 +297,135 @@ func NewLeaderboardMessage(players []*models.PublicUser, currentRound, maxRounds
 	return NewMessage(models.MessageTypeLeaderboard, data)
 }
 
+// NewGlobalLeaderboardMessage creates a global leaderboard message
+func NewGlobalLeaderboardMessage(data *models.GlobalLeaderboardData) (*Message, error) {
+	return NewMessage(models.MessageTypeGlobalLeaderboard, data)
+}
+
+// NewDrawBatchFromFrame decodes a binary drawing frame into a draw_batch message
+func NewDrawBatchFromFrame(frame []byte) (*Message, error) {
+	_, commands, err := DecodeDrawFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := NewMessage(models.MessageTypeDrawBatch, nil)
+	if err != nil {
+		return nil, err
+	}
+	msg.commands = commands
+	return msg, nil
+}
+
+// DrawCommands returns the drawing commands carried by a draw_start,
+// draw_move, draw_end or draw_batch message, whether it arrived as JSON or
+// as a binary frame
+func (m *Message) DrawCommands() ([]models.DrawCommand, error) {
+	switch m.Type {
+	case models.MessageTypeDrawStart:
+		var data models.DrawStartData
+		if err := m.UnmarshalData(&data); err != nil {
+			return nil, err
+		}
+		return []models.DrawCommand{{Type: "start", X: data.X, Y: data.Y, Color: data.Color, Size: data.Size}}, nil
+
+	case models.MessageTypeDrawMove:
+		var data models.DrawMoveData
+		if err := m.UnmarshalData(&data); err != nil {
+			return nil, err
+		}
+		return []models.DrawCommand{{Type: "move", X: data.X, Y: data.Y}}, nil
+
+	case models.MessageTypeDrawEnd:
+		var data models.DrawEndData
+		if err := m.UnmarshalData(&data); err != nil {
+			return nil, err
+		}
+		return []models.DrawCommand{{Type: "end", X: data.X, Y: data.Y}}, nil
+
+	case models.MessageTypeDrawBatch:
+		if m.commands != nil {
+			return m.commands, nil
+		}
+		var data models.DrawBatchData
+		if err := m.UnmarshalData(&data); err != nil {
+			return nil, err
+		}
+		if len(data.Commands) > maxDrawCommandsPerFrame {
+			return nil, ErrInvalidDrawFrame
+		}
+		return data.Commands, nil
+	}
+
+	return nil, fmt.Errorf("message type %s carries no drawing commands", m.Type)
+}
+
+// Bytes kept free in each canvas snapshot message for fields whose encoded
+// length varies between messages, like the timestamp
+const canvasSnapshotSlack = 64
+
+// NewCanvasSnapshotMessages splits a round's canvas into canvas_snapshot
+// messages of at most maxSize bytes and maxDrawCommandsPerFrame commands
+// each. An empty canvas needs no messages.
+func NewCanvasSnapshotMessages(round int, drawerID string, commands []models.CanvasCommand, maxSize int) ([]*Message, error) {
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	// Measure a message without commands, with chunk numbers as long as they
+	// can get
+	empty, err := NewMessage(models.MessageTypeCanvasSnapshot, models.CanvasSnapshotData{
+		Round:    round,
+		DrawerID: drawerID,
+		Chunk:    len(commands),
+		Chunks:   len(commands),
+		Commands: []models.CanvasCommand{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	emptyData, err := empty.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	overhead := len(emptyData) + canvasSnapshotSlack
+
+	var chunks [][]models.CanvasCommand
+	var chunk []models.CanvasCommand
+	size := overhead
+	for _, cmd := range commands {
+		encoded, err := json.Marshal(cmd)
+		if err != nil {
+			return nil, err
+		}
+		// A command too big for any message still goes out on its own
+		cmdSize := len(encoded) + 1
+		if len(chunk) > 0 && (size+cmdSize > maxSize || len(chunk) >= maxDrawCommandsPerFrame) {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, overhead
+		}
+		chunk = append(chunk, cmd)
+		size += cmdSize
+	}
+	chunks = append(chunks, chunk)
+
+	messages := make([]*Message, len(chunks))
+	for i, chunk := range chunks {
+		msg, err := NewMessage(models.MessageTypeCanvasSnapshot, models.CanvasSnapshotData{
+			Round:    round,
+			DrawerID: drawerID,
+			Chunk:    i + 1,
+			Chunks:   len(chunks),
+			Commands: chunk,
+		})
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = msg
+	}
+	return messages, nil
+}
+
 // ParseMessage parses a JSON message from WebSocket
 func ParseMessage(data []byte) (*Message, error) {
 	msg, err := models.ParseMessage(data)
@@ -237,6 +436,14 @@ func ParseMessage(data []byte) (*Message, error) {
 	return &Message{Message: msg}, nil
 }
 
+// InReplyTo tags the message with the request ID of the message it answers
+func (m *Message) InReplyTo(request *Message) *Message {
+	if request != nil {
+		m.RequestID = request.RequestID
+	}
+	return m
+}
+
 // SetRoomAndUser sets the room ID and user ID for the message
 func (m *Message) SetRoomAndUser(roomID, userID string) {
 	m.RoomID = roomID
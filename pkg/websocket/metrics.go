@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/metrics"
+)
+
+// Where messages can be dropped, used as the "channel" label
+const (
+	dropBroadcast      = "broadcast"
+	dropRoomBroadcast  = "roomBroadcast"
+	dropClientMessage  = "clientMessage"
+	dropMessageHandler = "messageHandler"
+	dropRoomMailbox    = "roomMailbox"
+	dropSendQueue      = "sendQueue"
+	dropObserver       = "observer"
+	dropPeerQueue      = "peerQueue"
+)
+
+// Why the server disconnected a client, used as the "reason" label
+const (
+	disconnectSlowConsumer = "slow_consumer"
+	disconnectRateLimited  = "rate_limited"
+	disconnectRegisterFull = "register_full"
+	disconnectReplaced     = "replaced"
+	disconnectKicked       = "kicked"
+)
+
+var (
+	messagesIn = metrics.Default.NewCounterVec(
+		"doodledash_messages_in_total",
+		"WebSocket messages received from clients, by message type.",
+		"type")
+
+	messagesOut = metrics.Default.NewCounterVec(
+		"doodledash_messages_out_total",
+		"WebSocket frames queued for clients, by message type.",
+		"type")
+
+	messagesDropped = metrics.Default.NewCounterVec(
+		"doodledash_messages_dropped_total",
+		"Messages dropped because a channel or queue was full, by channel.",
+		"channel")
+
+	forcedDisconnects = metrics.Default.NewCounterVec(
+		"doodledash_forced_disconnects_total",
+		"Clients disconnected by the server, by reason.",
+		"reason")
+
+	handlerDuration = metrics.Default.NewHistogramVec(
+		"doodledash_handler_duration_seconds",
+		"Time spent processing a client message, by message type.",
+		metrics.DefaultLatencyBuckets,
+		"type")
+)
+
+// typeLabel bounds the label values taken from client input: types clients
+// aren't expected to send are counted together
+func typeLabel(msgType models.MessageType) string {
+	if _, known := inboundMessageCategories[msgType]; !known {
+		return "unknown"
+	}
+	return string(msgType)
+}
@@ -0,0 +1,101 @@
+package websocket
+
+import (
+	"bytes"
+	"sync/atomic"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+)
+
+// RoomObserver receives a copy of every broadcast to a room without being
+// a player in it, e.g. for read-only HTTP feeds
+type RoomObserver struct {
+	roomID string
+	events chan []byte
+
+	// Number of messages dropped because the observer fell behind
+	dropped atomic.Int64
+}
+
+// Events returns the observer's messages, one JSON message per value. The
+// channel is closed when the observer is stopped or the hub shuts down.
+func (o *RoomObserver) Events() <-chan []byte {
+	return o.events
+}
+
+// Dropped returns how many messages were dropped because the observer fell behind
+func (o *RoomObserver) Dropped() int64 {
+	return o.dropped.Load()
+}
+
+// ObserveRoom starts delivering a room's broadcasts to a new observer. Up
+// to buffer messages are queued; after that new ones are dropped until the
+// observer catches up.
+func (h *Hub) ObserveRoom(roomID string, buffer int) *RoomObserver {
+	observer := &RoomObserver{
+		roomID: roomID,
+		events: make(chan []byte, buffer),
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	select {
+	case <-h.shutdown:
+		close(observer.events)
+		return observer
+	default:
+	}
+
+	if h.observers[roomID] == nil {
+		h.observers[roomID] = make(map[*RoomObserver]bool)
+	}
+	h.observers[roomID][observer] = true
+	return observer
+}
+
+// StopObserving stops an observer and closes its events channel
+func (h *Hub) StopObserving(observer *RoomObserver) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.removeObserver(observer)
+}
+
+// removeObserver unlists an observer. Caller must hold h.mutex.
+func (h *Hub) removeObserver(observer *RoomObserver) {
+	observers, exists := h.observers[observer.roomID]
+	if !exists || !observers[observer] {
+		return
+	}
+	delete(observers, observer)
+	if len(observers) == 0 {
+		delete(h.observers, observer.roomID)
+	}
+	close(observer.events)
+}
+
+// notifyObservers hands a room broadcast to the room's observers. Batched
+// messages are split so each event is a single JSON message. Caller must
+// hold h.mutex for reading.
+func (h *Hub) notifyObservers(roomID string, message []byte) {
+	observers := h.observers[roomID]
+	if len(observers) == 0 {
+		return
+	}
+
+	for _, line := range bytes.Split(message, newline) {
+		if len(line) == 0 {
+			continue
+		}
+		for observer := range observers {
+			select {
+			case observer.events <- line:
+			default:
+				messagesDropped.WithLabelValues(dropObserver).Inc()
+				if observer.dropped.Add(1) == 1 {
+					logger.Warn("room observer is falling behind, dropping messages", logging.RoomID(roomID))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// messagePriority decides what happens to a queued message when a client
+// falls behind
+type messagePriority int
+
+const (
+	// Dropped first under pressure; may be coalesced with newer messages
+	priorityDroppable messagePriority = iota
+	// Dropped only when the queue is full of normal traffic
+	priorityNormal
+	// Never dropped; the client is disconnected if these can't be delivered
+	priorityCritical
+)
+
+// coalesceKind groups droppable messages where only the newest one matters
+type coalesceKind int
+
+const (
+	coalesceNone coalesceKind = iota
+	coalesceTimer
+	coalesceDrawMove
+)
+
+// Message types that must always reach the client
+var criticalMessageTypes = map[models.MessageType]bool{
+	models.MessageTypeSession:      true,
+	models.MessageTypeConnected:    true,
+	models.MessageTypeGameStarted:  true,
+	models.MessageTypeNewRound:     true,
+	models.MessageTypeRoundEnded:   true,
+	models.MessageTypeCorrectGuess: true,
+	models.MessageTypeGuessResult:  true,
+	models.MessageTypeGameEnded:    true,
+}
+
+// outboundFrame is a message queued for the write pump
+type outboundFrame struct {
+	data     []byte
+	msgType  models.MessageType
+	binary   bool
+	alone    bool // Written as its own websocket message, never batched
+	priority messagePriority
+	coalesce coalesceKind
+	queuedAt time.Time
+}
+
+// newTextFrame builds a frame for a single JSON message, classifying it by type
+func newTextFrame(data []byte) outboundFrame {
+	frame := outboundFrame{data: data, priority: priorityNormal}
+
+	var envelope struct {
+		Type models.MessageType `json:"type"`
+		Data json.RawMessage    `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return frame
+	}
+	frame.msgType = envelope.Type
+
+	// Snapshot chunks are sized to fit the transport's message limit, so
+	// batching them back together would defeat the chunking
+	frame.alone = envelope.Type == models.MessageTypeCanvasSnapshot
+
+	switch {
+	case criticalMessageTypes[envelope.Type]:
+		frame.priority = priorityCritical
+	case envelope.Type == models.MessageTypeTimer:
+		frame.priority = priorityDroppable
+		frame.coalesce = coalesceTimer
+	case envelope.Type == models.MessageTypeDrawData:
+		var draw struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(envelope.Data, &draw) == nil && draw.Type == "move" {
+			frame.priority = priorityDroppable
+			frame.coalesce = coalesceDrawMove
+		}
+	}
+	return frame
+}
+
+// newDrawFrame builds a frame for a drawing broadcast. Batches made only of
+// move commands can be coalesced; anything that starts or ends a stroke
+// must be delivered.
+func newDrawFrame(data []byte, binary bool, commands []models.DrawCommand) outboundFrame {
+	frame := outboundFrame{data: data, msgType: models.MessageTypeDrawData, binary: binary, priority: priorityDroppable, coalesce: coalesceDrawMove}
+	for _, cmd := range commands {
+		if cmd.Type != "move" {
+			frame.priority = priorityNormal
+			frame.coalesce = coalesceNone
+			break
+		}
+	}
+	return frame
+}
+
+// batchable reports whether the frame can be joined with other text frames
+// into one websocket message
+func (f outboundFrame) batchable() bool {
+	return !f.binary && !f.alone
+}
+
+// typeLabel names the frame's message type for metrics
+func (f outboundFrame) typeLabel() string {
+	if f.msgType == "" {
+		return "unknown"
+	}
+	return string(f.msgType)
+}
+
+// outboundQueue is a per-client send queue that keeps critical messages
+// and sheds droppable ones when the client can't keep up
+type outboundQueue struct {
+	frames []outboundFrame
+
+	// Soft limit on non-critical frames
+	capacity int
+
+	// How long a critical frame may wait before the client is considered stuck
+	criticalDeadline time.Duration
+
+	// Signalled whenever frames are queued
+	notify chan struct{}
+
+	// Number of frames dropped or coalesced away
+	dropped int64
+
+	mutex sync.Mutex
+}
+
+func newOutboundQueue(capacity int, criticalDeadline time.Duration) *outboundQueue {
+	return &outboundQueue{
+		frames:           make([]outboundFrame, 0, capacity),
+		capacity:         capacity,
+		criticalDeadline: criticalDeadline,
+		notify:           make(chan struct{}, 1),
+	}
+}
+
+// push queues a frame. Returns false if critical traffic has been stuck
+// past its deadline and the client should be disconnected.
+func (q *outboundQueue) push(frame outboundFrame) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	frame.queuedAt = now
+
+	if !q.criticalHealthy(now) {
+		return false
+	}
+
+	// Only the newest timer tick is worth sending
+	if frame.coalesce == coalesceTimer {
+		q.removeWhere(func(f outboundFrame) bool { return f.coalesce == coalesceTimer })
+	}
+
+	// Under pressure, a move replaces the move queued directly before it
+	if frame.coalesce == coalesceDrawMove && q.underPressure() {
+		if last := len(q.frames) - 1; last >= 0 && q.frames[last].coalesce == coalesceDrawMove && q.frames[last].binary == frame.binary {
+			q.frames[last] = frame
+			q.drop()
+			q.signal()
+			return true
+		}
+	}
+
+	if frame.priority != priorityCritical && q.nonCriticalCount() >= q.capacity {
+		if !q.makeRoom(frame.priority) {
+			// Nothing lower priority to shed, so drop the new frame
+			q.drop()
+			return true
+		}
+	}
+
+	q.frames = append(q.frames, frame)
+	q.signal()
+	return true
+}
+
+// drain removes and returns every queued frame in order
+func (q *outboundQueue) drain() []outboundFrame {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	frames := q.frames
+	q.frames = make([]outboundFrame, 0, q.capacity)
+	return frames
+}
+
+// droppedCount returns how many frames were dropped or coalesced away
+func (q *outboundQueue) droppedCount() int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.dropped
+}
+
+// Internal methods; callers must hold q.mutex
+
+func (q *outboundQueue) drop() {
+	q.dropped++
+	messagesDropped.WithLabelValues(dropSendQueue).Inc()
+}
+
+func (q *outboundQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// criticalHealthy reports whether the oldest pending critical frame is
+// still within its deadline and the backlog is bounded
+func (q *outboundQueue) criticalHealthy(now time.Time) bool {
+	critical := 0
+	for _, f := range q.frames {
+		if f.priority != priorityCritical {
+			continue
+		}
+		if critical == 0 && now.Sub(f.queuedAt) > q.criticalDeadline {
+			return false
+		}
+		critical++
+	}
+	return critical < q.capacity
+}
+
+func (q *outboundQueue) underPressure() bool {
+	return len(q.frames) >= q.capacity/2
+}
+
+func (q *outboundQueue) nonCriticalCount() int {
+	n := 0
+	for _, f := range q.frames {
+		if f.priority != priorityCritical {
+			n++
+		}
+	}
+	return n
+}
+
+// makeRoom drops the oldest frame whose priority is no higher than the
+// incoming one, preferring droppable frames. Returns false if nothing could go.
+func (q *outboundQueue) makeRoom(incoming messagePriority) bool {
+	for _, victim := range []messagePriority{priorityDroppable, priorityNormal} {
+		if victim > incoming {
+			break
+		}
+		for i, f := range q.frames {
+			if f.priority == victim {
+				q.frames = append(q.frames[:i], q.frames[i+1:]...)
+				q.drop()
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *outboundQueue) removeWhere(match func(outboundFrame) bool) {
+	kept := q.frames[:0]
+	for _, f := range q.frames {
+		if match(f) {
+			q.drop()
+			continue
+		}
+		kept = append(kept, f)
+	}
+	q.frames = kept
+}
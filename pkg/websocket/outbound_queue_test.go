@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+)
+
+// testFrame builds a text frame for a message of the given type and data
+func testFrame(t *testing.T, msgType models.MessageType, data interface{}) outboundFrame {
+	t.Helper()
+	msg, err := NewMessage(msgType, data)
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	encoded, err := msg.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	return newTextFrame(encoded)
+}
+
+func TestNewTextFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    outboundFrame
+		priority messagePriority
+		coalesce coalesceKind
+		alone    bool
+	}{
+		{"critical", testFrame(t, models.MessageTypeRoundEnded, nil), priorityCritical, coalesceNone, false},
+		{"timer", testFrame(t, models.MessageTypeTimer, nil), priorityDroppable, coalesceTimer, false},
+		{"draw move", testFrame(t, models.MessageTypeDrawData, map[string]string{"type": "move"}), priorityDroppable, coalesceDrawMove, false},
+		{"draw start", testFrame(t, models.MessageTypeDrawData, map[string]string{"type": "start"}), priorityNormal, coalesceNone, false},
+		{"chat", testFrame(t, models.MessageTypeChatMessage, nil), priorityNormal, coalesceNone, false},
+		{"canvas snapshot", testFrame(t, models.MessageTypeCanvasSnapshot, nil), priorityNormal, coalesceNone, true},
+		{"not JSON", newTextFrame([]byte("not json")), priorityNormal, coalesceNone, false},
+	}
+
+	for _, tt := range tests {
+		if tt.frame.priority != tt.priority || tt.frame.coalesce != tt.coalesce || tt.frame.alone != tt.alone {
+			t.Errorf("%s: priority, coalesce, alone = %d, %d, %v, want %d, %d, %v",
+				tt.name, tt.frame.priority, tt.frame.coalesce, tt.frame.alone, tt.priority, tt.coalesce, tt.alone)
+		}
+	}
+}
+
+func TestNewDrawFrame(t *testing.T) {
+	moves := []models.DrawCommand{{Type: "move"}, {Type: "move"}}
+	stroke := []models.DrawCommand{{Type: "move"}, {Type: "end"}}
+
+	tests := []struct {
+		name     string
+		commands []models.DrawCommand
+		priority messagePriority
+		coalesce coalesceKind
+	}{
+		{"only moves", moves, priorityDroppable, coalesceDrawMove},
+		{"ends a stroke", stroke, priorityNormal, coalesceNone},
+	}
+
+	for _, tt := range tests {
+		frame := newDrawFrame(nil, true, tt.commands)
+		if frame.priority != tt.priority || frame.coalesce != tt.coalesce || !frame.binary {
+			t.Errorf("%s: priority, coalesce, binary = %d, %d, %v, want %d, %d, true",
+				tt.name, frame.priority, frame.coalesce, frame.binary, tt.priority, tt.coalesce)
+		}
+	}
+}
+
+// frameTypes lists the queued frames' message types, marking binary ones
+func frameTypes(frames []outboundFrame) []string {
+	types := make([]string, len(frames))
+	for i, f := range frames {
+		types[i] = string(f.msgType)
+		if f.binary {
+			types[i] += "/binary"
+		}
+	}
+	return types
+}
+
+func TestOutboundQueue(t *testing.T) {
+	timer := testFrame(t, models.MessageTypeTimer, nil)
+	move := testFrame(t, models.MessageTypeDrawData, map[string]string{"type": "move"})
+	binaryMove := newDrawFrame(nil, true, []models.DrawCommand{{Type: "move"}})
+	start := testFrame(t, models.MessageTypeDrawData, map[string]string{"type": "start"})
+	chat := testFrame(t, models.MessageTypeChatMessage, nil)
+	critical := testFrame(t, models.MessageTypeRoundEnded, nil)
+
+	tests := []struct {
+		name     string
+		capacity int
+		push     []outboundFrame
+		want     []string
+		dropped  int64
+	}{
+		{
+			"timer ticks coalesce",
+			8,
+			[]outboundFrame{timer, chat, timer, timer},
+			[]string{"chat_message", "timer"},
+			2,
+		},
+		{
+			"moves kept when not under pressure",
+			8,
+			[]outboundFrame{move, move},
+			[]string{"draw_data", "draw_data"},
+			0,
+		},
+		{
+			"moves coalesce under pressure",
+			4,
+			[]outboundFrame{chat, chat, move, move, move},
+			[]string{"chat_message", "chat_message", "draw_data"},
+			2,
+		},
+		{
+			"text and binary moves don't coalesce",
+			4,
+			[]outboundFrame{chat, chat, move, binaryMove},
+			[]string{"chat_message", "chat_message", "draw_data", "draw_data/binary"},
+			0,
+		},
+		{
+			"full queue sheds droppable frames first",
+			2,
+			[]outboundFrame{move, chat, chat},
+			[]string{"chat_message", "chat_message"},
+			1,
+		},
+		{
+			"full queue sheds droppable frames before normal ones",
+			2,
+			[]outboundFrame{chat, timer, chat},
+			[]string{"chat_message", "chat_message"},
+			1,
+		},
+		{
+			"full queue sheds the oldest normal frame",
+			2,
+			[]outboundFrame{start, chat, chat},
+			[]string{"chat_message", "chat_message"},
+			1,
+		},
+		{
+			"full queue drops incoming droppable frames",
+			2,
+			[]outboundFrame{chat, chat, move},
+			[]string{"chat_message", "chat_message"},
+			1,
+		},
+		{
+			"critical frames are never shed",
+			3,
+			[]outboundFrame{critical, chat, chat, chat, critical, start},
+			[]string{"round_ended", "chat_message", "chat_message", "round_ended", "draw_data"},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		q := newOutboundQueue(tt.capacity, time.Minute)
+		for _, frame := range tt.push {
+			if !q.push(frame) {
+				t.Fatalf("%s: push reported a stuck client", tt.name)
+			}
+		}
+		got := frameTypes(q.drain())
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: queued %v, want %v", tt.name, got, tt.want)
+		} else {
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s: queued %v, want %v", tt.name, got, tt.want)
+					break
+				}
+			}
+		}
+		if dropped := q.droppedCount(); dropped != tt.dropped {
+			t.Errorf("%s: dropped %d, want %d", tt.name, dropped, tt.dropped)
+		}
+	}
+}
+
+func TestOutboundQueueStuckClient(t *testing.T) {
+	critical := testFrame(t, models.MessageTypeRoundEnded, nil)
+	chat := testFrame(t, models.MessageTypeChatMessage, nil)
+
+	// A critical frame waiting past its deadline means the client is stuck
+	q := newOutboundQueue(8, time.Minute)
+	q.push(critical)
+	q.frames[0].queuedAt = time.Now().Add(-2 * time.Minute)
+	if q.push(chat) {
+		t.Error("push accepted a frame behind an overdue critical frame")
+	}
+
+	// So does a critical backlog as long as the queue's capacity
+	q = newOutboundQueue(2, time.Minute)
+	if !q.push(critical) || !q.push(critical) {
+		t.Fatal("push rejected critical frames below capacity")
+	}
+	if q.push(chat) {
+		t.Error("push accepted a frame behind a full critical backlog")
+	}
+
+	// Draining clears the way
+	q.drain()
+	if !q.push(chat) {
+		t.Error("push rejected a frame after the queue drained")
+	}
+}
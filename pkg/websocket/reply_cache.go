@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// How long a reply is kept for retried requests
+	replyCacheTTL = 2 * time.Minute
+
+	// Upper bound on cached replies across all clients
+	replyCacheMaxEntries = 10000
+)
+
+// cachedReply is a reply kept for duplicate request detection
+type cachedReply struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// replyCache remembers replies by user and request ID so retried requests
+// can be answered without running them twice
+type replyCache struct {
+	entries map[string]cachedReply
+	mutex   sync.Mutex
+}
+
+func newReplyCache() *replyCache {
+	return &replyCache{
+		entries: make(map[string]cachedReply),
+	}
+}
+
+func replyCacheKey(userID, requestID string) string {
+	return userID + "\x00" + requestID
+}
+
+func (rc *replyCache) get(userID, requestID string) ([]byte, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	key := replyCacheKey(userID, requestID)
+	entry, exists := rc.entries[key]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (rc *replyCache) put(userID, requestID string, data []byte) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	now := time.Now()
+	if len(rc.entries) >= replyCacheMaxEntries {
+		rc.prune(now)
+	}
+	rc.entries[replyCacheKey(userID, requestID)] = cachedReply{
+		data:      data,
+		expiresAt: now.Add(replyCacheTTL),
+	}
+}
+
+// prune drops expired entries, or everything if none have expired yet.
+// Caller must hold rc.mutex.
+func (rc *replyCache) prune(now time.Time) {
+	for key, entry := range rc.entries {
+		if now.After(entry.expiresAt) {
+			delete(rc.entries, key)
+		}
+	}
+	if len(rc.entries) >= replyCacheMaxEntries {
+		rc.entries = make(map[string]cachedReply)
+	}
+}
+
+// CachedReply returns the reply already sent for a user's request ID
+func (h *Hub) CachedReply(userID, requestID string) ([]byte, bool) {
+	return h.replies.get(userID, requestID)
+}
+
+// CacheReply stores the reply to a user's request ID for duplicate detection
+func (h *Hub) CacheReply(userID, requestID string, reply []byte) {
+	h.replies.put(userID, requestID, reply)
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+)
+
+const (
+	// Number of pending tasks a room can queue before new ones are dropped
+	roomMailboxSize = 256
+
+	// How long a room actor may sit idle before its goroutine exits
+	roomActorIdleTimeout = 2 * time.Minute
+
+	// Actor key for messages from clients that are not in a room yet
+	lobbyActorID = ""
+)
+
+// roomActor owns the goroutine that runs a single room's tasks in order.
+// The hub only routes work into the mailbox, so a slow or stuck room
+// backs up its own mailbox without stalling the hub or other rooms.
+type roomActor struct {
+	roomID  string
+	mailbox chan func()
+}
+
+// DispatchToRoom queues a task on the room's actor. Tasks for the same room
+// never run concurrently. Returns false if the room's mailbox is full, which
+// is counted as a dropped message, or the hub is shutting down.
+func (h *Hub) DispatchToRoom(roomID string, task func()) bool {
+	return h.dispatch(roomID, task)
+}
+
+// DispatchToLobby queues a task on the actor shared by clients outside any room
+func (h *Hub) DispatchToLobby(task func()) bool {
+	return h.dispatch(lobbyActorID, task)
+}
+
+func (h *Hub) dispatch(roomID string, task func()) bool {
+	h.actorsMutex.Lock()
+	defer h.actorsMutex.Unlock()
+
+	select {
+	case <-h.shutdown:
+		return false
+	default:
+	}
+
+	actor, exists := h.actors[roomID]
+	if !exists {
+		actor = &roomActor{
+			roomID:  roomID,
+			mailbox: make(chan func(), roomMailboxSize),
+		}
+		h.actors[roomID] = actor
+		go h.runActor(actor)
+	}
+
+	select {
+	case actor.mailbox <- task:
+		return true
+	default:
+		messagesDropped.WithLabelValues(dropRoomMailbox).Inc()
+		return false
+	}
+}
+
+func (h *Hub) runActor(actor *roomActor) {
+	idle := time.NewTimer(roomActorIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case task := <-actor.mailbox:
+			actor.run(task)
+			idle.Reset(roomActorIdleTimeout)
+
+		case <-idle.C:
+			// Tasks are only queued while holding actorsMutex, so once the
+			// actor is unlisted nothing else can reach its mailbox
+			h.actorsMutex.Lock()
+			if len(actor.mailbox) == 0 {
+				delete(h.actors, actor.roomID)
+				h.actorsMutex.Unlock()
+				return
+			}
+			h.actorsMutex.Unlock()
+			idle.Reset(roomActorIdleTimeout)
+
+		case <-h.shutdown:
+			return
+		}
+	}
+}
+
+// run executes a task, keeping a panicking handler from killing the actor
+func (a *roomActor) run(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("recovered from panic in room handler", logging.RoomID(a.roomID), "panic", r)
+		}
+	}()
+	task()
+}
+
+// GetActiveRoomActors returns the number of running room actors
+func (h *Hub) GetActiveRoomActors() int {
+	h.actorsMutex.Lock()
+	defer h.actorsMutex.Unlock()
+	return len(h.actors)
+}
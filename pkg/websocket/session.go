@@ -0,0 +1,290 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/models"
+	"github.com/RITWIZSINGH/DoodleDash-backend/pkg/utils"
+)
+
+// Length of generated resume tokens
+const resumeTokenLength = 32
+
+// Session tracks a user across connections so a dropped client can resume
+// with the same identity, room seat and the messages it missed in the gap
+type Session struct {
+	Token  string
+	User   *models.User
+	RoomID string
+
+	disconnectedAt time.Time
+	missed         [][]byte
+	truncated      bool
+}
+
+// SessionManager issues resume tokens and holds the sessions of
+// disconnected users until their grace period runs out
+type SessionManager struct {
+	byToken  map[string]*Session
+	byUserID map[string]*Session
+
+	// Disconnected sessions by room, used to buffer room broadcasts
+	waitingByRoom map[string]map[*Session]bool
+
+	gracePeriod time.Duration
+	maxReplay   int
+
+	mutex sync.Mutex
+}
+
+// NewSessionManager creates a new session manager
+func NewSessionManager(gracePeriod time.Duration, maxReplay int) *SessionManager {
+	return &SessionManager{
+		byToken:       make(map[string]*Session),
+		byUserID:      make(map[string]*Session),
+		waitingByRoom: make(map[string]map[*Session]bool),
+		gracePeriod:   gracePeriod,
+		maxReplay:     maxReplay,
+	}
+}
+
+// GracePeriod returns how long a disconnected session can be resumed
+func (sm *SessionManager) GracePeriod() time.Duration {
+	return sm.gracePeriod
+}
+
+// Create issues a new session for a freshly connected user
+func (sm *SessionManager) Create(user *models.User) *Session {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if old, exists := sm.byUserID[user.ID]; exists {
+		sm.remove(old)
+	}
+
+	session := &Session{
+		Token: utils.GenerateRandomString(resumeTokenLength),
+		User:  user,
+	}
+	sm.byToken[session.Token] = session
+	sm.byUserID[user.ID] = session
+	return session
+}
+
+// Restore recreates a disconnected user's session with the resume token
+// they held before a restart. Their grace period starts now.
+func (sm *SessionManager) Restore(user *models.User, token, roomID string) *Session {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if old, exists := sm.byUserID[user.ID]; exists {
+		sm.remove(old)
+	}
+	if old, exists := sm.byToken[token]; exists {
+		sm.remove(old)
+	}
+
+	session := &Session{
+		Token:          token,
+		User:           user,
+		RoomID:         roomID,
+		disconnectedAt: time.Now(),
+	}
+	sm.byToken[token] = session
+	sm.byUserID[user.ID] = session
+	if roomID != "" {
+		if sm.waitingByRoom[roomID] == nil {
+			sm.waitingByRoom[roomID] = make(map[*Session]bool)
+		}
+		sm.waitingByRoom[roomID][session] = true
+	}
+	return session
+}
+
+// Token returns the current resume token of a user's session, or an empty
+// string if they have none
+func (sm *SessionManager) Token(userID string) string {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if session, exists := sm.byUserID[userID]; exists {
+		return session.Token
+	}
+	return ""
+}
+
+// Resume looks up a session by resume token. The token is rotated on
+// success so each one can be used only once. Returns nil if the token is
+// unknown or its grace period has run out.
+func (sm *SessionManager) Resume(token string) *Session {
+	if token == "" {
+		return nil
+	}
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	session, exists := sm.byToken[token]
+	if !exists {
+		return nil
+	}
+	if sm.isExpired(session, time.Now()) {
+		sm.remove(session)
+		return nil
+	}
+
+	delete(sm.byToken, token)
+	session.Token = utils.GenerateRandomString(resumeTokenLength)
+	sm.byToken[session.Token] = session
+	return session
+}
+
+// Attach marks a session as connected again and hands back the messages
+// buffered while it was away, plus whether some had to be discarded
+func (sm *SessionManager) Attach(session *Session) ([][]byte, bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.unmarkWaiting(session)
+	session.disconnectedAt = time.Time{}
+
+	missed, truncated := session.missed, session.truncated
+	session.missed = nil
+	session.truncated = false
+	return missed, truncated
+}
+
+// MarkDisconnected starts the grace period for a user's session and begins
+// buffering the given room's broadcasts for it
+func (sm *SessionManager) MarkDisconnected(userID, roomID string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	session, exists := sm.byUserID[userID]
+	if !exists {
+		return
+	}
+
+	sm.unmarkWaiting(session)
+	session.disconnectedAt = time.Now()
+	session.RoomID = roomID
+	session.missed = nil
+	session.truncated = false
+
+	if roomID != "" {
+		if sm.waitingByRoom[roomID] == nil {
+			sm.waitingByRoom[roomID] = make(map[*Session]bool)
+		}
+		sm.waitingByRoom[roomID][session] = true
+	}
+}
+
+// SetRoom moves a disconnected user's session to another room, or out of
+// any room if roomID is empty
+func (sm *SessionManager) SetRoom(userID, roomID string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	session, exists := sm.byUserID[userID]
+	if !exists || session.disconnectedAt.IsZero() {
+		return
+	}
+
+	sm.unmarkWaiting(session)
+	session.RoomID = roomID
+	if roomID != "" {
+		if sm.waitingByRoom[roomID] == nil {
+			sm.waitingByRoom[roomID] = make(map[*Session]bool)
+		}
+		sm.waitingByRoom[roomID][session] = true
+	}
+}
+
+// BufferForRoom stores a room broadcast for every disconnected session in the room
+func (sm *SessionManager) BufferForRoom(roomID string, message []byte) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	for session := range sm.waitingByRoom[roomID] {
+		sm.buffer(session, message)
+	}
+}
+
+// BufferForUser stores a direct message if the user's session is disconnected.
+// Returns false if there is no disconnected session to hold it.
+func (sm *SessionManager) BufferForUser(userID string, message []byte) bool {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	session, exists := sm.byUserID[userID]
+	if !exists || session.disconnectedAt.IsZero() {
+		return false
+	}
+	sm.buffer(session, message)
+	return true
+}
+
+// Remove ends a user's session immediately
+func (sm *SessionManager) Remove(userID string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if session, exists := sm.byUserID[userID]; exists {
+		sm.remove(session)
+	}
+}
+
+// ExpireStale removes sessions whose grace period has run out and returns them
+func (sm *SessionManager) ExpireStale() []*Session {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	now := time.Now()
+	var expired []*Session
+	for _, session := range sm.byUserID {
+		if sm.isExpired(session, now) {
+			expired = append(expired, session)
+		}
+	}
+	for _, session := range expired {
+		sm.remove(session)
+	}
+	return expired
+}
+
+// Internal methods; callers must hold sm.mutex
+
+func (sm *SessionManager) isExpired(session *Session, now time.Time) bool {
+	return !session.disconnectedAt.IsZero() && now.Sub(session.disconnectedAt) > sm.gracePeriod
+}
+
+func (sm *SessionManager) buffer(session *Session, message []byte) {
+	if sm.maxReplay == 0 {
+		session.truncated = true
+		return
+	}
+	if len(session.missed) >= sm.maxReplay {
+		// Keep the newest messages and let the client know it missed some
+		session.missed = session.missed[1:]
+		session.truncated = true
+	}
+	session.missed = append(session.missed, message)
+}
+
+func (sm *SessionManager) unmarkWaiting(session *Session) {
+	if waiting, exists := sm.waitingByRoom[session.RoomID]; exists {
+		delete(waiting, session)
+		if len(waiting) == 0 {
+			delete(sm.waitingByRoom, session.RoomID)
+		}
+	}
+}
+
+func (sm *SessionManager) remove(session *Session) {
+	sm.unmarkWaiting(session)
+	delete(sm.byToken, session.Token)
+	if sm.byUserID[session.User.ID] == session {
+		delete(sm.byUserID, session.User.ID)
+	}
+}
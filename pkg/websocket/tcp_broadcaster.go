@@ -0,0 +1,317 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/logging"
+)
+
+const (
+	// Envelopes a peer can have queued before new ones are dropped
+	peerQueueSize = 4096
+
+	// Largest envelope accepted from a peer
+	maxEnvelopeSize = 1 << 20
+
+	// Time allowed to connect to or write to a peer
+	peerDialTimeout  = 5 * time.Second
+	peerWriteTimeout = 10 * time.Second
+
+	// Bounds for the delay between reconnect attempts
+	peerMinBackoff = 500 * time.Millisecond
+	peerMaxBackoff = 10 * time.Second
+)
+
+// peerHello is the first line a node sends on a bus connection
+type peerHello struct {
+	NodeID string `json:"node_id"`
+	Secret string `json:"secret"`
+}
+
+// TCPBroadcaster links nodes over plain TCP. Each node dials every peer and
+// writes newline-delimited JSON envelopes, after a hello line carrying its
+// node ID and the cluster's shared secret.
+type TCPBroadcaster struct {
+	nodeID     string
+	listenAddr string
+	secret     string
+	nodes      []string
+	peers      map[string]*tcpPeer
+
+	listener net.Listener
+
+	handlers      []func(*Envelope)
+	handlersMutex sync.RWMutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// tcpPeer is the outbound link to one other node
+type tcpPeer struct {
+	nodeID string
+	addr   string
+	queue  chan []byte
+}
+
+// NewTCPBroadcaster creates a broadcaster for the configured cluster
+func NewTCPBroadcaster(cfg config.ClusterConfig) *TCPBroadcaster {
+	b := &TCPBroadcaster{
+		nodeID:     cfg.NodeID,
+		listenAddr: cfg.ListenAddr,
+		secret:     cfg.SharedSecret,
+		nodes:      []string{cfg.NodeID},
+		peers:      make(map[string]*tcpPeer),
+		closed:     make(chan struct{}),
+	}
+	for _, peer := range cfg.Peers {
+		b.peers[peer.NodeID] = &tcpPeer{
+			nodeID: peer.NodeID,
+			addr:   peer.Addr,
+			queue:  make(chan []byte, peerQueueSize),
+		}
+		b.nodes = append(b.nodes, peer.NodeID)
+	}
+	sort.Strings(b.nodes)
+	return b
+}
+
+// Start listens for peers and begins connecting to them
+func (b *TCPBroadcaster) Start() error {
+	listener, err := net.Listen("tcp", b.listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", b.listenAddr, err)
+	}
+	b.listener = listener
+
+	go b.acceptLoop()
+	for _, peer := range b.peers {
+		go b.runPeer(peer)
+	}
+
+	clusterLogger.Info("cluster node listening", "node", b.nodeID, "addr", b.listenAddr, "peers", len(b.peers))
+	return nil
+}
+
+// NodeID returns this node's ID
+func (b *TCPBroadcaster) NodeID() string {
+	return b.nodeID
+}
+
+// Nodes returns every node in the cluster, sorted
+func (b *TCPBroadcaster) Nodes() []string {
+	return b.nodes
+}
+
+// Publish delivers the envelope locally if it is addressed to this node and
+// queues it for every addressed peer. It never blocks; envelopes for a peer
+// that has fallen too far behind are dropped.
+func (b *TCPBroadcaster) Publish(envelope *Envelope) error {
+	envelope.Origin = b.nodeID
+
+	if envelope.Target == "" || envelope.Target == b.nodeID {
+		b.deliver(envelope)
+	}
+	if envelope.Target == b.nodeID {
+		return nil
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if envelope.Target != "" {
+		peer, exists := b.peers[envelope.Target]
+		if !exists {
+			return fmt.Errorf("unknown node %q", envelope.Target)
+		}
+		peer.enqueue(data)
+		return nil
+	}
+	for _, peer := range b.peers {
+		peer.enqueue(data)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for envelopes addressed to this node
+func (b *TCPBroadcaster) Subscribe(handler func(*Envelope)) {
+	b.handlersMutex.Lock()
+	defer b.handlersMutex.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Close stops listening and disconnects from peers
+func (b *TCPBroadcaster) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		if b.listener != nil {
+			err = b.listener.Close()
+		}
+	})
+	return err
+}
+
+func (b *TCPBroadcaster) deliver(envelope *Envelope) {
+	b.handlersMutex.RLock()
+	defer b.handlersMutex.RUnlock()
+	for _, handler := range b.handlers {
+		handler(envelope)
+	}
+}
+
+func (p *tcpPeer) enqueue(data []byte) {
+	select {
+	case p.queue <- data:
+	default:
+		clusterLogger.Warn("peer queue is full, dropping envelope", "node", p.nodeID)
+		messagesDropped.WithLabelValues(dropPeerQueue).Inc()
+	}
+}
+
+// runPeer keeps a connection to a peer open and writes its queued envelopes
+func (b *TCPBroadcaster) runPeer(peer *tcpPeer) {
+	backoff := peerMinBackoff
+	for {
+		conn, err := b.dialPeer(peer)
+		if err != nil {
+			clusterLogger.Warn("connecting to peer", "node", peer.nodeID, "addr", peer.addr, logging.Err(err))
+			select {
+			case <-time.After(backoff):
+			case <-b.closed:
+				return
+			}
+			if backoff *= 2; backoff > peerMaxBackoff {
+				backoff = peerMaxBackoff
+			}
+			continue
+		}
+
+		clusterLogger.Info("connected to peer", "node", peer.nodeID, "addr", peer.addr)
+		backoff = peerMinBackoff
+		err = b.writePeer(conn, peer)
+		conn.Close()
+		if err == nil {
+			return
+		}
+		clusterLogger.Warn("lost connection to peer", "node", peer.nodeID, logging.Err(err))
+	}
+}
+
+func (b *TCPBroadcaster) dialPeer(peer *tcpPeer) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", peer.addr, peerDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	hello, err := json.Marshal(peerHello{NodeID: b.nodeID, Secret: b.secret})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Now().Add(peerWriteTimeout))
+	if _, err := conn.Write(append(hello, '\n')); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// writePeer writes queued envelopes until the connection fails or the
+// broadcaster is closed, in which case it returns nil
+func (b *TCPBroadcaster) writePeer(conn net.Conn, peer *tcpPeer) error {
+	writer := bufio.NewWriter(conn)
+	for {
+		select {
+		case data := <-peer.queue:
+			conn.SetWriteDeadline(time.Now().Add(peerWriteTimeout))
+			writer.Write(data)
+
+			// Batch whatever else is already queued into the same flush
+			for n := len(peer.queue); n > 0; n-- {
+				writer.Write(<-peer.queue)
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+
+		case <-b.closed:
+			return nil
+		}
+	}
+}
+
+func (b *TCPBroadcaster) acceptLoop() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			select {
+			case <-b.closed:
+				return
+			default:
+			}
+			clusterLogger.Error("accepting cluster connection", logging.Err(err))
+			time.Sleep(peerMinBackoff)
+			continue
+		}
+		go b.readPeer(conn)
+	}
+}
+
+// readPeer checks a peer's hello line and then delivers every envelope it sends
+func (b *TCPBroadcaster) readPeer(conn net.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+	defer conn.Close()
+
+	// Unblock the reader when the broadcaster closes
+	go func() {
+		select {
+		case <-b.closed:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEnvelopeSize)
+
+	conn.SetReadDeadline(time.Now().Add(peerDialTimeout))
+	if !scanner.Scan() {
+		return
+	}
+	var hello peerHello
+	if err := json.Unmarshal(scanner.Bytes(), &hello); err != nil {
+		clusterLogger.Warn("invalid hello from peer", "addr", conn.RemoteAddr().String(), logging.Err(err))
+		return
+	}
+	if _, known := b.peers[hello.NodeID]; !known || subtle.ConstantTimeCompare([]byte(hello.Secret), []byte(b.secret)) != 1 {
+		clusterLogger.Warn("rejected cluster connection", "addr", conn.RemoteAddr().String(), "node", hello.NodeID)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	for scanner.Scan() {
+		envelope := &Envelope{}
+		if err := json.Unmarshal(scanner.Bytes(), envelope); err != nil {
+			clusterLogger.Warn("decoding envelope", "node", hello.NodeID, logging.Err(err))
+			continue
+		}
+		envelope.Origin = hello.NodeID
+		b.deliver(envelope)
+	}
+	if err := scanner.Err(); err != nil {
+		clusterLogger.Warn("reading from peer", "node", hello.NodeID, logging.Err(err))
+	}
+}
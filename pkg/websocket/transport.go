@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RITWIZSINGH/DoodleDash-backend/internal/config"
+	"github.com/gorilla/websocket"
+)
+
+// NewUpgrader builds the WebSocket upgrader from configuration. Browser
+// origins are checked against the CORS allowlist.
+func NewUpgrader(cfg *config.Config) *websocket.Upgrader {
+	allowed := cfg.CORS.AllowedOrigins
+	return &websocket.Upgrader{
+		ReadBufferSize:    cfg.WebSocket.ReadBufferSize,
+		WriteBufferSize:   cfg.WebSocket.WriteBufferSize,
+		HandshakeTimeout:  cfg.WebSocket.WriteWait,
+		Subprotocols:      Subprotocols(),
+		EnableCompression: cfg.WebSocket.EnableCompression,
+		CheckOrigin: func(r *http.Request) bool {
+			return OriginAllowed(r, allowed)
+		},
+	}
+}
+
+// OriginAllowed reports whether a WebSocket handshake's Origin is allowed.
+// Requests without an Origin header come from non-browser clients and are
+// let through, as are same-host requests. Entries may be "*" or contain a
+// single "*" wildcard, e.g. "https://*.example.com".
+func OriginAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if prefix, suffix, found := strings.Cut(pattern, "*"); found &&
+			len(origin) >= len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressionNegotiated reports whether permessage-deflate is in use on a
+// connection upgraded by upgrader: it is when compression is enabled and
+// the client offered it
+func CompressionNegotiated(upgrader *websocket.Upgrader, r *http.Request) bool {
+	if !upgrader.EnableCompression {
+		return false
+	}
+	for _, header := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, extension := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(extension, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RejectConnection closes a freshly upgraded connection with a policy
+// violation close frame carrying reason
+func RejectConnection(conn *websocket.Conn, cfg *config.Config, reason string) {
+	closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(cfg.WebSocket.WriteWait))
+	conn.Close()
+}